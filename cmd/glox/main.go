@@ -5,6 +5,7 @@ import (
 	"log/slog"
 	"os"
 
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/runner"
 )
 
@@ -26,6 +27,10 @@ func main() {
 	})))
 
 	i := runner.Runner{}
+	backend := os.Getenv("BACKEND")
+	if backend == "VM" || backend == "vm" {
+		i.Backend = runner.BackendVM
+	}
 
 	var err error
 	if len(args) == 1 {
@@ -36,7 +41,7 @@ func main() {
 	}
 
 	if err != nil {
-		fmt.Println(err)
+		i.Diagnostics(diagnostic.IsTerminal(os.Stdout)).Render(os.Stdout, err)
 		os.Exit(65)
 	}
 }
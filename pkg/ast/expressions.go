@@ -14,9 +14,22 @@ type ExpressionVisitor interface {
 	VisitUnary(Unary) (any, error)
 	VisitVariable(Variable) (any, error)
 	VisitLogical(Logical) (any, error)
+	VisitConditional(Conditional) (any, error)
 	VisitPostUnary(PostUnary) (any, error)
+	VisitPreUnary(PreUnary) (any, error)
+	VisitCompound(Compound) (any, error)
 	VisitCall(Call) (any, error)
 	VisitLambda(Lambda) (any, error)
+	VisitGet(Get) (any, error)
+	VisitSet(Set) (any, error)
+	VisitThis(This) (any, error)
+	VisitSuper(Super) (any, error)
+	VisitList(List) (any, error)
+	VisitIndex(Index) (any, error)
+	VisitIndexSet(IndexSet) (any, error)
+	VisitMap(Map) (any, error)
+	VisitInterpolation(Interpolation) (any, error)
+	VisitMatch(Match) (any, error)
 }
 
 type Expr interface {
@@ -89,6 +102,18 @@ func (e Logical) Accept(v ExpressionVisitor) (any, error) {
 	return v.VisitLogical(e)
 }
 
+// Conditional evaluates to Then if the condition is truthy, and to Else otherwise, e.g. cond ? a : b.
+type Conditional struct {
+	Condition Expr
+	Question  token.Token
+	Then      Expr
+	Else      Expr
+}
+
+func (e Conditional) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitConditional(e)
+}
+
 type PostUnary struct {
 	Left     Expr
 	Operator token.Token
@@ -98,6 +123,28 @@ func (e PostUnary) Accept(v ExpressionVisitor) (any, error) {
 	return v.VisitPostUnary(e)
 }
 
+// PreUnary increments or decrements its operand before evaluating to it, e.g. ++x.
+type PreUnary struct {
+	Operator token.Token
+	Right    Expr
+}
+
+func (e PreUnary) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitPreUnary(e)
+}
+
+// Compound assigns the result of an operator on the target and the value to the target, e.g. x += 1.
+// the target is a Variable, a Get or an Index, and its object and index are only evaluated once.
+type Compound struct {
+	Target   Expr
+	Operator token.Token
+	Value    Expr
+}
+
+func (e Compound) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitCompound(e)
+}
+
 type Call struct {
 	Callee Expr
 	Args   []Expr
@@ -115,6 +162,8 @@ type Lambda struct {
 	Name   token.Token
 	Params []token.Token
 	Body   []Stmt
+	// Generator is whether the body yields, so that calling the function returns a generator rather than running the body.
+	Generator bool
 }
 
 func (e Lambda) Accept(v ExpressionVisitor) (any, error) {
@@ -124,3 +173,121 @@ func (e Lambda) Accept(v ExpressionVisitor) (any, error) {
 func (e Lambda) String() string {
 	return fmt.Sprintf("Lambda{Params: %+v, Body: %+v}", e.Params, e.Body)
 }
+
+// property access, e.g. obj.name
+type Get struct {
+	Object Expr
+	Name   token.Token
+}
+
+func (e Get) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitGet(e)
+}
+
+// property assignment, e.g. obj.name = value
+type Set struct {
+	Object Expr
+	Name   token.Token
+	Value  Expr
+}
+
+func (e Set) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitSet(e)
+}
+
+type This struct {
+	Keyword token.Token
+}
+
+func (e This) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitThis(e)
+}
+
+// superclass method access, e.g. super.method
+type Super struct {
+	Keyword token.Token
+	Method  token.Token
+}
+
+func (e Super) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitSuper(e)
+}
+
+// list literal, e.g. [1, 2, 3]
+type List struct {
+	Bracket  token.Token
+	Elements []Expr
+}
+
+func (e List) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitList(e)
+}
+
+// subscript, e.g. xs[i] or m[key]
+type Index struct {
+	Object Expr
+	// used to report error on the location of the opening bracket
+	Bracket token.Token
+	Index   Expr
+}
+
+func (e Index) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitIndex(e)
+}
+
+// subscript assignment, e.g. xs[i] = value or m[key] = value
+type IndexSet struct {
+	Object  Expr
+	Bracket token.Token
+	Index   Expr
+	Value   Expr
+}
+
+func (e IndexSet) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitIndexSet(e)
+}
+
+// map literal, e.g. {"a": 1, "b": 2}
+type Map struct {
+	Brace  token.Token
+	Keys   []Expr
+	Values []Expr
+}
+
+func (e Map) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitMap(e)
+}
+
+// string interpolation, e.g. "Hello ${name}!". the values of the parts are concatenated.
+type Interpolation struct {
+	// Quote is the start of the string, up to the first interpolated expression.
+	Quote token.Token
+	Parts []Expr
+}
+
+func (e Interpolation) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitInterpolation(e)
+}
+
+// Arm is a case of a match, without its body.
+// it matches if any of its patterns does, and then its guard, if any, is truthy.
+type Arm struct {
+	Case     token.Token
+	Patterns []Pattern
+	// nil if the arm has no guard
+	Guard Expr
+}
+
+// match expression, e.g. match (x) { case 1, 2 => "small", case _ => "big" }.
+// evaluates to the body of the first arm that matches the value.
+type Match struct {
+	Keyword token.Token
+	Value   Expr
+	Arms    []Arm
+	// Bodies are the bodies of the arms, in the same order.
+	Bodies []Expr
+}
+
+func (e Match) Accept(v ExpressionVisitor) (any, error) {
+	return v.VisitMatch(e)
+}
@@ -0,0 +1,45 @@
+package ast
+
+import "github.com/taehioum/glox/pkg/token"
+
+// Pattern is what the value of a match is tested against, in a case of a match arm.
+type Pattern interface {
+	pattern()
+}
+
+// LiteralPattern matches values equal to the literal, e.g. case 1 or case "a".
+type LiteralPattern struct {
+	// Value is a literal, or a negated number literal.
+	Value Expr
+}
+
+// BindingPattern matches any value, and binds it to the name in the arm, e.g. case x.
+type BindingPattern struct {
+	Name token.Token
+}
+
+// WildcardPattern matches any value without binding it, e.g. case _.
+type WildcardPattern struct {
+	Underscore token.Token
+}
+
+// ListPattern matches lists of as many elements as it has, each matching its pattern, e.g. case [a, 2].
+type ListPattern struct {
+	Bracket  token.Token
+	Elements []Pattern
+}
+
+// MapPattern matches maps that have all of its keys, with values matching their patterns, e.g. case {"x": x}.
+// keys the pattern doesn't mention are ignored.
+type MapPattern struct {
+	Brace token.Token
+	// Keys are literals, like the ones of literal patterns.
+	Keys   []Expr
+	Values []Pattern
+}
+
+func (LiteralPattern) pattern()  {}
+func (BindingPattern) pattern()  {}
+func (WildcardPattern) pattern() {}
+func (ListPattern) pattern()     {}
+func (MapPattern) pattern()      {}
@@ -12,10 +12,17 @@ type StatementVistior interface {
 	VisitBlock(Block) error
 	VisitIf(If) error
 	VisitWhile(While) error
+	VisitForIn(ForIn) error
 	VisitBreak(Break) error
 	VisitContinue(Continue) error
 	VisitReturn(Return) error
+	VisitYield(Yield) error
 	VisitExpression(Expression) error
+	VisitClass(Class) error
+	VisitImport(Import) error
+	VisitThrow(Throw) error
+	VisitTry(Try) error
+	VisitMatchStmt(MatchStmt) error
 }
 
 type Stmt interface {
@@ -86,7 +93,23 @@ func (stmt While) Accept(v StatementVistior) error {
 	return v.VisitWhile(stmt)
 }
 
-type Break struct{}
+// ForIn runs the body for each value of the iterable, bound to the name, e.g. for (var x in xs) body.
+type ForIn struct {
+	Keyword token.Token
+	Name    token.Token
+	// In is used to report errors about the iterable.
+	In       token.Token
+	Iterable Expr
+	Body     Stmt
+}
+
+func (stmt ForIn) Accept(v StatementVistior) error {
+	return v.VisitForIn(stmt)
+}
+
+type Break struct {
+	Keyword token.Token
+}
 
 func (stmt Break) Accept(v StatementVistior) error {
 	return v.VisitBreak(stmt)
@@ -96,7 +119,9 @@ func (stmt Break) String() string {
 	return "Break{}"
 }
 
-type Continue struct{}
+type Continue struct {
+	Keyword token.Token
+}
 
 func (stmt Continue) Accept(v StatementVistior) error {
 	return v.VisitContinue(stmt)
@@ -114,3 +139,96 @@ type Return struct {
 func (stmt Return) Accept(v StatementVistior) error {
 	return v.VisitReturn(stmt)
 }
+
+// Yield suspends the generator it is in, handing the value to whoever resumed it, e.g. yield x;
+type Yield struct {
+	Keyword token.Token
+	// nil if there is no value
+	Value Expr
+}
+
+func (stmt Yield) Accept(v StatementVistior) error {
+	return v.VisitYield(stmt)
+}
+
+type Class struct {
+	Name token.Token
+	// nil if the class does not inherit from another class
+	Superclass *Variable
+	Methods    []Lambda
+}
+
+func (stmt Class) Accept(v StatementVistior) error {
+	return v.VisitClass(stmt)
+}
+
+func (stmt Class) String() string {
+	return fmt.Sprintf("Class{Name: %s, Superclass: %v, Methods: %+v}", stmt.Name, stmt.Superclass, stmt.Methods)
+}
+
+// Import binds the module at the path, relative to the importing file, to the name.
+type Import struct {
+	Keyword token.Token
+	// Path is the string literal of the path.
+	Path token.Token
+	Name token.Token
+}
+
+func (stmt Import) Accept(v StatementVistior) error {
+	return v.VisitImport(stmt)
+}
+
+func (stmt Import) String() string {
+	return fmt.Sprintf("Import{Path: %v, Name: %s}", stmt.Path.Literal, stmt.Name)
+}
+
+type Throw struct {
+	Keyword token.Token
+	Value   Expr
+}
+
+func (stmt Throw) Accept(v StatementVistior) error {
+	return v.VisitThrow(stmt)
+}
+
+func (stmt Throw) String() string {
+	return fmt.Sprintf("Throw{Value: %v}", stmt.Value)
+}
+
+// Try runs the body, and the catch block if it throws. the finally block runs after either, no matter what.
+// either the catch or the finally block may be missing, but not both.
+type Try struct {
+	Keyword token.Token
+	Body    Block
+	// Name is bound to the thrown value in the catch block.
+	Name token.Token
+	// nil if there is no catch block
+	Catch *Block
+	// nil if there is no finally block
+	Finally *Block
+}
+
+func (stmt Try) Accept(v StatementVistior) error {
+	return v.VisitTry(stmt)
+}
+
+func (stmt Try) String() string {
+	return fmt.Sprintf("Try{Body: %v, Name: %s, Catch: %v, Finally: %v}", stmt.Body, stmt.Name, stmt.Catch, stmt.Finally)
+}
+
+// MatchStmt runs the body of the first arm that matches the value, like the match expression does.
+type MatchStmt struct {
+	Keyword token.Token
+	Value   Expr
+	Arms    []Arm
+	// Bodies are the bodies of the arms, in the same order.
+	Bodies []Stmt
+}
+
+func (stmt MatchStmt) Accept(v StatementVistior) error {
+	return v.VisitMatchStmt(stmt)
+}
+
+func (stmt MatchStmt) String() string {
+	return fmt.Sprintf("Match{Value: %v, Arms: %v, Bodies: %v}", stmt.Value, stmt.Arms, stmt.Bodies)
+}
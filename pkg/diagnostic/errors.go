@@ -0,0 +1,147 @@
+// Package diagnostic defines the errors reported by each phase of running a program.
+// all of them carry the position of the offending token, and can be matched with errors.As.
+package diagnostic
+
+import (
+	"fmt"
+
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Position locates an error in the source.
+type Position struct {
+	// empty when the source was not read from a file, e.g. the prompt.
+	File string
+	Line int
+	// Column starts from 1. zero if unknown.
+	Column int
+}
+
+// At returns the position of the token.
+func At(tok token.Token) Position {
+	return Position{File: tok.File, Line: tok.Ln, Column: tok.Col}
+}
+
+func (p Position) String() string {
+	if p.File != "" {
+		return fmt.Sprintf("%s:%d:%d", p.File, p.Line, p.Column)
+	}
+	return fmt.Sprintf("line %d:%d", p.Line, p.Column)
+}
+
+// ScanError is reported for source that can't be split into tokens.
+// its token holds the characters scanned so far.
+type ScanError struct {
+	Position
+	Token token.Token
+	Msg   string
+}
+
+func NewScanError(tok token.Token, format string, args ...any) *ScanError {
+	return &ScanError{Position: At(tok), Token: tok, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+}
+
+// ParseError is reported for tokens that don't form a valid program.
+type ParseError struct {
+	Position
+	Token token.Token
+	Msg   string
+}
+
+func NewParseError(tok token.Token, format string, args ...any) *ParseError {
+	return &ParseError{Position: At(tok), Token: tok, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *ParseError) Error() string {
+	if e.Token.Type == token.EOF {
+		return fmt.Sprintf("%s at end: %s", e.Position, e.Msg)
+	}
+	return fmt.Sprintf("%s at '%s': %s", e.Position, e.Token.Lexeme, e.Msg)
+}
+
+// ResolveError is reported for programs that are syntactically valid, but misuse names or keywords,
+// e.g. 'this' outside of a class.
+type ResolveError struct {
+	Position
+	Token token.Token
+	Msg   string
+}
+
+func NewResolveError(tok token.Token, format string, args ...any) *ResolveError {
+	return &ResolveError{Position: At(tok), Token: tok, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("%s at '%s': %s", e.Position, e.Token.Lexeme, e.Msg)
+}
+
+// CompileError is reported by the bytecode compiler, for programs exceeding its limits.
+type CompileError struct {
+	Position
+	Token token.Token
+	Msg   string
+}
+
+func NewCompileError(tok token.Token, format string, args ...any) *CompileError {
+	return &CompileError{Position: At(tok), Token: tok, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+}
+
+// RuntimeError is reported while running a program.
+type RuntimeError struct {
+	Position
+	Token token.Token
+	Msg   string
+	// Err is the error that caused this one, e.g. the error returned by a native function.
+	Err error
+	// Hint is an optional suggestion on how to fix the error.
+	Hint string
+	// Stack holds the calls that were running when the error was raised, innermost first.
+	// it is empty for errors raised outside of any call.
+	Stack []Frame
+}
+
+// Frame is a call that was running when a runtime error was raised.
+type Frame struct {
+	// Function is the name of the called function, as it is printed. e.g. "<fn fib>".
+	Function string
+	// Native is set for functions implemented in Go, which have no position of their own.
+	Native bool
+	// Call is the token of the call, in the calling frame.
+	// it is the zero token for the frame of the top-level script.
+	Call token.Token
+	// Position is where the frame was running at: the position of the error for the innermost frame,
+	// and the call into the next frame for the others.
+	Position
+}
+
+func (f Frame) String() string {
+	if f.Native {
+		return fmt.Sprintf("at %s", f.Function)
+	}
+	return fmt.Sprintf("at %s (%s)", f.Function, f.Position)
+}
+
+func NewRuntimeError(tok token.Token, format string, args ...any) *RuntimeError {
+	return &RuntimeError{Position: At(tok), Token: tok, Msg: fmt.Sprintf(format, args...)}
+}
+
+// WrapRuntimeError positions an error raised without a token at hand, at the given token.
+func WrapRuntimeError(tok token.Token, err error) *RuntimeError {
+	return &RuntimeError{Position: At(tok), Token: tok, Msg: err.Error(), Err: err}
+}
+
+func (e *RuntimeError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Position, e.Msg)
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,174 @@
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taehioum/glox/pkg/token"
+)
+
+const (
+	colorReset = "\x1b[0m"
+	colorBold  = "\x1b[1m"
+	colorRed   = "\x1b[31m"
+	colorBlue  = "\x1b[34m"
+	colorCyan  = "\x1b[36m"
+)
+
+// Renderer renders errors along with the line of source they point at, e.g.
+//
+//	runtime error: undefined variable 'coutn'
+//	  --> script.lox:3:7
+//	   |
+//	 3 | print(coutn);
+//	   |       ^~~~~
+//	   = hint: did you mean 'count'?
+type Renderer struct {
+	// Sources maps file names to their contents. sources that are not files, e.g. the prompt, are keyed by "".
+	Sources map[string]string
+	// Color highlights the output with ANSI escape codes.
+	Color bool
+}
+
+// IsTerminal reports whether the file is a terminal, to decide whether to render in color.
+func IsTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Render writes the error to w. errors joined with errors.Join are rendered one after another,
+// even when wrapped, e.g. with fmt.Errorf("running: %w", err). errors without a position are written as they are.
+func (r Renderer) Render(w io.Writer, err error) {
+	if errs, ok := split(err); ok {
+		for _, e := range errs {
+			r.Render(w, e)
+		}
+		return
+	}
+
+	kind, tok, msg, hint, stack, ok := describe(err)
+	if !ok {
+		fmt.Fprintf(w, "%s %s\n", r.paint(colorBold+colorRed, "error:"), err)
+		return
+	}
+
+	// the line number is in the gutter of the source line, everything else is aligned with it.
+	gutter := fmt.Sprint(tok.Ln)
+	pad := strings.Repeat(" ", len(gutter))
+
+	fmt.Fprintf(w, "%s %s\n", r.paint(colorBold+colorRed, kind+":"), msg)
+	fmt.Fprintf(w, " %s%s %s\n", pad, r.paint(colorBlue, "-->"), At(tok))
+	if line, ok := r.line(tok); ok {
+		fmt.Fprintf(w, " %s %s\n", pad, r.paint(colorBlue, "|"))
+		fmt.Fprintf(w, " %s %s %s\n", r.paint(colorBlue, gutter), r.paint(colorBlue, "|"), line)
+		fmt.Fprintf(w, " %s %s %s\n", pad, r.paint(colorBlue, "|"), r.paint(colorBold+colorRed, underline(line, tok)))
+	}
+	if hint != "" {
+		fmt.Fprintf(w, " %s %s %s\n", pad, r.paint(colorBlue, "="), r.paint(colorCyan, "hint: "+hint))
+	}
+	if len(stack) > 0 {
+		fmt.Fprintf(w, " %s %s stack, innermost first:\n", pad, r.paint(colorBlue, "="))
+		for idx, f := range stack {
+			// deep stacks, e.g. of unbounded recursion, only show their ends.
+			if len(stack) > 2*stackEnds && idx == stackEnds {
+				fmt.Fprintf(w, " %s     ... %d more frames\n", pad, len(stack)-2*stackEnds)
+			}
+			if len(stack) > 2*stackEnds && idx >= stackEnds && idx < len(stack)-stackEnds {
+				continue
+			}
+			fmt.Fprintf(w, " %s     %s\n", pad, f)
+		}
+	}
+}
+
+// split returns the errors joined with errors.Join, under the errors wrapping them.
+// positioned errors are rendered as a whole, so the errors they wrap aren't split.
+func split(err error) ([]error, bool) {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		switch e.(type) {
+		case *ScanError, *ParseError, *ResolveError, *CompileError, *RuntimeError:
+			return nil, false
+		}
+		if joined, ok := e.(interface{ Unwrap() []error }); ok {
+			return joined.Unwrap(), true
+		}
+	}
+	return nil, false
+}
+
+// stackEnds is how many frames are shown from either end of a deep stack.
+const stackEnds = 10
+
+// describe extracts what is rendered from the positioned errors.
+func describe(err error) (kind string, tok token.Token, msg string, hint string, stack []Frame, ok bool) {
+	var scanErr *ScanError
+	var parseErr *ParseError
+	var resolveErr *ResolveError
+	var compileErr *CompileError
+	var runtimeErr *RuntimeError
+	switch {
+	case errors.As(err, &scanErr):
+		return "scan error", scanErr.Token, scanErr.Msg, "", nil, true
+	case errors.As(err, &parseErr):
+		return "parse error", parseErr.Token, parseErr.Msg, "", nil, true
+	case errors.As(err, &resolveErr):
+		return "resolve error", resolveErr.Token, resolveErr.Msg, "", nil, true
+	case errors.As(err, &compileErr):
+		return "compile error", compileErr.Token, compileErr.Msg, "", nil, true
+	case errors.As(err, &runtimeErr):
+		return "runtime error", runtimeErr.Token, runtimeErr.Msg, runtimeErr.Hint, runtimeErr.Stack, true
+	}
+	return "", token.Token{}, "", "", nil, false
+}
+
+// line returns the source line the token is on.
+func (r Renderer) line(tok token.Token) (string, bool) {
+	source, ok := r.Sources[tok.File]
+	if !ok || tok.Ln < 1 {
+		return "", false
+	}
+	lines := strings.Split(source, "\n")
+	if tok.Ln > len(lines) {
+		return "", false
+	}
+	return strings.TrimRight(lines[tok.Ln-1], "\r"), true
+}
+
+// underline marks the token on its line with ^~~~.
+// tokens spanning several lines, e.g. strings, are underlined up to the end of the first line.
+func underline(line string, tok token.Token) string {
+	// columns count characters, rather than bytes.
+	prefix := []rune(line)
+	prefix = prefix[:min(max(tok.Col-1, 0), len(prefix))]
+	// keep the tabs, so that the underline is aligned with the line.
+	var sb strings.Builder
+	for _, c := range prefix {
+		if c == '\t' {
+			sb.WriteRune('\t')
+		} else {
+			sb.WriteRune(' ')
+		}
+	}
+
+	lexeme, _, _ := strings.Cut(tok.Lexeme, "\n")
+	width := utf8.RuneCountInString(lexeme)
+	sb.WriteString("^")
+	if width > 1 {
+		sb.WriteString(strings.Repeat("~", width-1))
+	}
+	return sb.String()
+}
+
+func (r Renderer) paint(color string, s string) string {
+	if !r.Color {
+		return s
+	}
+	return color + s + colorReset
+}
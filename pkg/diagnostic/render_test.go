@@ -0,0 +1,84 @@
+package diagnostic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+func TestRender(t *testing.T) {
+	source := "var count = 1;\nfun f() {\n\tprint(coutn);\n}\n"
+	tok := token.Token{Type: token.IDENTIFIER, Lexeme: "coutn", Ln: 3, Col: 8, File: "script.lox"}
+	rerr := NewRuntimeError(tok, "undefined variable '%s'", tok.Lexeme)
+	rerr.Hint = "did you mean 'count'?"
+
+	var sb strings.Builder
+	r := Renderer{Sources: map[string]string{"script.lox": source}}
+	r.Render(&sb, fmt.Errorf("running: %w", rerr))
+
+	expected := `runtime error: undefined variable 'coutn'
+  --> script.lox:3:8
+   |
+ 3 | 	print(coutn);
+   | 	      ^~~~~
+   = hint: did you mean 'count'?
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestRenderUnicode(t *testing.T) {
+	source := "var 名前 = \"日本\" + nil;\n"
+	tok := token.Token{Type: token.PLUS, Lexeme: "+", Ln: 1, Col: 15}
+
+	var sb strings.Builder
+	r := Renderer{Sources: map[string]string{"": source}}
+	r.Render(&sb, NewRuntimeError(tok, "operands must be two numbers or two strings"))
+
+	expected := `runtime error: operands must be two numbers or two strings
+  --> line 1:15
+   |
+ 1 | var 名前 = "日本" + nil;
+   |               ^
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestRenderJoined(t *testing.T) {
+	source := "var = 1;\nprint(1)\n"
+	err := errors.Join(
+		NewParseError(token.Token{Type: token.EQUAL, Lexeme: "=", Ln: 1, Col: 5}, "expected variable name"),
+		NewParseError(token.Token{Type: token.EOF, Ln: 3, Col: 1}, "expected ';' after expression"),
+		errors.New("not positioned"),
+	)
+
+	var sb strings.Builder
+	r := Renderer{Sources: map[string]string{"": source}}
+	r.Render(&sb, err)
+
+	expected := `parse error: expected variable name
+  --> line 1:5
+   |
+ 1 | var = 1;
+   |     ^
+parse error: expected ';' after expression
+  --> line 3:1
+   |
+ 3 | 
+   | ^
+error: not positioned
+`
+	assert.Equal(t, expected, sb.String())
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"count", "counter", "print", "clock"}
+	assert.Equal(t, "count", Suggest("coutn", candidates))
+	assert.Equal(t, "print", Suggest("pritn", candidates))
+	assert.Equal(t, "counter", Suggest("countre", candidates))
+	assert.Equal(t, "", Suggest("xyz", candidates))
+	assert.Equal(t, "", Suggest("count", []string{"count"}))
+}
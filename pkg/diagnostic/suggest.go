@@ -0,0 +1,53 @@
+package diagnostic
+
+import "sort"
+
+// Suggest returns the candidate closest to the name, for hints like "did you mean 'count'?".
+// it returns an empty string if no candidate is close enough to be a likely typo.
+func Suggest(name string, candidates []string) string {
+	// allow a single typo in short names, and one more for every three characters.
+	limit := max(1, len([]rune(name))/3)
+
+	sorted := append([]string(nil), candidates...)
+	sort.Strings(sorted)
+
+	best, bestDistance := "", limit+1
+	for _, c := range sorted {
+		if c == name {
+			continue
+		}
+		if d := distance(name, c); d < bestDistance {
+			best, bestDistance = c, d
+		}
+	}
+	return best
+}
+
+// distance is the optimal string alignment distance: the number of insertions, deletions, substitutions
+// and transpositions of adjacent characters needed to turn a into b.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// d[i][j] is the distance between the first i runes of a, and the first j runes of b.
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
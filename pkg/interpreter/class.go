@@ -0,0 +1,76 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+type Class struct {
+	Name       string
+	superclass *Class
+	methods    map[string]Function
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+// findMethod looks up a method, walking up the superclass chain.
+func (c *Class) findMethod(name string) (Function, bool) {
+	if m, ok := c.methods[name]; ok {
+		return m, true
+	}
+	if c.superclass != nil {
+		return c.superclass.findMethod(name)
+	}
+	return Function{}, false
+}
+
+// Arity of a class is the arity of its initializer, if any.
+func (c *Class) Arity() int {
+	if init, ok := c.findMethod("init"); ok {
+		return init.Arity()
+	}
+	return 0
+}
+
+// Call instantiates the class, running the initializer if there is one.
+func (c *Class) Call(i *Interpreter, args []any) (any, error) {
+	instance := &Instance{
+		class:  c,
+		fields: make(map[string]any),
+	}
+	if init, ok := c.findMethod("init"); ok {
+		if _, err := init.bind(instance).Call(i, args); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
+}
+
+type Instance struct {
+	class  *Class
+	fields map[string]any
+}
+
+func (in *Instance) String() string {
+	return fmt.Sprintf("%s instance", in.class.Name)
+}
+
+// Get looks up a property, fields shadowing methods.
+func (in *Instance) Get(name token.Token) (any, error) {
+	if v, ok := in.fields[name.Lexeme]; ok {
+		return v, nil
+	}
+	if m, ok := in.class.findMethod(name.Lexeme); ok {
+		return m.bind(in), nil
+	}
+	return nil, diagnostic.NewRuntimeError(name, "undefined property '%s'", name.Lexeme)
+}
+
+func (in *Instance) Set(name token.Token, value any) error {
+	in.fields[name.Lexeme] = value
+	return nil
+}
@@ -0,0 +1,81 @@
+package interpreter
+
+import (
+	"math/big"
+	"strings"
+)
+
+// DefaultDecimalPrecision is how many digits after the decimal point the quotients of decimals are rounded to.
+const DefaultDecimalPrecision = 28
+
+// Decimal is an exact decimal number, written with a d suffix, e.g. 0.10d.
+// sums, differences and products of decimals are exact, while quotients are rounded to the precision of the interpreter.
+type Decimal struct {
+	r *big.Rat
+}
+
+// NewDecimal returns the decimal of the rational, which is copied.
+// rationals that can't be written with finitely many digits are printed rounded to DefaultDecimalPrecision digits.
+func NewDecimal(r *big.Rat) Decimal {
+	return Decimal{r: new(big.Rat).Set(r)}
+}
+
+// Rat returns the value of the decimal.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).Set(d.r)
+}
+
+// String formats the decimal with as many digits as it has, e.g. 0.1 rather than 1/10.
+func (d Decimal) String() string {
+	s := d.r.FloatString(scale(d.r))
+	if strings.Contains(s, ".") {
+		s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	}
+	return s
+}
+
+// scale is the number of digits after the decimal point needed to write the rational,
+// capped at DefaultDecimalPrecision for the rationals that have infinitely many.
+func scale(r *big.Rat) int {
+	den := new(big.Int).Set(r.Denom())
+	twos := int(den.TrailingZeroBits())
+	den.Rsh(den, uint(twos))
+	fives := 0
+	five := big.NewInt(5)
+	m := new(big.Int)
+	for {
+		q, rem := new(big.Int).QuoRem(den, five, m)
+		if rem.Sign() != 0 {
+			break
+		}
+		den = q
+		fives++
+	}
+	if den.Cmp(big.NewInt(1)) != 0 {
+		return DefaultDecimalPrecision
+	}
+	return max(twos, fives)
+}
+
+// round rounds the rational to the digits after the decimal point, halfway cases to even.
+func round(r *big.Rat, digits int) *big.Rat {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	scaled := new(big.Rat).Mul(r, new(big.Rat).SetInt(unit))
+	n := floor(scaled)
+	frac := new(big.Rat).Sub(scaled, new(big.Rat).SetInt(n))
+	switch frac.Cmp(big.NewRat(1, 2)) {
+	case 1:
+		n.Add(n, big.NewInt(1))
+	case 0:
+		if n.Bit(0) == 1 {
+			n.Add(n, big.NewInt(1))
+		}
+	}
+	return new(big.Rat).SetFrac(n, unit)
+}
+
+// floor is the greatest integer less than or equal to the rational.
+func floor(r *big.Rat) *big.Int {
+	// the denominator is positive, so the euclidean division rounds down.
+	return new(big.Int).Div(r.Num(), r.Denom())
+}
@@ -0,0 +1,61 @@
+package interpreter
+
+import "fmt"
+
+// Value is a script value, as seen by the host.
+// it is one of nil, bool, int64, *big.Int, Decimal, float64, string, *List, *Map, *Instance, *HostObject, *Namespace, *ErrorValue or a Callable.
+type Value = any
+
+// Native is a function implemented by the host.
+type Native struct {
+	name string
+	// -1 for variadic functions
+	arity int
+	fn    func(args []Value) (Value, error)
+}
+
+// NewNative returns a function implemented by the host, to be called from scripts.
+// an arity of -1 accepts any number of arguments.
+func NewNative(name string, arity int, fn func(args []Value) (Value, error)) *Native {
+	return &Native{name: name, arity: arity, fn: fn}
+}
+
+func (n *Native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.name)
+}
+
+func (n *Native) Arity() int {
+	return n.arity
+}
+
+func (n *Native) Call(i *Interpreter, args []any) (v any, err error) {
+	// a panic fails the program rather than the host, as with go functions.
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: panic: %v", n.name, r)
+		}
+	}()
+	return n.fn(args)
+}
+
+// DefineNative defines a global function implemented by the host, visible to imported modules as well.
+// errors it returns are raised as runtime errors at the call.
+func (i *Interpreter) DefineNative(name string, arity int, fn func(args []Value) (Value, error)) {
+	i.builtins.Define(name, NewNative(name, arity, fn))
+}
+
+// SetGlobal defines a global variable of the program, or overwrites it if it is already defined.
+// unlike natives, it is not visible to imported modules.
+// Go structs and maps are exposed to scripts with Expose first.
+func (i *Interpreter) SetGlobal(name string, v Value) {
+	i.global.Define(name, v)
+}
+
+// GetGlobal returns the value of a global variable, and whether it is defined.
+func (i *Interpreter) GetGlobal(name string) (Value, bool) {
+	v, err := i.global.Get(name)
+	if err != nil {
+		return nil, false
+	}
+	return v, true
+}
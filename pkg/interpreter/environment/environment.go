@@ -52,10 +52,31 @@ func (env *Environment) Get(name string) (any, error) {
 	return env.enclosing.Get(name)
 }
 
+// Lookup gets the value of the name defined in this environment, ignoring the enclosing ones.
+func (env *Environment) Lookup(name string) (any, bool) {
+	v, ok := env.values[name]
+	return v, ok
+}
+
 func (env *Environment) GetAt(distance int, name string) (any, error) {
 	return env.ancestor(distance).values[name], nil
 }
 
+// Names returns the names visible from the environment, including the ones of the enclosing environments.
+func (env *Environment) Names() []string {
+	var names []string
+	for e := env; e != nil; e = e.enclosing {
+		for name := range e.values {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (env *Environment) Enclosing() *Environment {
+	return env.enclosing
+}
+
 func (env *Environment) ancestor(distance int) *Environment {
 	e := env
 	for i := 0; i < distance; i++ {
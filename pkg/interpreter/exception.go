@@ -0,0 +1,106 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter/environment"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Thrown is the error of a throw statement, carrying the thrown value up to the catch block.
+type Thrown struct {
+	Value any
+}
+
+func (e *Thrown) Error() string {
+	if errValue, ok := e.Value.(*ErrorValue); ok {
+		// rethrown runtime errors read as they did originally.
+		return errValue.Message
+	}
+	return fmt.Sprintf("uncaught exception: %s", repr(e.Value))
+}
+
+// ErrorValue is how runtime errors are seen by catch blocks. its message and line are read as properties.
+type ErrorValue struct {
+	Message string
+	Line    int
+}
+
+func (e *ErrorValue) String() string {
+	return fmt.Sprintf("<error: %s>", e.Message)
+}
+
+func (e *ErrorValue) Get(name token.Token) (any, error) {
+	switch name.Lexeme {
+	case "message":
+		return e.Message, nil
+	case "line":
+		return int64(e.Line), nil
+	default:
+		return nil, diagnostic.NewRuntimeError(name, "undefined property '%s'", name.Lexeme)
+	}
+}
+
+func (e *ErrorValue) Set(name token.Token, value any) error {
+	return diagnostic.NewRuntimeError(name, "can't set property '%s' of an error", name.Lexeme)
+}
+
+func (i *Interpreter) VisitThrow(stmt ast.Throw) error {
+	v, err := i.Eval(stmt.Value)
+	if err != nil {
+		return err
+	}
+	return diagnostic.WrapRuntimeError(stmt.Keyword, &Thrown{Value: v})
+}
+
+func (i *Interpreter) VisitTry(stmt ast.Try) error {
+	err := i.execute(stmt.Body)
+	if stmt.Catch != nil {
+		if v, ok := catchable(err); ok {
+			err = i.catch(stmt, v)
+		}
+	}
+	if stmt.Finally != nil {
+		// a break, continue, return or error of the finally block takes over the one of the body.
+		if ferr := i.execute(*stmt.Finally); ferr != nil {
+			return ferr
+		}
+	}
+	return err
+}
+
+// catch runs the catch block, with the thrown value bound to its name.
+func (i *Interpreter) catch(stmt ast.Try, v any) error {
+	prev := i.env
+	defer func() {
+		// restore env
+		i.env = prev
+	}()
+	i.env = environment.NewEnclosedEnvironment(prev)
+	i.env.Define(stmt.Name.Lexeme, v)
+	return i.execute(*stmt.Catch)
+}
+
+// catchable returns the value a catch block sees for the error, and whether it can be caught at all.
+// control flow, and exceeding the limits of the interpreter, can't be caught.
+func catchable(err error) (any, bool) {
+	if err == nil || errors.Is(err, ErrBreak) || errors.Is(err, ErrContinue) || errors.As(err, new(ErrReturn)) || errors.Is(err, errClosed) {
+		return nil, false
+	}
+	if errors.As(err, new(*CancelledError)) || errors.As(err, new(*StepLimitError)) || errors.As(err, new(*CallDepthError)) {
+		return nil, false
+	}
+
+	var thrown *Thrown
+	if errors.As(err, &thrown) {
+		return thrown.Value, true
+	}
+	var rerr *diagnostic.RuntimeError
+	if errors.As(err, &rerr) {
+		return &ErrorValue{Message: rerr.Msg, Line: rerr.Line}, true
+	}
+	return nil, false
+}
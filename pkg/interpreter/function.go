@@ -11,18 +11,50 @@ import (
 type Function struct {
 	def     statements.Lambda
 	closure *environment.Environment
+	// globals of the module the function is defined in, for the names the resolver left to the globals.
+	globals *environment.Environment
+
+	// initializers always return 'this'
+	isInitializer bool
 }
 
 func (f Function) Arity() int {
 	return len(f.def.Params)
 }
 
+func (f Function) String() string {
+	return fmt.Sprintf("<fn %s>", f.def.Name.Lexeme)
+}
+
+// bind returns a copy of the method, whose closure has 'this' bound to the instance.
+func (f Function) bind(instance *Instance) Function {
+	env := environment.NewEnclosedEnvironment(f.closure)
+	env.Define("this", instance)
+	return Function{
+		def:           f.def,
+		closure:       env,
+		globals:       f.globals,
+		isInitializer: f.isInitializer,
+	}
+}
+
 func (f Function) Call(i *Interpreter, args []any) (any, error) {
-	prev := i.env
+	if f.def.Generator {
+		// the body runs as the generator is resumed.
+		return newGenerator(i, f, args), nil
+	}
+	if i.limits.CallDepth > 0 && i.depth >= i.limits.CallDepth {
+		return nil, &CallDepthError{Limit: i.limits.CallDepth}
+	}
+	i.depth++
+
+	prev, prevGlobal := i.env, i.global
 	defer func() {
 		// restore env
-		i.env = prev
+		i.env, i.global = prev, prevGlobal
+		i.depth--
 	}()
+	i.global = f.globals
 	i.env = environment.NewEnclosedEnvironment(f.closure)
 	for idx, param := range f.def.Params {
 		i.env.Define(param.Lexeme, args[idx])
@@ -31,10 +63,16 @@ func (f Function) Call(i *Interpreter, args []any) (any, error) {
 	err := i.Interprete(f.def.Body...)
 	var res ErrReturn
 	if errors.As(err, &res) {
+		if f.isInitializer {
+			return f.closure.GetAt(0, "this")
+		}
 		return res.Value, nil
 	} else if err != nil {
-		return nil, fmt.Errorf("calling %s defined on line %d: %w", f.def.Name.Lexeme, f.def.Name.Ln, err)
+		return nil, err
 	}
 
+	if f.isInitializer {
+		return f.closure.GetAt(0, "this")
+	}
 	return nil, nil
 }
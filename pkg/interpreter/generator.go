@@ -0,0 +1,278 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter/environment"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// errClosed unwinds the body of a generator that is closed before it runs out, running its finally blocks.
+// it can't be caught.
+var errClosed = errors.New("generator closed")
+
+// Generator is returned by calling a function that yields. its body runs a piece at a time, up to the next yield,
+// whenever the generator is resumed by next(), or by a for-in loop.
+//
+// the body runs on a goroutine of its own, which takes turns with the one that resumes it, so that only one of them runs at a time.
+// a generator that is abandoned before it runs out, e.g. by a loop that breaks early, is closed, and one that is no longer reachable
+// has its goroutine stopped, without running its finally blocks. those still reachable once the program is done, e.g. from a global,
+// are closed by Interpreter.Close.
+type Generator struct {
+	fn Function
+	co *coroutine
+}
+
+type generatorState int
+
+const (
+	generatorCreated generatorState = iota
+	generatorSuspended
+	generatorRunning
+	generatorDone
+)
+
+// coroutine is the part of a generator its goroutine refers to, so that the generator itself can be collected while the goroutine is suspended.
+type coroutine struct {
+	// interpreter of the body, a copy of the one that called the function, with an environment of its own.
+	i     *Interpreter
+	body  []ast.Stmt
+	state generatorState
+	// whether the generator is being closed, so that the body can't yield anymore
+	closing bool
+
+	// resume is sent true to resume the body, and false to close it.
+	resume chan bool
+	// yield is sent the yielded values, and the result of the body when it is done.
+	yield chan yielded
+	// abandon is closed when the generator is collected.
+	abandon chan struct{}
+}
+
+// generators are the coroutines whose body has started and isn't done.
+// they aren't the generators themselves, so that those can still be collected.
+type generators struct {
+	// the finalizers of generators run on a goroutine of their own.
+	mu   sync.Mutex
+	live map[*coroutine]struct{}
+}
+
+func (gs *generators) add(co *coroutine) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	gs.live[co] = struct{}{}
+}
+
+func (gs *generators) remove(co *coroutine) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	delete(gs.live, co)
+}
+
+func (gs *generators) list() []*coroutine {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	cos := make([]*coroutine, 0, len(gs.live))
+	for co := range gs.live {
+		cos = append(cos, co)
+	}
+	return cos
+}
+
+type yielded struct {
+	value any
+	done  bool
+	err   error
+}
+
+func newGenerator(i *Interpreter, fn Function, args []any) *Generator {
+	gi := *i
+	gi.global = fn.globals
+	gi.env = environment.NewEnclosedEnvironment(fn.closure)
+	for idx, param := range fn.def.Params {
+		gi.env.Define(param.Lexeme, args[idx])
+	}
+	co := &coroutine{
+		i:       &gi,
+		body:    fn.def.Body,
+		resume:  make(chan bool),
+		yield:   make(chan yielded),
+		abandon: make(chan struct{}),
+	}
+	gi.coroutine = co
+
+	g := &Generator{fn: fn, co: co}
+	runtime.SetFinalizer(g, func(g *Generator) {
+		g.co.i.generators.remove(g.co)
+		close(g.co.abandon)
+	})
+	return g
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.fn.def.Name.Lexeme)
+}
+
+// next runs the body up to the next yield, returning the yielded value, and whether there was one.
+func (g *Generator) next(i *Interpreter) (any, bool, error) {
+	switch g.co.state {
+	case generatorDone:
+		return nil, false, nil
+	case generatorRunning:
+		return nil, false, fmt.Errorf("generator %s is already running", g.fn.def.Name.Lexeme)
+	}
+	y := g.co.switchTo(i, true)
+	if y.done {
+		return nil, false, y.err
+	}
+	return y.value, true, nil
+}
+
+// close unwinds the body of a suspended generator, so that its finally blocks run, and its goroutine exits.
+func (g *Generator) close(i *Interpreter) error {
+	return g.co.close(i)
+}
+
+func (co *coroutine) close(i *Interpreter) error {
+	switch co.state {
+	case generatorCreated:
+		co.state = generatorDone
+		return nil
+	case generatorSuspended:
+		return co.switchTo(i, false).err
+	default:
+		return nil
+	}
+}
+
+// Close closes the generators left suspended once the program is done, running their finally blocks.
+// the goroutines of those that are still reachable, e.g. from a global, would never exit otherwise.
+func (i *Interpreter) Close() error {
+	if !i.running {
+		defer i.startRun()()
+	}
+	var errs []error
+	for _, co := range i.generators.list() {
+		if err := co.close(i); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// switchTo hands over to the body of the generator, until it yields or is done.
+func (co *coroutine) switchTo(i *Interpreter, resume bool) yielded {
+	// the body runs as if called from where it is resumed, within the same limits.
+	co.i.steps, co.i.depth, co.i.frames = i.steps, i.depth, slices.Clip(i.frames)
+	if co.state == generatorCreated {
+		co.state = generatorRunning
+		i.generators.add(co)
+		go co.run()
+	} else {
+		co.state = generatorRunning
+		select {
+		case co.resume <- resume:
+		case <-co.abandon:
+			// only Close can still get hold of a collected generator. its goroutine has exited, or is about to.
+			co.state = generatorDone
+			return yielded{done: true}
+		}
+	}
+	y := <-co.yield
+	i.steps = co.i.steps
+
+	co.state = generatorSuspended
+	if y.done {
+		co.state = generatorDone
+		i.generators.remove(co)
+	}
+	return y
+}
+
+func (co *coroutine) run() {
+	err := co.i.Interprete(co.body...)
+	if errors.As(err, new(ErrReturn)) || errors.Is(err, errClosed) {
+		err = nil
+	}
+	co.yield <- yielded{done: true, err: err}
+}
+
+// suspend hands the value over to whoever resumed the generator, and waits to be resumed.
+func (co *coroutine) suspend(v any) error {
+	if co.closing {
+		return errClosed
+	}
+	co.yield <- yielded{value: v}
+	select {
+	case resume := <-co.resume:
+		if !resume {
+			co.closing = true
+			return errClosed
+		}
+		return nil
+	case <-co.abandon:
+		// nobody can resume the generator anymore. the deferred calls on the way out only restore the state of its own interpreter.
+		runtime.Goexit()
+		return nil
+	}
+}
+
+func (i *Interpreter) VisitYield(stmt ast.Yield) error {
+	var v any
+	if stmt.Value != nil {
+		var err error
+		v, err = i.Eval(stmt.Value)
+		if err != nil {
+			return err
+		}
+	}
+	return i.coroutine.suspend(v)
+}
+
+func (g *Generator) Get(name token.Token) (any, error) {
+	switch name.Lexeme {
+	case "next", "close":
+		return generatorMethod{generator: g, name: name.Lexeme}, nil
+	default:
+		return nil, diagnostic.NewRuntimeError(name, "undefined property '%s'", name.Lexeme)
+	}
+}
+
+func (g *Generator) Set(name token.Token, value any) error {
+	return diagnostic.NewRuntimeError(name, "can't set property '%s' of a generator", name.Lexeme)
+}
+
+// generatorMethod is the next() or close() method of a generator.
+// next() returns the next value, or done when the generator has run out.
+type generatorMethod struct {
+	generator *Generator
+	name      string
+}
+
+func (m generatorMethod) String() string {
+	return fmt.Sprintf("<native fn %s>", m.name)
+}
+
+func (m generatorMethod) Arity() int {
+	return 0
+}
+
+func (m generatorMethod) Call(i *Interpreter, args []any) (any, error) {
+	if m.name == "close" {
+		return nil, m.generator.close(i)
+	}
+	v, ok, err := m.generator.next(i)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return Done{}, nil
+	}
+	return v, nil
+}
@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"fmt"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// GoFunc adapts a Go func into a Callable, converting its arguments and results with reflection.
+// numbers, strings, bools and nil convert to Go's numeric, string and bool types, lists to slices, and maps to maps.
+type GoFunc struct {
+	name string
+	fn   reflect.Value
+}
+
+// NewGoFunc returns a Callable for the Go func, e.g. func(a int, b string) (string, error).
+// the func may return nothing, a value, an error, or a value and an error.
+// variadic funcs accept any number of arguments, like print.
+func NewGoFunc(name string, fn any) (*GoFunc, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("binding %s: expected a func, got %T", name, fn)
+	}
+	t := v.Type()
+	switch {
+	case t.NumOut() > 2:
+		return nil, fmt.Errorf("binding %s: expected at most 2 results, got %d", name, t.NumOut())
+	case t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("binding %s: expected the second result to be an error, got %s", name, t.Out(1))
+	}
+	return &GoFunc{name: name, fn: v}, nil
+}
+
+// DefineFunc defines a global function, implemented by the Go func, visible to imported modules as well. see NewGoFunc.
+func (i *Interpreter) DefineFunc(name string, fn any) error {
+	f, err := NewGoFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	i.builtins.Define(name, f)
+	return nil
+}
+
+func (f *GoFunc) String() string {
+	return fmt.Sprintf("<native fn %s>", f.name)
+}
+
+func (f *GoFunc) Arity() int {
+	if f.fn.Type().IsVariadic() {
+		return -1
+	}
+	return f.fn.Type().NumIn()
+}
+
+func (f *GoFunc) Call(i *Interpreter, args []any) (any, error) {
+	t := f.fn.Type()
+	fixed := t.NumIn()
+	if t.IsVariadic() {
+		fixed--
+		if len(args) < fixed {
+			return nil, fmt.Errorf("%s: expected at least %d arguments, got %d", f.name, fixed, len(args))
+		}
+	}
+
+	in := make([]reflect.Value, len(args))
+	for idx, arg := range args {
+		typ := t.In(min(idx, t.NumIn()-1))
+		if t.IsVariadic() && idx >= fixed {
+			typ = typ.Elem()
+		}
+		v, err := fromValue(arg, typ)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %w", f.name, idx+1, err)
+		}
+		in[idx] = v
+	}
+
+	out, err := f.call(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) > 0 && t.Out(len(out)-1) == errorType {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return toValue(out[0]), nil
+}
+
+// call calls the go function, turning a panic into an error, so that it fails the program rather than the host.
+func (f *GoFunc) call(in []reflect.Value) (out []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: panic: %v", f.name, r)
+		}
+	}()
+	return f.fn.Call(in), nil
+}
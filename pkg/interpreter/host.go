@@ -0,0 +1,309 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Object is a value with properties, read and written with '.'.
+type Object interface {
+	Get(name token.Token) (any, error)
+	Set(name token.Token, value any) error
+}
+
+// HostObject exposes a Go struct or map to scripts, as an object.
+// struct fields, or map entries, are its properties. exported methods can be called as well.
+// it is a view, rather than a copy: scripts read and write the Go value directly.
+type HostObject struct {
+	v reflect.Value
+}
+
+// Expose returns a script object for a Go struct, or a map with string keys.
+// pass a pointer to a struct for the host to see the changes made by scripts.
+//
+// fields are named after the Go field, unless renamed with a `glox:"name"` tag.
+// unexported fields, and fields tagged `glox:"-"`, are hidden.
+func Expose(v any) (*HostObject, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch {
+	case rv.Kind() == reflect.Struct:
+		if !rv.CanAddr() {
+			// a struct passed by value is copied, so that its fields can still be set.
+			p := reflect.New(rv.Type())
+			p.Elem().Set(rv)
+			rv = p.Elem()
+		}
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		if rv.IsNil() {
+			return nil, fmt.Errorf("exposing %T: nil map", v)
+		}
+	default:
+		return nil, fmt.Errorf("exposing %T: expected a struct or a map with string keys", v)
+	}
+	return &HostObject{v: rv}, nil
+}
+
+// Unwrap returns the exposed Go value.
+func (o *HostObject) Unwrap() any {
+	if o.v.CanAddr() {
+		return o.v.Addr().Interface()
+	}
+	return o.v.Interface()
+}
+
+func (o *HostObject) String() string {
+	return fmt.Sprint(o.v.Interface())
+}
+
+func (o *HostObject) Get(name token.Token) (any, error) {
+	if o.v.Kind() == reflect.Map {
+		entry := o.v.MapIndex(reflect.ValueOf(name.Lexeme).Convert(o.v.Type().Key()))
+		if entry.IsValid() {
+			return toValue(entry), nil
+		}
+	} else if field, ok := o.field(name.Lexeme); ok {
+		return toValue(field), nil
+	}
+
+	// methods with pointer receivers are only found on addressable values.
+	method := o.v.MethodByName(name.Lexeme)
+	if o.v.CanAddr() {
+		method = o.v.Addr().MethodByName(name.Lexeme)
+	}
+	if method.IsValid() {
+		f, err := NewGoFunc(name.Lexeme, method.Interface())
+		if err != nil {
+			return nil, diagnostic.WrapRuntimeError(name, err)
+		}
+		return f, nil
+	}
+	return nil, diagnostic.NewRuntimeError(name, "undefined property '%s'", name.Lexeme)
+}
+
+func (o *HostObject) Set(name token.Token, value any) error {
+	if o.v.Kind() == reflect.Map {
+		v, err := fromValue(value, o.v.Type().Elem())
+		if err != nil {
+			return diagnostic.WrapRuntimeError(name, err)
+		}
+		o.v.SetMapIndex(reflect.ValueOf(name.Lexeme).Convert(o.v.Type().Key()), v)
+		return nil
+	}
+
+	field, ok := o.field(name.Lexeme)
+	if !ok {
+		return diagnostic.NewRuntimeError(name, "undefined property '%s'", name.Lexeme)
+	}
+	if !field.CanSet() {
+		// e.g. a struct stored in a map, which is a copy.
+		return diagnostic.NewRuntimeError(name, "can't set property '%s' of a copied struct", name.Lexeme)
+	}
+	v, err := fromValue(value, field.Type())
+	if err != nil {
+		return diagnostic.WrapRuntimeError(name, err)
+	}
+	field.Set(v)
+	return nil
+}
+
+// field looks up the struct field exposed under the name.
+func (o *HostObject) field(name string) (reflect.Value, bool) {
+	t := o.v.Type()
+	for idx := 0; idx < t.NumField(); idx++ {
+		f := t.Field(idx)
+		if !f.IsExported() {
+			continue
+		}
+		fieldName := f.Name
+		if tag, ok := f.Tag.Lookup("glox"); ok {
+			fieldName = tag
+		}
+		if fieldName == name {
+			return o.v.Field(idx), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// toValue converts a Go value into a script value.
+// integers become int64, floats become float64, *big.Int and Decimal are passed as they are, slices become lists, structs and maps become host objects, and funcs become callables.
+func toValue(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	// big numbers are script values of their own, rather than host objects.
+	if v.CanInterface() {
+		switch n := v.Interface().(type) {
+		case *big.Int:
+			if n == nil {
+				return nil
+			}
+			return normalizeInt(new(big.Int).Set(n))
+		case Decimal:
+			return n
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return float64(v.Uint())
+		}
+		return int64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.String:
+		return v.String()
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		elements := make([]any, v.Len())
+		for idx := range elements {
+			elements[idx] = toValue(v.Index(idx))
+		}
+		return &List{Elements: elements}
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Kind() == reflect.Interface {
+			// script values stored in an interface are passed as they are.
+			if _, ok := v.Interface().(Callable); ok {
+				return v.Interface()
+			}
+			switch v.Interface().(type) {
+			case *List, *Map, *Instance, *HostObject, *Namespace, *ErrorValue:
+				return v.Interface()
+			}
+		}
+		return toValue(v.Elem())
+	case reflect.Struct:
+		return &HostObject{v: v}
+	case reflect.Func:
+		if v.IsNil() {
+			return nil
+		}
+		f, err := NewGoFunc(v.Type().String(), v.Interface())
+		if err != nil {
+			return nil
+		}
+		return f
+	case reflect.Map:
+		if v.IsNil() || v.Type().Key().Kind() != reflect.String {
+			return nil
+		}
+		return &HostObject{v: v}
+	default:
+		return nil
+	}
+}
+
+// fromValue converts a script value into a Go value of the type.
+func fromValue(v any, t reflect.Type) (reflect.Value, error) {
+	if host, ok := v.(*HostObject); ok {
+		if host.v.CanAddr() && host.v.Addr().Type().AssignableTo(t) {
+			return host.v.Addr(), nil
+		}
+		if host.v.Type().AssignableTo(t) {
+			return host.v, nil
+		}
+	}
+
+	if t.Kind() == reflect.Interface {
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		if rv := reflect.ValueOf(v); rv.Type().AssignableTo(t) {
+			return rv, nil
+		}
+		return reflect.Value{}, fmt.Errorf("expected %s, got %s", t, repr(v))
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			return reflect.ValueOf(b).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if isNumber(v) {
+			n, ok := toInt(v)
+			if !ok || reflect.Zero(t).OverflowInt(int64(n)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v", t, v)
+			}
+			return reflect.ValueOf(int64(n)).Convert(t), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := v.(int64); ok {
+			if n < 0 || reflect.Zero(t).OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v", t, n)
+			}
+			return reflect.ValueOf(uint64(n)).Convert(t), nil
+		}
+		if n, ok := v.(float64); ok {
+			if n != math.Trunc(n) || n < 0 || reflect.Zero(t).OverflowUint(uint64(n)) {
+				return reflect.Value{}, fmt.Errorf("expected %s, got %v", t, n)
+			}
+			return reflect.ValueOf(uint64(n)).Convert(t), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := toFloat(v); ok {
+			return reflect.ValueOf(n).Convert(t), nil
+		}
+	case reflect.String:
+		if s, ok := v.(string); ok {
+			return reflect.ValueOf(s).Convert(t), nil
+		}
+	case reflect.Slice:
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		if l, ok := v.(*List); ok {
+			s := reflect.MakeSlice(t, len(l.Elements), len(l.Elements))
+			for idx, e := range l.Elements {
+				ev, err := fromValue(e, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("list element %d: %w", idx, err)
+				}
+				s.Index(idx).Set(ev)
+			}
+			return s, nil
+		}
+	case reflect.Map:
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+		if m, ok := v.(*Map); ok {
+			res := reflect.MakeMapWithSize(t, m.Len())
+			for _, k := range m.Keys() {
+				kv, err := fromValue(k, t.Key())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("map key %s: %w", repr(k), err)
+				}
+				e, _ := m.Get(k)
+				ev, err := fromValue(e, t.Elem())
+				if err != nil {
+					return reflect.Value{}, fmt.Errorf("map entry %s: %w", repr(k), err)
+				}
+				res.SetMapIndex(kv, ev)
+			}
+			return res, nil
+		}
+	case reflect.Pointer:
+		if v == nil {
+			return reflect.Zero(t), nil
+		}
+	}
+	return reflect.Value{}, fmt.Errorf("expected %s, got %s", t, repr(v))
+}
@@ -2,14 +2,17 @@ package interpreter
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"io"
 	"log/slog"
-	"os"
+	"math/rand"
+	"time"
 
 	"github.com/taehioum/glox/pkg/ast"
-	expressions "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/interpreter/environment"
+	"github.com/taehioum/glox/pkg/token"
 )
 
 // ErrBreak is a sentinel error to break out of a loop.
@@ -33,33 +36,95 @@ func (e ErrReturn) Error() string {
 type Interpreter struct {
 	env    *environment.Environment
 	global *environment.Environment
-
-	Locals map[any]int
-
-	writer io.Writer
-	reader bufio.Reader
+	// builtins encloses the globals of the program and of every module, with the natives.
+	builtins *environment.Environment
+
+	// Locals are the distances to the environments of the local variables, keyed by the tokens naming them where they are used.
+	// tokens are positioned in their file, so each is resolved once.
+	Locals map[token.Token]int
+
+	// calls being run, innermost last
+	frames []diagnostic.Frame
+
+	ctx    context.Context
+	limits Limits
+	// statements run so far, by the current run
+	steps int
+	// whether a run of the host is in progress, so that nested runs don't reset the steps
+	running bool
+	// depth of nested function calls
+	depth int
+
+	// digits after the decimal point that quotients of decimals are rounded to
+	precision int
+
+	// the generator whose body is being run, if any
+	coroutine *coroutine
+	// generators whose body has started and isn't done, shared with the interpreters running their bodies
+	generators *generators
+
+	writer    io.Writer
+	stderr    io.Writer
+	reader    *bufio.Reader
+	now       func() time.Time
+	rand      *rand.Rand
+	lookupEnv func(key string) (string, bool)
+
+	loader Loader
+	// modules imported so far, by absolute path
+	modules map[string]*Namespace
+	// paths of the modules being imported, outermost first, to report import cycles
+	importing []string
 }
 
-func New(writer io.Writer) *Interpreter {
-	global := environment.NewGlobalEnvironment()
+// New returns an interpreter configured by the options.
+// only the natives of the modules in the options are defined, besides print, range and those on lists and maps.
+func New(opts Options) *Interpreter {
+	opts = opts.withDefaults()
+	builtins := environment.NewGlobalEnvironment()
+	global := environment.NewEnclosedEnvironment(builtins)
 	i := &Interpreter{
-		env:    global,
-		global: global,
-		writer: writer,
-		reader: *bufio.NewReader(os.Stdin),
-		Locals: make(map[any]int),
+		env:       global,
+		global:    global,
+		builtins:  builtins,
+		writer:    opts.Stdout,
+		stderr:    opts.Stderr,
+		reader:    bufio.NewReader(opts.Stdin),
+		now:       opts.Now,
+		rand:      opts.Rand,
+		lookupEnv: opts.LookupEnv,
+		loader:    opts.Loader,
+		modules:   make(map[string]*Namespace),
+		generators: &generators{
+			live: make(map[*coroutine]struct{}),
+		},
+		Locals:    make(map[token.Token]int),
+		ctx:       opts.Context,
+		limits:    *opts.Limits,
+		precision: opts.DecimalPrecision,
 	}
 
-	i.global.Define("clock", Clock{})
-	i.global.Define("print", Print{})
-	i.global.Define("input", Input{})
+	i.builtins.Define("print", Print{})
+	i.builtins.Define("len", Len{})
+	i.builtins.Define("substr", Substr{})
+	i.builtins.Define("push", Push{})
+	i.builtins.Define("keys", Keys{})
+	i.builtins.Define("values", Values{})
+	i.builtins.Define("has", Has{})
+	i.builtins.Define("delete", Delete{})
+	i.builtins.Define("range", RangeFunc{})
+	i.builtins.Define("done", Done{})
+	i.defineModules(opts.Modules)
 
 	return i
 }
 
 func (i *Interpreter) Interprete(stmts ...ast.Stmt) error {
+	if !i.running {
+		defer i.startRun()()
+	}
 	for _, stmt := range stmts {
-		err := stmt.Accept(i)
+		err := i.execute(stmt)
 		if err != nil {
 			return err
 		}
@@ -67,20 +132,72 @@ func (i *Interpreter) Interprete(stmts ...ast.Stmt) error {
 	return nil
 }
 
+// execute runs a single statement, within the limits of the interpreter.
+func (i *Interpreter) execute(stmt ast.Stmt) error {
+	if err := i.step(); err != nil {
+		return err
+	}
+	return stmt.Accept(i)
+}
+
 func (i *Interpreter) Eval(e ast.Expr) (any, error) {
+	if !i.running {
+		defer i.startRun()()
+	}
 	return e.Accept(i)
 }
 
-func (i *Interpreter) Resolve(e ast.Expr, depth int) {
+func (i *Interpreter) Resolve(name token.Token, depth int) {
 	slog.Debug("resolving", slog.Attr{Key: "locals", Value: slog.AnyValue(i.Locals)})
-	i.Locals[e] = depth
+	i.Locals[name] = depth
+}
+
+func (i *Interpreter) lookup(name token.Token) (any, error) {
+	if distance, ok := i.Locals[name]; ok {
+		return i.env.GetAt(distance, name.Lexeme)
+	}
+	v, err := i.global.Get(name.Lexeme)
+	if err != nil {
+		return nil, i.undefinedVariable(name)
+	}
+	return v, nil
 }
 
-func (i *Interpreter) lookup(e expressions.Variable) (any, error) {
-	if distance, ok := i.Locals[e]; ok {
-		return i.env.GetAt(distance, e.Name.Lexeme)
+// undefinedVariable reports the name, along with a hint for a similar name, in case it is a typo.
+func (i *Interpreter) undefinedVariable(name token.Token) error {
+	err := diagnostic.NewRuntimeError(name, "undefined variable '%s'", name.Lexeme)
+	if similar := diagnostic.Suggest(name.Lexeme, i.env.Names()); similar != "" {
+		err.Hint = fmt.Sprintf("did you mean '%s'?", similar)
 	}
-	return i.global.Get(e.Name.Lexeme)
+	return err
+}
+
+// newFrame returns the frame of a call to the callable.
+// functions and classes are defined in the script, every other callable is native.
+func newFrame(fn Callable, call token.Token) diagnostic.Frame {
+	f := diagnostic.Frame{Function: fmt.Sprint(fn), Call: call}
+	switch fn.(type) {
+	case Function, *Class:
+	default:
+		f.Native = true
+	}
+	return f
+}
+
+// stack returns the calls being run, innermost first, given the position the innermost one is running at.
+// the top-level script is the outermost frame.
+func (i *Interpreter) stack(pos diagnostic.Position) []diagnostic.Frame {
+	stack := make([]diagnostic.Frame, 0, len(i.frames)+1)
+	for idx := len(i.frames) - 1; idx >= 0; idx-- {
+		f := i.frames[idx]
+		if !f.Native {
+			f.Position = pos
+		}
+		stack = append(stack, f)
+		// the caller is running at the call.
+		pos = diagnostic.At(f.Call)
+	}
+	return append(stack, diagnostic.Frame{Function: "<script>", Position: pos})
 }
 
 type Callable interface {
@@ -88,15 +205,6 @@ type Callable interface {
 	Arity() int
 }
 
-// TODO: we might return type checked value, so we don't have to type check twice.
-func checkNumberOperands(l, r any) bool {
-	_, ok := l.(float64)
-	if !ok {
-		return false
-	}
-	_, ok = r.(float64)
-	return ok
-}
 func checkStringOperands(l, r any) bool {
 	_, ok := l.(string)
 	if !ok {
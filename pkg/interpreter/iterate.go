@@ -0,0 +1,238 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter/environment"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Done is returned by iterators when they have no more values, e.g. by the next() method of an object, or by a closure.
+type Done struct{}
+
+func (d Done) String() string {
+	return "done"
+}
+
+// iterator yields the values a for-in loop runs over.
+type iterator interface {
+	// next returns the next value, and whether there was one.
+	next(i *Interpreter) (any, bool, error)
+}
+
+// closer is an iterator that holds on to resources until it runs out, like generators do.
+type closer interface {
+	close(i *Interpreter) error
+}
+
+// iteratorFunc is an iterator that doesn't need the interpreter to find its next value.
+type iteratorFunc func() (any, bool, error)
+
+func (f iteratorFunc) next(i *Interpreter) (any, bool, error) {
+	return f()
+}
+
+func (i *Interpreter) VisitForIn(stmt ast.ForIn) (err error) {
+	v, err := i.Eval(stmt.Iterable)
+	if err != nil {
+		return err
+	}
+	it, err := i.iterate(stmt.In, v)
+	if err != nil {
+		return err
+	}
+	if c, ok := it.(closer); ok {
+		// loops that break, return or fail before the iterator runs out release it.
+		defer func() {
+			if cerr := c.close(i); err == nil {
+				err = cerr
+			}
+		}()
+	}
+	for {
+		v, ok, err := it.next(i)
+		if err != nil {
+			if !errors.As(err, new(*diagnostic.RuntimeError)) {
+				// like natives, generators don't know where they are resumed from.
+				err = diagnostic.WrapRuntimeError(stmt.In, err)
+			}
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		err = i.iteration(stmt, v)
+		if errors.Is(err, ErrBreak) {
+			return nil
+		}
+		// by continuing the for loop here, we get the continue behavior for free.
+		if errors.Is(err, ErrContinue) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// iteration runs the body of the loop, with the value bound to the name in an environment of its own,
+// so that closures capture the value of their iteration.
+func (i *Interpreter) iteration(stmt ast.ForIn, v any) error {
+	prev := i.env
+	defer func() {
+		// restore env
+		i.env = prev
+	}()
+	i.env = environment.NewEnclosedEnvironment(prev)
+	i.env.Define(stmt.Name.Lexeme, v)
+	return i.execute(stmt.Body)
+}
+
+// iterate returns an iterator over the values of lists, the keys of maps, the code points of strings, and ranges.
+// objects are iterated with their iter() method, which returns an iterator, or with their next() method, if they are an iterator themselves.
+// an iterator is an object with a next() method, or a function called with no arguments, returning done when it has no more values.
+func (i *Interpreter) iterate(tok token.Token, v any) (iterator, error) {
+	switch v := v.(type) {
+	case *List:
+		idx := 0
+		return iteratorFunc(func() (any, bool, error) {
+			if idx >= len(v.Elements) {
+				return nil, false, nil
+			}
+			idx++
+			return v.Elements[idx-1], true, nil
+		}), nil
+	case *Map:
+		// keys set during the loop aren't visited.
+		return i.iterate(tok, &List{Elements: v.Keys()})
+	case string:
+		rest := v
+		return iteratorFunc(func() (any, bool, error) {
+			if rest == "" {
+				return nil, false, nil
+			}
+			_, size := utf8.DecodeRuneInString(rest)
+			c := rest[:size]
+			rest = rest[size:]
+			return c, true, nil
+		}), nil
+	case Range:
+		n, done := v.Start, false
+		return iteratorFunc(func() (any, bool, error) {
+			if done || (v.Step > 0 && n >= v.Stop) || (v.Step < 0 && n <= v.Stop) {
+				return nil, false, nil
+			}
+			curr := n
+			// stop rather than overflow past the end of the int64s.
+			if (v.Step > 0 && n > math.MaxInt64-v.Step) || (v.Step < 0 && n < math.MinInt64-v.Step) {
+				done = true
+			} else {
+				n += v.Step
+			}
+			return curr, true, nil
+		}), nil
+	case *Instance:
+		if iter, ok := v.class.findMethod("iter"); ok {
+			it, err := i.call(tok, iter.bind(v), nil)
+			if err != nil {
+				return nil, err
+			}
+			if g, ok := it.(*Generator); ok {
+				return g, nil
+			}
+			fn, ok := nextFunc(it)
+			if !ok {
+				return nil, diagnostic.NewRuntimeError(tok, "iter() must return an iterator, got %v", it)
+			}
+			return i.iterator(tok, fn), nil
+		}
+		if fn, ok := nextFunc(v); ok {
+			return i.iterator(tok, fn), nil
+		}
+	case *Generator:
+		return v, nil
+	case Function:
+		return i.iterator(tok, v), nil
+	}
+	return nil, diagnostic.NewRuntimeError(tok, "can't iterate over %v", v)
+}
+
+// nextFunc returns the function returning the next value of the iterator.
+func nextFunc(it any) (Callable, bool) {
+	switch it := it.(type) {
+	case *Instance:
+		if next, ok := it.class.findMethod("next"); ok {
+			return next.bind(it), true
+		}
+	case Function:
+		return it, true
+	}
+	return nil, false
+}
+
+// iterator calls the function for each value, until it returns done.
+func (i *Interpreter) iterator(tok token.Token, next Callable) iterator {
+	return iteratorFunc(func() (any, bool, error) {
+		v, err := i.call(tok, next, nil)
+		if err != nil {
+			return nil, false, err
+		}
+		if _, ok := v.(Done); ok {
+			return nil, false, nil
+		}
+		return v, true, nil
+	})
+}
+
+// Range is the integers from Start up to Stop, exclusive, by Step, e.g. range(0, 10, 2).
+// the step may be negative, to count down.
+type Range struct {
+	Start, Stop, Step int64
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.Start, r.Stop, r.Step)
+}
+
+// RangeFunc returns the range of its arguments: range(stop), range(start, stop) or range(start, stop, step).
+type RangeFunc struct{}
+
+func (f RangeFunc) String() string {
+	return "<native fn range>"
+}
+
+func (f RangeFunc) Arity() int {
+	return -1
+}
+
+func (f RangeFunc) Call(e *Interpreter, args []any) (any, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nil, fmt.Errorf("range: expected 1 to 3 arguments, got %d", len(args))
+	}
+	bounds := make([]int64, len(args))
+	for idx, arg := range args {
+		n, ok := toInt(arg)
+		if !ok {
+			return nil, fmt.Errorf("range: expected integers, got %v", arg)
+		}
+		bounds[idx] = int64(n)
+	}
+	r := Range{Step: 1}
+	switch len(bounds) {
+	case 1:
+		r.Stop = bounds[0]
+	case 2:
+		r.Start, r.Stop = bounds[0], bounds[1]
+	case 3:
+		r.Start, r.Stop, r.Step = bounds[0], bounds[1], bounds[2]
+	}
+	if r.Step == 0 {
+		return nil, fmt.Errorf("range: step must not be zero")
+	}
+	return r, nil
+}
@@ -0,0 +1,30 @@
+package interpreter
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type Len struct{}
+
+func (f Len) String() string {
+	return "<native fn len>"
+}
+
+func (f Len) Arity() int {
+	return 1
+}
+
+func (f Len) Call(e *Interpreter, args []any) (any, error) {
+	switch v := args[0].(type) {
+	case *List:
+		return int64(len(v.Elements)), nil
+	case *Map:
+		return int64(v.Len()), nil
+	case string:
+		// the number of code points, rather than bytes.
+		return int64(utf8.RuneCountInString(v)), nil
+	default:
+		return nil, fmt.Errorf("len: expected a list, a map or a string, got %v", v)
+	}
+}
@@ -0,0 +1,103 @@
+package interpreter
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DefaultCallDepth is the maximum depth of nested calls of a new interpreter,
+// so that unbounded recursion fails with an error instead of overflowing the Go stack.
+// it matches the number of frames of the vm.
+const DefaultCallDepth = 1024
+
+// DefaultIntegerBits is the maximum size of the integers computed by ** and << of a new interpreter,
+// about 315,000 decimal digits, so that a single operation can't exhaust the memory of the host.
+const DefaultIntegerBits = 1 << 20
+
+// Limits bounds the resources a program may use. zero fields mean no limit.
+type Limits struct {
+	// Steps is the maximum number of statements run by each call of Interprete or Eval from the host, loop iterations included.
+	Steps int
+	// CallDepth is the maximum depth of nested function calls.
+	CallDepth int
+	// IntegerBits is the maximum number of bits of the integers computed by ** and <<.
+	// without it, they are still capped at math.MaxInt32 bits, which is what math/big can shift by.
+	IntegerBits int
+}
+
+// StepLimitError is returned when a program runs more statements than Limits.Steps.
+type StepLimitError struct {
+	Limit int
+}
+
+func (e *StepLimitError) Error() string {
+	return fmt.Sprintf("step limit of %d exceeded", e.Limit)
+}
+
+// CallDepthError is returned when calls are nested deeper than Limits.CallDepth.
+type CallDepthError struct {
+	Limit int
+}
+
+func (e *CallDepthError) Error() string {
+	return fmt.Sprintf("call depth limit of %d exceeded", e.Limit)
+}
+
+// CancelledError is returned when the context of the interpreter is cancelled, or past its deadline.
+// it wraps the error of the context, e.g. context.DeadlineExceeded.
+type CancelledError struct {
+	Err error
+}
+
+func (e *CancelledError) Error() string {
+	return fmt.Sprintf("cancelled: %s", e.Err)
+}
+
+func (e *CancelledError) Unwrap() error {
+	return e.Err
+}
+
+// SetLimits bounds the resources the following programs may use.
+func (i *Interpreter) SetLimits(limits Limits) {
+	i.limits = limits
+}
+
+// SetContext stops the following programs as soon as the context is done.
+func (i *Interpreter) SetContext(ctx context.Context) {
+	i.ctx = ctx
+}
+
+// contextCheckInterval is how many steps are run in between checks of the context, as checking it is not free.
+const contextCheckInterval = 256
+
+// startRun starts counting the steps of a run of the host, and returns the func that ends it.
+func (i *Interpreter) startRun() func() {
+	i.running = true
+	i.steps = 0
+	return func() {
+		i.running = false
+	}
+}
+
+// integerBits is the maximum number of bits of the integers computed by ** and <<.
+func (i *Interpreter) integerBits() uint64 {
+	if i.limits.IntegerBits > 0 {
+		return uint64(min(i.limits.IntegerBits, math.MaxInt32))
+	}
+	return math.MaxInt32
+}
+
+// step accounts for running a single statement, and checks that the program is still within its limits.
+func (i *Interpreter) step() error {
+	i.steps++
+	if i.limits.Steps > 0 && i.steps > i.limits.Steps {
+		return &StepLimitError{Limit: i.limits.Steps}
+	}
+	if i.steps%contextCheckInterval == 0 {
+		if err := i.ctx.Err(); err != nil {
+			return &CancelledError{Err: err}
+		}
+	}
+	return nil
+}
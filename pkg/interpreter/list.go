@@ -0,0 +1,51 @@
+package interpreter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// List is a growable sequence of values.
+// lists are compared by reference, like instances.
+type List struct {
+	Elements []any
+}
+
+func (l *List) String() string {
+	parts := make([]string, len(l.Elements))
+	for i, e := range l.Elements {
+		parts[i] = repr(e)
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// index checks that the value is a valid index into the list.
+func (l *List) index(bracket token.Token, v any) (int, error) {
+	idx, ok := toInt(v)
+	if !ok {
+		return 0, diagnostic.NewRuntimeError(bracket, "list index must be an integer, got %v", v)
+	}
+	if idx < 0 {
+		return 0, diagnostic.NewRuntimeError(bracket, "negative list index %d", idx)
+	}
+	if idx >= len(l.Elements) {
+		return 0, diagnostic.NewRuntimeError(bracket, "list index %d out of range for length %d", idx, len(l.Elements))
+	}
+	return idx, nil
+}
+
+// repr formats a value nested inside a container, quoting strings.
+func repr(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return strconv.Quote(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
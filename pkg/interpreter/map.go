@@ -0,0 +1,105 @@
+package interpreter
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Map is an associative container, iterated in insertion order.
+// keys are limited to strings, numbers, booleans and nil. maps are compared by reference.
+type Map struct {
+	// keys are kept as they were first set, while entries are keyed by their normalized value.
+	keys    []any
+	entries map[any]any
+}
+
+func NewMap() *Map {
+	return &Map{
+		entries: make(map[any]any),
+	}
+}
+
+// hashable reports whether the value can be used as a map key.
+func hashable(k any) bool {
+	switch k.(type) {
+	case nil, bool, int64, *big.Int, Decimal, float64, string:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalize makes numbers that are equal the same key, so that m[1], m[1.0] and m[1d] are one entry.
+// integers too big for an int64, and decimals with a fraction, are keyed by their text.
+func normalize(k any) any {
+	if n, ok := toInt(k); ok {
+		return int64(n)
+	}
+	switch k := k.(type) {
+	case *big.Int:
+		return bigKey(k.String())
+	case Decimal:
+		if f, _ := k.r.Float64(); k.r.Cmp(new(big.Rat).SetFloat64(f)) == 0 {
+			return f
+		}
+		return bigKey(k.String())
+	}
+	return k
+}
+
+// bigKey is the key of a big number, which doesn't compare by value by itself.
+type bigKey string
+
+func (m *Map) Get(k any) (any, bool) {
+	v, ok := m.entries[normalize(k)]
+	return v, ok
+}
+
+func (m *Map) Set(k, v any) {
+	nk := normalize(k)
+	if _, ok := m.entries[nk]; !ok {
+		m.keys = append(m.keys, k)
+	}
+	m.entries[nk] = v
+}
+
+// Delete removes the key, and reports whether it was present.
+func (m *Map) Delete(k any) bool {
+	k = normalize(k)
+	if _, ok := m.entries[k]; !ok {
+		return false
+	}
+	delete(m.entries, k)
+	for idx, key := range m.keys {
+		if normalize(key) == k {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// Keys returns the keys in insertion order.
+func (m *Map) Keys() []any {
+	return append([]any(nil), m.keys...)
+}
+
+func (m *Map) Len() int {
+	return len(m.keys)
+}
+
+func (m *Map) String() string {
+	parts := make([]string, len(m.keys))
+	for idx, k := range m.keys {
+		parts[idx] = fmt.Sprintf("%s: %s", repr(k), repr(m.entries[normalize(k)]))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func checkKey(k any) error {
+	if !hashable(k) {
+		return fmt.Errorf("unhashable map key %v: keys must be strings, numbers, booleans or nil", k)
+	}
+	return nil
+}
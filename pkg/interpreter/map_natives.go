@@ -0,0 +1,97 @@
+package interpreter
+
+import "fmt"
+
+func mapArg(name string, v any) (*Map, error) {
+	m, ok := v.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a map, got %v", name, v)
+	}
+	return m, nil
+}
+
+// Keys returns the keys of a map as a list, in insertion order.
+type Keys struct{}
+
+func (f Keys) String() string {
+	return "<native fn keys>"
+}
+
+func (f Keys) Arity() int {
+	return 1
+}
+
+func (f Keys) Call(e *Interpreter, args []any) (any, error) {
+	m, err := mapArg("keys", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return &List{Elements: m.Keys()}, nil
+}
+
+// Values returns the values of a map as a list, in the order of their keys.
+type Values struct{}
+
+func (f Values) String() string {
+	return "<native fn values>"
+}
+
+func (f Values) Arity() int {
+	return 1
+}
+
+func (f Values) Call(e *Interpreter, args []any) (any, error) {
+	m, err := mapArg("values", args[0])
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, m.Len())
+	for _, k := range m.keys {
+		values = append(values, m.entries[normalize(k)])
+	}
+	return &List{Elements: values}, nil
+}
+
+type Has struct{}
+
+func (f Has) String() string {
+	return "<native fn has>"
+}
+
+func (f Has) Arity() int {
+	return 2
+}
+
+func (f Has) Call(e *Interpreter, args []any) (any, error) {
+	m, err := mapArg("has", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nil, fmt.Errorf("has: %w", err)
+	}
+	_, ok := m.Get(args[1])
+	return ok, nil
+}
+
+// Delete removes a key from a map, returning whether it was present.
+type Delete struct{}
+
+func (f Delete) String() string {
+	return "<native fn delete>"
+}
+
+func (f Delete) Arity() int {
+	return 2
+}
+
+func (f Delete) Call(e *Interpreter, args []any) (any, error) {
+	m, err := mapArg("delete", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nil, fmt.Errorf("delete: %w", err)
+	}
+	return m.Delete(args[1]), nil
+}
@@ -0,0 +1,130 @@
+package interpreter
+
+import (
+	"fmt"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter/environment"
+)
+
+func (i *Interpreter) VisitMatch(e ast.Match) (any, error) {
+	v, err := i.Eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	for idx, arm := range e.Arms {
+		var res any
+		ok, err := i.arm(arm, v, func() error {
+			var err error
+			res, err = i.Eval(e.Bodies[idx])
+			return err
+		})
+		if ok || err != nil {
+			return res, err
+		}
+	}
+	return nil, diagnostic.NewRuntimeError(e.Keyword, "no arm matches %s", repr(v))
+}
+
+func (i *Interpreter) VisitMatchStmt(stmt ast.MatchStmt) error {
+	v, err := i.Eval(stmt.Value)
+	if err != nil {
+		return err
+	}
+	for idx, arm := range stmt.Arms {
+		ok, err := i.arm(arm, v, func() error {
+			return i.execute(stmt.Bodies[idx])
+		})
+		if ok || err != nil {
+			return err
+		}
+	}
+	return diagnostic.NewRuntimeError(stmt.Keyword, "no arm matches %s", repr(v))
+}
+
+// arm runs the body if the value matches the arm, with the names of its patterns bound around the guard and the body.
+// it reports whether the arm matched.
+func (i *Interpreter) arm(arm ast.Arm, v any, body func() error) (bool, error) {
+	prev := i.env
+	defer func() {
+		// restore env
+		i.env = prev
+	}()
+	i.env = environment.NewEnclosedEnvironment(prev)
+
+	matched := false
+	for _, p := range arm.Patterns {
+		ok, err := i.match(p, v)
+		if err != nil {
+			return false, err
+		}
+		if ok {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return false, nil
+	}
+
+	if arm.Guard != nil {
+		g, err := i.Eval(arm.Guard)
+		if err != nil {
+			return false, err
+		}
+		if !truthy(g) {
+			return false, nil
+		}
+	}
+	return true, body()
+}
+
+// match reports whether the value matches the pattern, defining the names it binds in the current environment.
+func (i *Interpreter) match(p ast.Pattern, v any) (bool, error) {
+	switch p := p.(type) {
+	case ast.WildcardPattern:
+		return true, nil
+	case ast.BindingPattern:
+		i.env.Define(p.Name.Lexeme, v)
+		return true, nil
+	case ast.LiteralPattern:
+		lit, err := i.Eval(p.Value)
+		if err != nil {
+			return false, err
+		}
+		return equal(lit, v), nil
+	case ast.ListPattern:
+		l, ok := v.(*List)
+		if !ok || len(l.Elements) != len(p.Elements) {
+			return false, nil
+		}
+		for idx, e := range p.Elements {
+			if ok, err := i.match(e, l.Elements[idx]); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	case ast.MapPattern:
+		m, ok := v.(*Map)
+		if !ok {
+			return false, nil
+		}
+		for idx, k := range p.Keys {
+			key, err := i.Eval(k)
+			if err != nil {
+				return false, err
+			}
+			value, ok := m.Get(key)
+			if !ok {
+				return false, nil
+			}
+			if ok, err := i.match(p.Values[idx], value); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	default:
+		return false, fmt.Errorf("unknown pattern %T", p)
+	}
+}
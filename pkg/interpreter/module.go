@@ -0,0 +1,104 @@
+package interpreter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter/environment"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Loader reads the module at the path, and parses and resolves it for the interpreter to run.
+// its tokens should be scanned with the path as their file, so that its own imports are relative to it.
+type Loader func(i *Interpreter, path string) ([]ast.Stmt, error)
+
+// Namespace is the module of an imported file, with its own globals.
+// its top-level names are exported, unless they start with '_'.
+type Namespace struct {
+	name    string
+	path    string
+	globals *environment.Environment
+}
+
+func (m *Namespace) String() string {
+	return fmt.Sprintf("<module %s>", m.name)
+}
+
+func (m *Namespace) Get(name token.Token) (any, error) {
+	if strings.HasPrefix(name.Lexeme, "_") {
+		return nil, diagnostic.NewRuntimeError(name, "'%s' is private to module %s", name.Lexeme, m.name)
+	}
+	v, ok := m.globals.Lookup(name.Lexeme)
+	if !ok {
+		return nil, diagnostic.NewRuntimeError(name, "module %s has no '%s'", m.name, name.Lexeme)
+	}
+	return v, nil
+}
+
+func (m *Namespace) Set(name token.Token, value any) error {
+	return diagnostic.NewRuntimeError(name, "can't assign to '%s' of module %s", name.Lexeme, m.name)
+}
+
+func (i *Interpreter) VisitImport(stmt ast.Import) error {
+	m, err := i.importModule(stmt)
+	if err != nil {
+		return err
+	}
+	i.env.Define(stmt.Name.Lexeme, m)
+	return nil
+}
+
+// importModule runs the module the first time it is imported, and returns it from the cache afterwards.
+func (i *Interpreter) importModule(stmt ast.Import) (*Namespace, error) {
+	path := stmt.Path.Literal.(string)
+	if !filepath.IsAbs(path) {
+		// relative to the importing file, or to the working directory for sources that are not files.
+		path = filepath.Join(filepath.Dir(stmt.Keyword.File), path)
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil, diagnostic.WrapRuntimeError(stmt.Path, err)
+	}
+
+	importing := i.importing
+	if len(importing) == 0 && stmt.Keyword.File != "" {
+		// the program itself is the root of the chain.
+		importing = []string{stmt.Keyword.File}
+	}
+	for idx, p := range importing {
+		if abs, _ := filepath.Abs(p); abs == key {
+			chain := append(importing[idx:len(importing):len(importing)], path)
+			return nil, diagnostic.NewRuntimeError(stmt.Path, "import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	if m, ok := i.modules[key]; ok {
+		return m, nil
+	}
+
+	if i.loader == nil {
+		return nil, diagnostic.NewRuntimeError(stmt.Keyword, "imports are not available")
+	}
+	stmts, err := i.loader(i, path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Namespace{
+		name:    stmt.Name.Lexeme,
+		path:    path,
+		globals: environment.NewEnclosedEnvironment(i.builtins),
+	}
+	prevImporting, prevGlobal, prevEnv := i.importing, i.global, i.env
+	i.importing, i.global, i.env = append(importing, path), m.globals, m.globals
+	defer func() {
+		i.importing, i.global, i.env = prevImporting, prevGlobal, prevEnv
+	}()
+	if err := i.Interprete(stmts...); err != nil {
+		return nil, err
+	}
+	i.modules[key] = m
+	return m, nil
+}
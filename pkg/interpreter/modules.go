@@ -0,0 +1,79 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// defineModules defines the natives of the modules.
+func (i *Interpreter) defineModules(modules Module) {
+	if modules&ModuleIO != 0 {
+		i.DefineNative("input", 0, func(args []Value) (Value, error) {
+			s, err := i.reader.ReadString('\n')
+			if err == io.EOF && s != "" {
+				// the last line needn't end with a newline.
+				return s, nil
+			}
+			return s, err
+		})
+		i.DefineNative("eprint", -1, func(args []Value) (Value, error) {
+			_, err := fmt.Fprintln(i.stderr, args...)
+			return nil, err
+		})
+	}
+
+	if modules&ModuleFS != 0 {
+		i.DefineNative("readFile", 1, func(args []Value) (Value, error) {
+			path, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("readFile: expected a path, got %v", args[0])
+			}
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("readFile: %w", err)
+			}
+			return string(b), nil
+		})
+		i.DefineNative("writeFile", 2, func(args []Value) (Value, error) {
+			path, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("writeFile: expected a path, got %v", args[0])
+			}
+			contents, ok := args[1].(string)
+			if !ok {
+				return nil, fmt.Errorf("writeFile: expected a string, got %v", args[1])
+			}
+			if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+				return nil, fmt.Errorf("writeFile: %w", err)
+			}
+			return nil, nil
+		})
+	}
+
+	if modules&ModuleTime != 0 {
+		i.DefineNative("clock", 0, func(args []Value) (Value, error) {
+			return float64(i.now().UnixNano()) / 1e9, nil
+		})
+	}
+
+	if modules&ModuleEnv != 0 {
+		i.DefineNative("getenv", 1, func(args []Value) (Value, error) {
+			key, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("getenv: expected a name, got %v", args[0])
+			}
+			if v, ok := i.lookupEnv(key); ok {
+				return v, nil
+			}
+			// unset variables are nil, to tell them apart from empty ones.
+			return nil, nil
+		})
+	}
+
+	if modules&ModuleRandom != 0 {
+		i.DefineNative("random", 0, func(args []Value) (Value, error) {
+			return i.rand.Float64(), nil
+		})
+	}
+}
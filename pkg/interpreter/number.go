@@ -0,0 +1,468 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// numbers are exact integers, exact decimals, or float64s.
+// integers are int64s, and are promoted to *big.Ints when they overflow, so that they never wrap around.
+// an operation on two integers results in an integer, except for "/" which always divides as floats.
+// mixing numbers promotes them to the less exact of the two: integers to decimals, and either of them to floats.
+
+func isNumber(v any) bool {
+	switch v.(type) {
+	case int64, *big.Int, Decimal, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isInteger(v any) bool {
+	switch v.(type) {
+	case int64, *big.Int:
+		return true
+	default:
+		return false
+	}
+}
+
+func toFloat(v any) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(v).Float64()
+		return f, true
+	case Decimal:
+		f, _ := v.r.Float64()
+		return f, true
+	case float64:
+		return v, true
+	default:
+		return 0, false
+	}
+}
+
+func toBig(v any) (*big.Int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return big.NewInt(v), true
+	case *big.Int:
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+func toRat(v any) (*big.Rat, bool) {
+	switch v := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(v), true
+	case *big.Int:
+		return new(big.Rat).SetInt(v), true
+	case Decimal:
+		return v.r, true
+	default:
+		return nil, false
+	}
+}
+
+// normalizeInt returns the integer as an int64 when it fits in one.
+func normalizeInt(n *big.Int) any {
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n
+}
+
+// toInt converts an integral number to an int, so that 2.0 indexes a list like 2 does.
+func toInt(v any) (int, bool) {
+	switch v := v.(type) {
+	case int64:
+		return int(v), true
+	case Decimal:
+		if !v.r.IsInt() || !v.r.Num().IsInt64() {
+			return 0, false
+		}
+		return int(v.r.Num().Int64()), true
+	case float64:
+		if v != math.Trunc(v) || v >= 0x1p63 || v < -0x1p63 {
+			return 0, false
+		}
+		return int(v), true
+	default:
+		return 0, false
+	}
+}
+
+// arithmetic applies one of the arithmetic or bitwise operators to two numbers.
+func (i *Interpreter) arithmetic(op token.Token, l, r any) (any, error) {
+	li, lok := l.(int64)
+	ri, rok := r.(int64)
+	if lok && rok {
+		return i.intArithmetic(op, li, ri)
+	}
+
+	switch op.Type {
+	case token.AMPERSAND, token.PIPE, token.CARET, token.LESSLESS, token.GREATERGREATER:
+		lb, lok := toBig(l)
+		rb, rok := toBig(r)
+		if !lok || !rok {
+			return nil, diagnostic.NewRuntimeError(op, "operands must be integers, got %v and %v", l, r)
+		}
+		return i.bigArithmetic(op, lb, rb)
+	}
+	if !isNumber(l) || !isNumber(r) {
+		return nil, diagnostic.NewRuntimeError(op, "operands must be numbers, got %v and %v", l, r)
+	}
+
+	_, lfloat := l.(float64)
+	_, rfloat := r.(float64)
+	if lfloat || rfloat {
+		lf, _ := toFloat(l)
+		rf, _ := toFloat(r)
+		return floatArithmetic(op, lf, rf)
+	}
+	if isInteger(l) && isInteger(r) {
+		lb, _ := toBig(l)
+		rb, _ := toBig(r)
+		return i.bigArithmetic(op, lb, rb)
+	}
+	lr, _ := toRat(l)
+	rr, _ := toRat(r)
+	return i.decimalArithmetic(op, lr, rr)
+}
+
+func floatArithmetic(op token.Token, l, r float64) (any, error) {
+	switch op.Type {
+	case token.PLUS:
+		return l + r, nil
+	case token.MINUS:
+		return l - r, nil
+	case token.STAR:
+		return l * r, nil
+	case token.SLASH:
+		return l / r, nil
+	case token.SLASHSLASH:
+		return math.Floor(l / r), nil
+	case token.PERCENT:
+		// the remainder has the sign of the divisor, so that it agrees with //.
+		m := math.Mod(l, r)
+		if m != 0 && (m < 0) != (r < 0) {
+			m += r
+		}
+		return m, nil
+	case token.STARSTAR:
+		return math.Pow(l, r), nil
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator %s", op.Lexeme)
+	}
+}
+
+// intArithmetic computes with int64s, falling back to *big.Ints when the result would overflow.
+func (i *Interpreter) intArithmetic(op token.Token, l, r int64) (any, error) {
+	switch op.Type {
+	case token.SLASH, token.SLASHSLASH, token.PERCENT:
+		if r == 0 {
+			return nil, diagnostic.NewRuntimeError(op, "division by zero")
+		}
+	}
+
+	switch op.Type {
+	case token.PLUS:
+		if s := l + r; (s > l) == (r > 0) {
+			return s, nil
+		}
+	case token.MINUS:
+		if d := l - r; (d < l) == (r > 0) {
+			return d, nil
+		}
+	case token.STAR:
+		if p, ok := mul(l, r); ok {
+			return p, nil
+		}
+	case token.SLASH:
+		return float64(l) / float64(r), nil
+	case token.SLASHSLASH:
+		if l == math.MinInt64 && r == -1 {
+			break
+		}
+		// rounds towards negative infinity, rather than zero.
+		q := l / r
+		if l%r != 0 && (l < 0) != (r < 0) {
+			q--
+		}
+		return q, nil
+	case token.PERCENT:
+		m := l % r
+		if m != 0 && (m < 0) != (r < 0) {
+			m += r
+		}
+		return m, nil
+	case token.STARSTAR:
+		if r < 0 {
+			return math.Pow(float64(l), float64(r)), nil
+		}
+		if p, ok := intPow(l, r); ok {
+			return p, nil
+		}
+	case token.AMPERSAND:
+		return l & r, nil
+	case token.PIPE:
+		return l | r, nil
+	case token.CARET:
+		return l ^ r, nil
+	case token.LESSLESS:
+		if r < 0 {
+			return nil, diagnostic.NewRuntimeError(op, "negative shift count %d", r)
+		}
+		if r < 63 && (l<<r)>>r == l {
+			return l << r, nil
+		}
+	case token.GREATERGREATER:
+		if r < 0 {
+			return nil, diagnostic.NewRuntimeError(op, "negative shift count %d", r)
+		}
+		return l >> min(r, 63), nil
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator %s", op.Lexeme)
+	}
+	return i.bigArithmetic(op, big.NewInt(l), big.NewInt(r))
+}
+
+// mul multiplies two int64s, and reports whether the product didn't overflow.
+func mul(l, r int64) (int64, bool) {
+	p := l * r
+	return p, l == 0 || (p/l == r && !(l == -1 && r == math.MinInt64))
+}
+
+// intPow raises the base to a non-negative exponent by squaring, and reports whether it didn't overflow.
+func intPow(base, exp int64) (int64, bool) {
+	result := int64(1)
+	ok := true
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, ok = mul(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mul(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// bigArithmetic computes with *big.Ints, failing rather than computing integers of more than Limits.IntegerBits.
+func (i *Interpreter) bigArithmetic(op token.Token, l, r *big.Int) (any, error) {
+	switch op.Type {
+	case token.SLASH, token.SLASHSLASH, token.PERCENT:
+		if r.Sign() == 0 {
+			return nil, diagnostic.NewRuntimeError(op, "division by zero")
+		}
+	}
+
+	n := new(big.Int)
+	switch op.Type {
+	case token.PLUS:
+		n.Add(l, r)
+	case token.MINUS:
+		n.Sub(l, r)
+	case token.STAR:
+		n.Mul(l, r)
+	case token.SLASH:
+		f, _ := new(big.Rat).SetFrac(l, r).Float64()
+		return f, nil
+	case token.SLASHSLASH, token.PERCENT:
+		q, m := n.QuoRem(l, r, new(big.Int))
+		// rounds towards negative infinity, rather than zero.
+		if m.Sign() != 0 && (m.Sign() < 0) != (r.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+			m.Add(m, r)
+		}
+		if op.Type == token.PERCENT {
+			return normalizeInt(m), nil
+		}
+	case token.STARSTAR:
+		if r.Sign() < 0 {
+			lf, _ := toFloat(l)
+			rf, _ := toFloat(r)
+			return math.Pow(lf, rf), nil
+		}
+		if powTooLarge(l, r, i.integerBits()) {
+			return nil, diagnostic.NewRuntimeError(op, "exponent %v is too large, the result would take more than %d bits", r, i.integerBits())
+		}
+		n.Exp(l, r, nil)
+	case token.AMPERSAND:
+		n.And(l, r)
+	case token.PIPE:
+		n.Or(l, r)
+	case token.CARET:
+		n.Xor(l, r)
+	case token.LESSLESS, token.GREATERGREATER:
+		if r.Sign() < 0 {
+			return nil, diagnostic.NewRuntimeError(op, "negative shift count %v", r)
+		}
+		if op.Type == token.GREATERGREATER {
+			// shifting out all the bits leaves 0, or -1 for negative integers, however large the count.
+			shift := uint64(l.BitLen())
+			if r.IsUint64() {
+				shift = min(shift, r.Uint64())
+			}
+			n.Rsh(l, uint(shift))
+			break
+		}
+		bits := i.integerBits()
+		if l.Sign() != 0 && (!r.IsUint64() || r.Uint64() > bits || uint64(l.BitLen())+r.Uint64() > bits) {
+			return nil, diagnostic.NewRuntimeError(op, "shift count %v is too large, the result would take more than %d bits", r, bits)
+		}
+		if l.Sign() != 0 {
+			n.Lsh(l, uint(r.Uint64()))
+		}
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator %s", op.Lexeme)
+	}
+	return normalizeInt(n), nil
+}
+
+// powTooLarge reports whether raising the base to a non-negative exponent could take more than the given bits,
+// so that a power can't exhaust memory.
+func powTooLarge(base, exp *big.Int, bits uint64) bool {
+	if base.CmpAbs(big.NewInt(1)) <= 0 {
+		return false
+	}
+	// the power takes floor(exp * log2(|base|)) + 1 bits.
+	mant := new(big.Float)
+	e := new(big.Float).SetInt(base).MantExp(mant)
+	m, _ := mant.Float64()
+	x, _ := new(big.Float).SetInt(exp).Float64()
+	return x*(float64(e)+math.Log2(math.Abs(m))) >= float64(bits)
+}
+
+// decimalArithmetic computes with decimals, rounding quotients to the precision of the interpreter.
+func (i *Interpreter) decimalArithmetic(op token.Token, l, r *big.Rat) (any, error) {
+	switch op.Type {
+	case token.SLASH, token.SLASHSLASH, token.PERCENT:
+		if r.Sign() == 0 {
+			return nil, diagnostic.NewRuntimeError(op, "division by zero")
+		}
+	}
+
+	n := new(big.Rat)
+	switch op.Type {
+	case token.PLUS:
+		n.Add(l, r)
+	case token.MINUS:
+		n.Sub(l, r)
+	case token.STAR:
+		n.Mul(l, r)
+	case token.SLASH:
+		n = round(n.Quo(l, r), i.precision)
+	case token.SLASHSLASH:
+		n.SetInt(floor(n.Quo(l, r)))
+	case token.PERCENT:
+		q := new(big.Rat).SetInt(floor(n.Quo(l, r)))
+		n.Sub(l, q.Mul(q, r))
+	case token.STARSTAR:
+		if !r.IsInt() || !r.Num().IsInt64() {
+			lf, _ := l.Float64()
+			rf, _ := r.Float64()
+			return math.Pow(lf, rf), nil
+		}
+		exp := new(big.Int).Abs(r.Num())
+		if powTooLarge(l.Num(), exp, i.integerBits()) || powTooLarge(l.Denom(), exp, i.integerBits()) {
+			return nil, diagnostic.NewRuntimeError(op, "exponent %v is too large, the result would take more than %d bits", r.RatString(), i.integerBits())
+		}
+		n.SetFrac(new(big.Int).Exp(l.Num(), exp, nil), new(big.Int).Exp(l.Denom(), exp, nil))
+		if r.Sign() < 0 {
+			if n.Sign() == 0 {
+				return nil, diagnostic.NewRuntimeError(op, "division by zero")
+			}
+			n = round(n.Inv(n), i.precision)
+		}
+	default:
+		return nil, fmt.Errorf("unknown arithmetic operator %s", op.Lexeme)
+	}
+	return Decimal{n}, nil
+}
+
+// negate negates a number.
+func negate(v any) (any, bool) {
+	switch n := v.(type) {
+	case int64:
+		if n == math.MinInt64 {
+			return new(big.Int).Neg(big.NewInt(n)), true
+		}
+		return -n, true
+	case *big.Int:
+		return normalizeInt(new(big.Int).Neg(n)), true
+	case Decimal:
+		return Decimal{new(big.Rat).Neg(n.r)}, true
+	case float64:
+		return -n, true
+	default:
+		return nil, false
+	}
+}
+
+// comparison applies one of the comparison operators to two numbers.
+func comparison(op token.Token, l, r any) (any, error) {
+	if li, ok := l.(int64); ok {
+		if ri, ok := r.(int64); ok {
+			return compare(op, li, ri), nil
+		}
+	}
+	if !isNumber(l) || !isNumber(r) {
+		return nil, diagnostic.NewRuntimeError(op, "operands must be numbers, got %v and %v", l, r)
+	}
+	lr, lok := toRat(l)
+	rr, rok := toRat(r)
+	if lok && rok {
+		return compare(op, lr.Cmp(rr), 0), nil
+	}
+	lf, _ := toFloat(l)
+	rf, _ := toFloat(r)
+	return compare(op, lf, rf), nil
+}
+
+func compare[T int | int64 | float64](op token.Token, l, r T) bool {
+	switch op.Type {
+	case token.GREATER:
+		return l > r
+	case token.GREATEREQUAL:
+		return l >= r
+	case token.LESS:
+		return l < r
+	default:
+		return l <= r
+	}
+}
+
+// equal compares numbers by their value, so that 1 == 1.0, and everything else as Go does.
+func equal(l, r any) bool {
+	if !isNumber(l) || !isNumber(r) {
+		return l == r
+	}
+	if li, ok := l.(int64); ok {
+		if ri, ok := r.(int64); ok {
+			return li == ri
+		}
+	}
+	lr, lok := toRat(l)
+	rr, rok := toRat(r)
+	if lok && rok {
+		return lr.Cmp(rr) == 0
+	}
+	lf, _ := toFloat(l)
+	rf, _ := toFloat(r)
+	return lf == rf
+}
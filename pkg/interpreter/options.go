@@ -0,0 +1,101 @@
+package interpreter
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"os"
+	"time"
+)
+
+// Module is a set of native modules, which give scripts access to the outside world.
+// without any, scripts only compute, and print to Options.Stdout.
+type Module uint
+
+const (
+	// ModuleIO reads lines from Options.Stdin with input(), and writes to Options.Stderr with eprint().
+	ModuleIO Module = 1 << iota
+	// ModuleFS reads and writes files with readFile(path) and writeFile(path, contents).
+	ModuleFS
+	// ModuleTime tells the time of Options.Now with clock(), in seconds.
+	ModuleTime
+	// ModuleEnv looks up environment variables with getenv(name).
+	ModuleEnv
+	// ModuleRandom draws numbers in [0, 1) from Options.Rand with random().
+	ModuleRandom
+
+	// ModuleAll are all the native modules, for trusted scripts.
+	ModuleAll = ModuleIO | ModuleFS | ModuleTime | ModuleEnv | ModuleRandom
+)
+
+// Options configures an interpreter. the zero value runs untrusted scripts:
+// they only compute, and their output is discarded.
+type Options struct {
+	// Modules are the native modules available to scripts.
+	Modules Module
+
+	// Stdin is read by input(). defaults to an empty reader.
+	Stdin io.Reader
+	// Stdout is written by print(). defaults to discarding the output.
+	Stdout io.Writer
+	// Stderr is written by eprint(). defaults to discarding the output.
+	Stderr io.Writer
+
+	// Now returns the current time, e.g. a virtual clock for reproducible runs. defaults to time.Now.
+	Now func() time.Time
+	// Rand is the source of random numbers. defaults to a randomly seeded source.
+	Rand *rand.Rand
+	// LookupEnv looks up environment variables. defaults to os.LookupEnv.
+	LookupEnv func(key string) (string, bool)
+
+	// Loader loads the modules imported by scripts. imports fail without one.
+	Loader Loader
+
+	// Context stops the programs as soon as it is done. defaults to context.Background().
+	Context context.Context
+	// Limits bounds the resources programs may use. defaults to a call depth of DefaultCallDepth,
+	// and integers of DefaultIntegerBits.
+	Limits *Limits
+
+	// DecimalPrecision is how many digits after the decimal point the quotients of decimals are rounded to.
+	// defaults to DefaultDecimalPrecision.
+	DecimalPrecision int
+}
+
+// withDefaults fills in the unset options.
+func (o Options) withDefaults() Options {
+	if o.Stdin == nil {
+		o.Stdin = eofReader{}
+	}
+	if o.Stdout == nil {
+		o.Stdout = io.Discard
+	}
+	if o.Stderr == nil {
+		o.Stderr = io.Discard
+	}
+	if o.Now == nil {
+		o.Now = time.Now
+	}
+	if o.Rand == nil {
+		o.Rand = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	if o.LookupEnv == nil {
+		o.LookupEnv = os.LookupEnv
+	}
+	if o.Context == nil {
+		o.Context = context.Background()
+	}
+	if o.Limits == nil {
+		o.Limits = &Limits{CallDepth: DefaultCallDepth, IntegerBits: DefaultIntegerBits}
+	}
+	if o.DecimalPrecision <= 0 {
+		o.DecimalPrecision = DefaultDecimalPrecision
+	}
+	return o
+}
+
+type eofReader struct{}
+
+func (eofReader) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
@@ -7,11 +7,17 @@ import (
 
 type Print struct{}
 
+func (f Print) String() string {
+	return "<native fn print>"
+}
+
 func (f Print) Arity() int {
 	return -1
 }
 
 func (f Print) Call(e *Interpreter, args []any) (any, error) {
 	s := fmt.Sprintln(args...)
-	return io.WriteString(e.writer, s)
+	// print evaluates to nil, so that the prompt doesn't echo anything after it.
+	_, err := io.WriteString(e.writer, s)
+	return nil, err
 }
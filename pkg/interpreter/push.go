@@ -0,0 +1,23 @@
+package interpreter
+
+import "fmt"
+
+// Push appends a value to the end of a list.
+type Push struct{}
+
+func (f Push) String() string {
+	return "<native fn push>"
+}
+
+func (f Push) Arity() int {
+	return 2
+}
+
+func (f Push) Call(e *Interpreter, args []any) (any, error) {
+	l, ok := args[0].(*List)
+	if !ok {
+		return nil, fmt.Errorf("push: expected a list, got %v", args[0])
+	}
+	l.Elements = append(l.Elements, args[1])
+	return nil, nil
+}
@@ -0,0 +1,61 @@
+package interpreter
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// strings are indexed by code points, rather than bytes, so that "é"[0] is "é".
+
+// stringIndex returns the code point at the index of the string, as a string of its own.
+func stringIndex(bracket token.Token, s string, v any) (string, error) {
+	idx, ok := toInt(v)
+	if !ok {
+		return "", diagnostic.NewRuntimeError(bracket, "string index must be an integer, got %v", v)
+	}
+	if idx < 0 {
+		return "", diagnostic.NewRuntimeError(bracket, "negative string index %d", idx)
+	}
+	count := 0
+	for _, c := range s {
+		if count == idx {
+			return string(c), nil
+		}
+		count++
+	}
+	return "", diagnostic.NewRuntimeError(bracket, "string index %d out of range for length %d", idx, count)
+}
+
+type Substr struct{}
+
+func (f Substr) String() string {
+	return "<native fn substr>"
+}
+
+func (f Substr) Arity() int {
+	return 3
+}
+
+// Call returns the code points of the string from start, up to but excluding end.
+func (f Substr) Call(e *Interpreter, args []any) (any, error) {
+	s, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("substr: expected a string, got %v", args[0])
+	}
+	start, ok := toInt(args[1])
+	if !ok {
+		return nil, fmt.Errorf("substr: start must be an integer, got %v", args[1])
+	}
+	end, ok := toInt(args[2])
+	if !ok {
+		return nil, fmt.Errorf("substr: end must be an integer, got %v", args[2])
+	}
+	length := utf8.RuneCountInString(s)
+	if start < 0 || end < start || end > length {
+		return nil, fmt.Errorf("substr: range [%d, %d) out of range for length %d", start, end, length)
+	}
+	return string([]rune(s)[start:end]), nil
+}
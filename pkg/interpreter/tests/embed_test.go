@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/runner"
+)
+
+type account struct {
+	Owner   string
+	Balance int
+	Tags    []string
+	Limits  map[string]float64 `glox:"limits"`
+	secret  string
+}
+
+func TestEmbed(t *testing.T) {
+	acc := &account{Owner: "kim", Balance: 10, Tags: []string{"a", "b"}, Limits: map[string]float64{"daily": 100}}
+	settings := map[string]any{"greeting": "hello"}
+
+	var intpr *interpreter.Interpreter
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		intpr = i
+		i.DefineNative("upper", 1, func(args []interpreter.Value) (interpreter.Value, error) {
+			s, ok := args[0].(string)
+			if !ok {
+				return nil, fmt.Errorf("upper: expected a string, got %v", args[0])
+			}
+			return strings.ToUpper(s), nil
+		})
+		i.SetGlobal("limit", 3.0)
+
+		obj, err := interpreter.Expose(acc)
+		assert.NoError(t, err)
+		i.SetGlobal("account", obj)
+		obj, err = interpreter.Expose(settings)
+		assert.NoError(t, err)
+		i.SetGlobal("settings", obj)
+	}}
+
+	var b bytes.Buffer
+	err := r.Run(`
+print(upper(account.Owner), account.Balance, account.Tags, account.limits.daily);
+account.Balance = account.Balance + limit;
+settings.greeting = upper(settings.greeting);
+settings.count = len(account.Tags);
+var result = account.Balance * 2;
+`, &b)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.Equal(t, "KIM 10 [\"a\", \"b\"] 100\n", b.String())
+	assert.Equal(t, 13, acc.Balance)
+	assert.Equal(t, map[string]any{"greeting": "HELLO", "count": int64(2)}, settings)
+
+	result, ok := intpr.GetGlobal("result")
+	assert.True(t, ok)
+	assert.Equal(t, int64(26), result)
+	_, ok = intpr.GetGlobal("undefined")
+	assert.False(t, ok)
+}
+
+func TestEmbedErrors(t *testing.T) {
+	acc := &account{}
+	hostErr := errors.New("host failure")
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.DefineNative("fail", 0, func(args []interpreter.Value) (interpreter.Value, error) {
+			return nil, hostErr
+		})
+		i.DefineNative("crash", 1, func(args []interpreter.Value) (interpreter.Value, error) {
+			return args[0].(string), nil
+		})
+		obj, _ := interpreter.Expose(acc)
+		i.SetGlobal("account", obj)
+	}}
+
+	tests := []struct {
+		source string
+		msg    string
+	}{
+		{"fail();", "host failure"},
+		{"account.Balance = 1.5;", "expected int, got 1.5"},
+		{"account.secret;", "undefined property 'secret'"},
+		{"crash(1);", "crash: panic: interface conversion: interface {} is int64, not string"},
+	}
+	for _, tt := range tests {
+		err := r.Run(tt.source, &bytes.Buffer{})
+		var rerr *diagnostic.RuntimeError
+		if assert.True(t, errors.As(err, &rerr), tt.source) {
+			assert.Equal(t, tt.msg, rerr.Msg)
+			assert.Equal(t, 1, rerr.Line)
+		}
+	}
+	assert.ErrorIs(t, r.Run("fail();", &bytes.Buffer{}), hostErr)
+}
+
+func (a *account) Deposit(amount int) int {
+	a.Balance += amount
+	return a.Balance
+}
+
+func TestGoFunc(t *testing.T) {
+	acc := &account{Balance: 1}
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		funcs := map[string]any{
+			"repeat": func(a int, b string) (string, error) {
+				if a < 0 {
+					return "", errors.New("negative count")
+				}
+				return strings.Repeat(b, a), nil
+			},
+			"sum": func(prefix string, ns ...float64) string {
+				total := 0.0
+				for _, n := range ns {
+					total += n
+				}
+				return fmt.Sprint(prefix, total)
+			},
+			"join":   strings.Join,
+			"counts": func(m map[string]int) []int { return []int{m["a"], m["b"]} },
+			"none":   func() {},
+			"isZero": func(u uint8) bool { return u == 0 },
+			"first":  func(xs []string) string { return xs[0] },
+		}
+		for name, fn := range funcs {
+			assert.NoError(t, i.DefineFunc(name, fn))
+		}
+		obj, _ := interpreter.Expose(acc)
+		i.SetGlobal("account", obj)
+	}}
+
+	var b bytes.Buffer
+	err := r.Run(`
+print(repeat(3, "ab"));
+print(sum("total: "), sum("total: ", 1, 2, 3.5));
+print(join(["a", "b", "c"], "-"));
+print(counts({"a": 1, "b": 2}));
+print(none(), isZero(0));
+print(account.Deposit(41), account.Balance);
+`, &b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "ababab\ntotal: 0 total: 6.5\na-b-c\n[1, 2]\n<nil> true\n42 42\n", b.String())
+
+	tests := []struct {
+		source string
+		msg    string
+		col    int
+	}{
+		{`repeat(1.5, "a");`, "repeat: argument 1: expected int, got 1.5", 16},
+		{`repeat(1, nil);`, "repeat: argument 2: expected string, got nil", 14},
+		{`repeat(-1, "a");`, "negative count", 15},
+		{`isZero(256);`, "isZero: argument 1: expected uint8, got 256", 11},
+		{`join(["a", 1], "");`, "join: argument 1: list element 1: expected string, got 1", 18},
+		{`sum();`, "sum: expected at least 1 arguments, got 0", 5},
+		{`repeat(1);`, "expected 2 arguments, got 1", 9},
+		{`first([]);`, "first: panic: runtime error: index out of range [0] with length 0", 9},
+	}
+	for _, tt := range tests {
+		err := r.Run(tt.source, &bytes.Buffer{})
+		var rerr *diagnostic.RuntimeError
+		if assert.True(t, errors.As(err, &rerr), tt.source) {
+			assert.Equal(t, tt.msg, rerr.Msg, tt.source)
+			assert.Equal(t, 1, rerr.Line, tt.source)
+			assert.Equal(t, tt.col, rerr.Column, tt.source)
+		}
+	}
+}
+
+func TestGoFuncArity(t *testing.T) {
+	f, err := interpreter.NewGoFunc("sprint", fmt.Sprint)
+	assert.NoError(t, err)
+	assert.Equal(t, -1, f.Arity())
+
+	f, err = interpreter.NewGoFunc("repeat", strings.Repeat)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, f.Arity())
+
+	_, err = interpreter.NewGoFunc("notFunc", 1)
+	assert.Error(t, err)
+	_, err = interpreter.NewGoFunc("badResult", func() (int, int) { return 0, 0 })
+	assert.Error(t, err)
+}
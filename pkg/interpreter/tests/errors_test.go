@@ -0,0 +1,154 @@
+package tests
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/runner"
+)
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		// target is a pointer to the expected error type
+		target any
+		line   int
+		col    int
+		lexeme string
+	}{
+		{"scan", "var a = 1;\nvar b = @;", new(*diagnostic.ScanError), 2, 9, "@"},
+		{"unterminated string", "var a = \"abc\n;", new(*diagnostic.ScanError), 1, 9, "\"abc\n;"},
+		{"parse", "var a = 1;\nvar = 2;", new(*diagnostic.ParseError), 2, 5, "="},
+		{"parse in block", "{\n  print(1)\n}", new(*diagnostic.ParseError), 3, 1, "}"},
+		{"resolve", "fun f() {\n  return 1;\n}\nreturn 2;", new(*diagnostic.ResolveError), 4, 1, "return"},
+		{"break outside loop", "fun f() {\n  break;\n}", new(*diagnostic.ResolveError), 2, 3, "break"},
+		{"runtime", "var a = 1;\nprint(a + \"b\");", new(*diagnostic.RuntimeError), 2, 9, "+"},
+		{"undefined variable", "fun f() {\n  return g;\n}\nf();", new(*diagnostic.RuntimeError), 2, 10, "g"},
+		{"native", "push(1, 2);", new(*diagnostic.RuntimeError), 1, 10, ")"},
+		{"list index", "var l = [1];\nl[3];", new(*diagnostic.RuntimeError), 2, 2, "["},
+		{"unicode column", "var 名前 = \"日本\";\nprint(名前 + 1);", new(*diagnostic.RuntimeError), 2, 10, "+"},
+		{"string index", "var s = \"héllo\";\ns[5];", new(*diagnostic.RuntimeError), 2, 2, "["},
+		{"increment operand", "var a = 1;\n(a)++;", new(*diagnostic.ParseError), 2, 4, "++"},
+		{"compound assignment target", "1 += 2;", new(*diagnostic.ParseError), 1, 3, "+="},
+		{"conditional without else", "var a = true ? 1;", new(*diagnostic.ParseError), 1, 17, ";"},
+		{"compound assignment", "var a = \"a\";\na -= 1;", new(*diagnostic.RuntimeError), 2, 3, "-="},
+		{"match without case", "match (1) {\n  1 => 2;\n}", new(*diagnostic.ParseError), 2, 3, "1"},
+		{"yield outside function", "var a = 1;\nyield a;", new(*diagnostic.ResolveError), 2, 1, "yield"},
+		{"division by zero", "var a = 1;\nprint(a / 0);", new(*diagnostic.RuntimeError), 2, 9, "/"},
+		{"uncaught exception", "fun f() {\n  throw 1;\n}\nf();", new(*diagnostic.RuntimeError), 2, 3, "throw"},
+		{"alternative bindings", "match (1) {\n  case 1, x => nil;\n}", new(*diagnostic.ResolveError), 2, 11, "x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for backendName, backend := range backends {
+				t.Run(backendName, func(t *testing.T) {
+					r := runner.Runner{Backend: backend}
+					err := r.Run(tt.source, io.Discard)
+					if !errors.As(err, tt.target) {
+						t.Fatalf("expected %T, got %v", tt.target, err)
+					}
+
+					var pos diagnostic.Position
+					var lexeme string
+					switch target := tt.target.(type) {
+					case **diagnostic.ScanError:
+						pos, lexeme = (*target).Position, (*target).Token.Lexeme
+					case **diagnostic.ParseError:
+						pos, lexeme = (*target).Position, (*target).Token.Lexeme
+					case **diagnostic.ResolveError:
+						pos, lexeme = (*target).Position, (*target).Token.Lexeme
+					case **diagnostic.RuntimeError:
+						pos, lexeme = (*target).Position, (*target).Token.Lexeme
+					}
+					assert.Equal(t, tt.line, pos.Line)
+					assert.Equal(t, tt.col, pos.Column)
+					assert.Equal(t, tt.lexeme, lexeme)
+				})
+			}
+		})
+	}
+}
+
+func TestUndefinedGlobalAssignment(t *testing.T) {
+	r := runner.Runner{}
+	err := r.Run("undefined = 1;", io.Discard)
+
+	var rerr *diagnostic.RuntimeError
+	if assert.True(t, errors.As(err, &rerr)) {
+		assert.Equal(t, "undefined", rerr.Token.Lexeme)
+	}
+}
+
+func TestErrorStack(t *testing.T) {
+	source := `fun inner(l) {
+  return push(l, 1);
+}
+fun outer() {
+  return inner(nil);
+}
+outer();
+`
+	type frame struct {
+		function string
+		native   bool
+		line     int
+	}
+	expected := []frame{
+		{"<native fn push>", true, 0},
+		{"<fn inner>", false, 2},
+		{"<fn outer>", false, 5},
+		{"<script>", false, 7},
+	}
+
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			r := runner.Runner{Backend: backend}
+			err := r.Run(source, io.Discard)
+
+			var rerr *diagnostic.RuntimeError
+			if !errors.As(err, &rerr) {
+				t.Fatalf("expected a runtime error, got %v", err)
+			}
+			var stack []frame
+			for _, f := range rerr.Stack {
+				stack = append(stack, frame{f.Function, f.Native, f.Line})
+			}
+			assert.Equal(t, expected, stack)
+			assert.Equal(t, 5, rerr.Stack[1].Call.Ln)
+		})
+	}
+}
+
+func TestUncaughtException(t *testing.T) {
+	r := runner.Runner{}
+	err := r.Run("fun fail() {\n  throw \"boom\";\n}\nfail();", io.Discard)
+	var rerr *diagnostic.RuntimeError
+	if assert.ErrorAs(t, err, &rerr) {
+		assert.Equal(t, "uncaught exception: \"boom\"", rerr.Msg)
+		assert.Equal(t, 2, rerr.Line)
+		assert.Len(t, rerr.Stack, 2)
+	}
+	var thrown *interpreter.Thrown
+	if assert.ErrorAs(t, err, &thrown) {
+		assert.Equal(t, "boom", thrown.Value)
+	}
+
+	// limits can't be caught, so that scripts can't escape them.
+	r = runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetLimits(interpreter.Limits{Steps: 50})
+	}}
+	err = r.Run("while (true) { try { while (true) {} } catch (e) {} }", io.Discard)
+	assert.ErrorAs(t, err, new(*interpreter.StepLimitError))
+
+	r = runner.Runner{Backend: runner.BackendVM}
+	err = r.Run("fun down() { return down(); }\ntry { down(); } catch (e) {}", io.Discard)
+	if assert.ErrorAs(t, err, &rerr) {
+		assert.Equal(t, "call depth limit of 1024 exceeded", rerr.Msg)
+	}
+}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/runner"
+)
+
+// writeFiles writes the files into a temporary directory, and returns it.
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte(contents), 0o644))
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.lox": `
+import "lib/math.lox" as m;
+import "lib/util.lox" as u;
+fun helper() { return 100; }
+var result = m.twice(m.base);
+var calls = u.calls();
+`,
+		"lib/math.lox": `
+import "util.lox" as util;
+var base = 2;
+fun twice(x) { return util.double(x); }
+`,
+		"lib/util.lox": `
+loaded();
+var _calls = 0;
+fun helper() { return 0; }
+fun double(x) {
+  _calls = _calls + 1;
+  return x * 2 + helper();
+}
+fun calls() { return _calls; }
+`,
+	})
+
+	loads := 0
+	var intpr *interpreter.Interpreter
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		intpr = i
+		i.DefineNative("loaded", 0, func(args []interpreter.Value) (interpreter.Value, error) {
+			loads++
+			return nil, nil
+		})
+	}}
+	if !assert.NoError(t, r.Runfile(filepath.Join(dir, "main.lox"))) {
+		return
+	}
+
+	result, _ := intpr.GetGlobal("result")
+	assert.Equal(t, int64(4), result)
+	calls, _ := intpr.GetGlobal("calls")
+	assert.Equal(t, int64(1), calls)
+	assert.Equal(t, 1, loads, "modules are run once")
+	_, ok := intpr.GetGlobal("base")
+	assert.False(t, ok, "modules have their own globals")
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"private.lox": "import \"lib.lox\" as lib;\nlib._secret;",
+		"missing.lox": "import \"lib.lox\" as lib;\nlib.nothing;",
+		"assign.lox":  "import \"lib.lox\" as lib;\nlib.open = 1;",
+		"lib.lox":     "var _secret = 1;\nvar open = 2;",
+		"a.lox":       "import \"b.lox\" as b;",
+		"b.lox":       "import \"a.lox\" as a;",
+	})
+
+	tests := []struct {
+		file string
+		msg  string
+	}{
+		{"private.lox", "'_secret' is private to module lib"},
+		{"missing.lox", "module lib has no 'nothing'"},
+		{"assign.lox", "can't assign to 'open' of module lib"},
+		{"a.lox", "import cycle: " + filepath.Join(dir, "a.lox") + " -> " + filepath.Join(dir, "b.lox") + " -> " + filepath.Join(dir, "a.lox")},
+	}
+	for _, tt := range tests {
+		for backendName, backend := range backends {
+			t.Run(tt.file+"/"+backendName, func(t *testing.T) {
+				r := runner.Runner{Backend: backend}
+				err := r.Runfile(filepath.Join(dir, tt.file))
+				var rerr *diagnostic.RuntimeError
+				if assert.ErrorAs(t, err, &rerr) {
+					assert.Equal(t, tt.msg, rerr.Msg)
+				}
+			})
+		}
+	}
+
+	r := runner.Runner{Options: &interpreter.Options{}}
+	err := r.Runfile(filepath.Join(dir, "private.lox"))
+	assert.ErrorContains(t, err, "imports are not available")
+}
@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/parser"
+	"github.com/taehioum/glox/pkg/resolver"
+	"github.com/taehioum/glox/pkg/runner"
+	"github.com/taehioum/glox/pkg/scanner"
+)
+
+func TestContextDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetContext(ctx)
+	}}
+	err := r.Run("while (true) {}", io.Discard)
+
+	var cancelled *interpreter.CancelledError
+	assert.ErrorAs(t, err, &cancelled)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStepLimit(t *testing.T) {
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetLimits(interpreter.Limits{Steps: 100})
+	}}
+
+	err := r.Run("var i = 0;\nwhile (i < 10) { i = i + 1; }", io.Discard)
+	assert.NoError(t, err)
+
+	err = r.Run("var i = 0;\nwhile (true) { i = i + 1; }", io.Discard)
+	var limit *interpreter.StepLimitError
+	if assert.ErrorAs(t, err, &limit) {
+		assert.Equal(t, 100, limit.Limit)
+	}
+}
+
+func TestStepLimitPerRun(t *testing.T) {
+	i := interpreter.New(interpreter.Options{Limits: &interpreter.Limits{Steps: 100}})
+	r := resolver.New(i)
+	run := func(source string) error {
+		tokens, err := scanner.ScanTokens(source)
+		if err != nil {
+			return err
+		}
+		stmts, err := parser.Parse(tokens)
+		if err != nil {
+			return err
+		}
+		if err := r.Resolve(stmts); err != nil {
+			return err
+		}
+		return i.Interprete(stmts...)
+	}
+
+	// each run gets the whole budget, rather than what the previous ones left.
+	assert.NoError(t, run("var i = 0;\nwhile (i < 40) { i = i + 1; }"))
+	assert.NoError(t, run("\ni = 0;\nwhile (i < 40) { i = i + 1; }"))
+
+	var limit *interpreter.StepLimitError
+	assert.ErrorAs(t, run("\n\nwhile (true) { i = i + 1; }"), &limit)
+}
+
+func TestCallDepth(t *testing.T) {
+	source := `
+fun down(n) {
+  if (n == 0) return 0;
+  return down(n - 1);
+}
+down(depth);
+`
+
+	r := runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetGlobal("depth", float64(interpreter.DefaultCallDepth-1))
+	}}
+	assert.NoError(t, r.Run(source, io.Discard))
+
+	r = runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetGlobal("depth", 1e9)
+	}}
+	err := r.Run(source, io.Discard)
+	var depth *interpreter.CallDepthError
+	if assert.ErrorAs(t, err, &depth) {
+		assert.Equal(t, interpreter.DefaultCallDepth, depth.Limit)
+	}
+	assert.False(t, errors.Is(err, context.Canceled))
+
+	r = runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetLimits(interpreter.Limits{CallDepth: 10})
+		i.SetGlobal("depth", 20.0)
+	}}
+	assert.ErrorAs(t, r.Run(source, io.Discard), &depth)
+}
+
+func TestIntegerBits(t *testing.T) {
+	var out strings.Builder
+	r := runner.Runner{}
+	assert.NoError(t, r.Run("print(2 ** 1048575 > 0, (1 << 1048575) >> 1048574, (1 << 1000) >> 100000000000);", &out))
+	assert.Equal(t, "true 2 0\n", out.String())
+
+	for _, source := range []string{"2 ** 1048576;", "1 << 1048576;", "3 << 1048575;", "1.5d ** 1000000;"} {
+		err := r.Run(source, io.Discard)
+		if assert.Error(t, err, source) {
+			assert.Contains(t, err.Error(), "the result would take more than 1048576 bits")
+		}
+	}
+
+	r = runner.Runner{Setup: func(i *interpreter.Interpreter) {
+		i.SetLimits(interpreter.Limits{IntegerBits: 64})
+	}}
+	assert.NoError(t, r.Run("print(2 ** 63, 1 << 63, -(2 ** 62) << 1);", io.Discard))
+	for _, source := range []string{"2 ** 64;", "1 << 64;", "3 ** 41;", "-(2 ** 63) << 1;"} {
+		err := r.Run(source, io.Discard)
+		if assert.Error(t, err, source) {
+			assert.Contains(t, err.Error(), "the result would take more than 64 bits")
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/runner"
+)
+
+func TestSandbox(t *testing.T) {
+	r := runner.Runner{Options: &interpreter.Options{}}
+
+	var b bytes.Buffer
+	assert.NoError(t, r.Run("print(len([1, 2]) + 1);", &b))
+	assert.Equal(t, "3\n", b.String())
+
+	for _, name := range []string{"input", "eprint", "readFile", "writeFile", "clock", "getenv", "random"} {
+		err := r.Run(name+"();", io.Discard)
+		var rerr *diagnostic.RuntimeError
+		if assert.ErrorAs(t, err, &rerr, name) {
+			assert.Equal(t, "undefined variable '"+name+"'", rerr.Msg)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "out.txt")
+	var stderr bytes.Buffer
+	r := runner.Runner{Options: &interpreter.Options{
+		Modules: interpreter.ModuleAll,
+		Stdin:   strings.NewReader("first\nsecond"),
+		Stderr:  &stderr,
+		Now: func() time.Time {
+			return time.Unix(1700000000, 500000000)
+		},
+		Rand: rand.New(rand.NewSource(1)),
+		LookupEnv: func(key string) (string, bool) {
+			if key == "HOME" {
+				return "/home/glox", true
+			}
+			return "", false
+		},
+	}}
+	r.Setup = func(i *interpreter.Interpreter) {
+		i.SetGlobal("path", path)
+	}
+
+	var b bytes.Buffer
+	err := r.Run(`
+print(input(), input());
+eprint("oops", 1);
+print(clock());
+print(getenv("HOME"), getenv("UNSET"));
+var n = random();
+print(n >= 0 and n < 1);
+writeFile(path, "written");
+print(readFile(path));
+`, &b)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "first\n second\n1.7000000005e+09\n/home/glox <nil>\ntrue\nwritten\n", b.String())
+	assert.Equal(t, "oops 1\n", stderr.String())
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "written", string(contents))
+}
+
+func TestDecimalPrecision(t *testing.T) {
+	r := runner.Runner{Options: &interpreter.Options{DecimalPrecision: 2}}
+
+	var b bytes.Buffer
+	assert.NoError(t, r.Run("print(10d / 3, 2d / 3, 0.125d / 1, 0.135d / 1, 1.005d * 1);", &b))
+	assert.Equal(t, "3.33 0.67 0.12 0.14 1.005\n", b.String())
+}
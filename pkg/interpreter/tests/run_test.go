@@ -4,21 +4,382 @@ import (
 	"bytes"
 	_ "embed"
 	"io"
+	"runtime"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/taehioum/glox/pkg/runner"
 )
 
+// every script of the corpus must behave the same on all backends.
+var backends = map[string]runner.Backend{
+	"interpreter": runner.BackendInterpreter,
+	"vm":          runner.BackendVM,
+}
+
+func assertOutput(t *testing.T, name, source, expected string) {
+	assertOutputOn(t, backends, name, source, expected)
+}
+
+func assertOutputOn(t *testing.T, backends map[string]runner.Backend, name, source, expected string) {
+	for backendName, backend := range backends {
+		t.Run(backendName, func(t *testing.T) {
+			r := runner.Runner{Backend: backend}
+			var b bytes.Buffer
+			err := r.Run(source, io.Writer(&b))
+			if err != nil {
+				t.Fatalf("running %s: %s", name, err)
+			}
+			assert.Equal(t, expected, b.String())
+		})
+	}
+}
+
 //go:embed fib.lox
 var fib string
 
 func TestFib(t *testing.T) {
+	assertOutput(t, "fib.lox", fib, "0\n1\n1\n2\n3\n5\n8\n13\n21\n34\n")
+}
+
+//go:embed class.lox
+var class string
+
+func TestClass(t *testing.T) {
+	assertOutput(t, "class.lox", class, "12\n13\nCounter instance\nCounter\ntrue\n0\nboxed\n")
+}
+
+//go:embed inherit.lox
+var inherit string
+
+func TestInherit(t *testing.T) {
+	assertOutput(t, "inherit.lox", inherit, "log: login\nauth handled login (audited)\nhandler auth\ntrue\n")
+}
+
+//go:embed closure.lox
+var closure string
+
+func TestClosure(t *testing.T) {
+	assertOutput(t, "closure.lox", closure, "3 1\nafter\n1\n3\n4\ntrue true -5 3.5\n")
+}
+
+//go:embed bench.lox
+var bench string
+
+func BenchmarkFib(b *testing.B) {
+	for backendName, backend := range backends {
+		b.Run(backendName, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				r := runner.Runner{Backend: backend}
+				if err := r.Run(bench, io.Discard); err != nil {
+					b.Fatalf("running bench.lox: %s", err)
+				}
+			}
+		})
+	}
+}
+
+//go:embed list.lox
+var list string
+
+func TestList(t *testing.T) {
+	assertOutput(t, "list.lox", list, "[1, 2, 3]\n4\n[1, \"two\", 3] 3\ntrue 4\n10 true false []\n[0, 1, 4, 9, 16]\n")
+}
+
+//go:embed map.lox
+var hashmap string
+
+func TestMap(t *testing.T) {
+	assertOutput(t, "map.lox", hashmap, `{"a": 1, "b": 2} 2
+3 <nil>
+{"a": 1, "b": 2, "c": [3], 1: "one", true: nil}
+["a", "b", "c", 1, true] [1, 2, [3], "one", nil]
+true false true
+true false
+{"b": 2, "c": [3], 1: "one", true: nil}
+{"k": "v"}
+{"port": 8080, "host": "localhost"}
+false
+{"count": 1}
+`)
+}
+
+//go:embed exception.lox
+var exception string
+
+func TestException(t *testing.T) {
+	assertOutput(t, "exception.lox", exception, `caught boom
+caught {"code": 42}
+operands must be two numbers or two strings, got 1 and true 5
+undefined variable 'missing' 9
+expected 1 arguments, got 2 15
+finally
+inner finally
+outer caught rethrown
+0 finally
+1 finally
+2 finally
+2 finally
+done
+returned finally
+finally overrides
+caught deep
+inner 0
+outer 0
+inner 1
+outer 1
+returned 1
+finally after catch
+caught b
+`)
+}
+
+//go:embed string.lox
+var str string
+
+func TestString(t *testing.T) {
+	assertOutput(t, "string.lox", str, `Hello world!
+tab:	| quote:" backslash:\ unicode:Hé
+${not interpolated}
+hi kim, 3 letters
+items [1, 2] first 11 map v
+nested inner world
+raw \n ${name}
+second line
+multi
+line
+abbb
+`)
+}
+
+//go:embed unicode.lox
+var unicode string
+
+func TestUnicode(t *testing.T) {
+	assertOutput(t, "unicode.lox", unicode, "12 m e\nbrûlée 本語\nはちにんこ 1 😀\n")
+}
+
+//go:embed ints.lox
+var ints string
+
+func TestInts(t *testing.T) {
+	assertOutput(t, "ints.lox", ints, `3.5 3 -4 1 2 -2
+1024 512 -4 0.5 6.25
+3.5 4.5 9.5 true true
+255 15 10 1000000 65535
+2 7 5 -6 1024 -4
+14 3 true
+one 30
+42
+division by zero +Inf
+division by zero
+operands must be integers, got 1.5 and 1
+9007199254740993 9007199254740995 1
+half
+`)
+}
+
+//go:embed bignum.lox
+var bignum string
+
+func TestBignum(t *testing.T) {
+	assertOutput(t, "bignum.lox", bignum, `9223372036854775808 -9223372036854775809 85070591730234615847396907784232501249
+1267650600228229401496703205376 4 1
+890 1180591620717411303424 2
+9223372036854775808 true true
+265252859812191058636308480000000
+false true 0.3
+3.3 2.5 0.3333333333333333333333333333 0.6666666666666666666666666667
+2.5 1.75 3 1.5 1.21 0.25
+true true -1.25 [1.5] two
+big big
+<nil> min
+exponent 100000000000 is too large, the result would take more than 1048576 bits
+exponent 100000000000 is too large, the result would take more than 1048576 bits
+1 -1
+`)
+}
+
+//go:embed compound.lox
+var compound string
+
+func TestCompound(t *testing.T) {
+	assertOutput(t, "compound.lox", compound, `1 2 3 3 3 1
+abc
+3.5 4.5
+5 6
+[1, 21, 3] 21 2
+-1
+2 3
+11
+1
+`)
+}
+
+//go:embed increment.lox
+var increment string
+
+func TestIncrement(t *testing.T) {
+	assertOutput(t, "increment.lox", increment, `1 2 2 1
+5 6
+0
+10 [11, 20] 1
+`)
+}
+
+//go:embed conditional.lox
+var conditional string
+
+func TestConditional(t *testing.T) {
+	assertOutput(t, "conditional.lox", conditional, `big small
+positive
+1 12
+localhost 8080 false
+last fallback false
+1 x 4 2
+yes yes
+`)
+}
+
+//go:embed match.lox
+var match string
+
+func TestMatch(t *testing.T) {
+	assertOutput(t, "match.lox", match, `zero small small minus one
+greeting nothing empty one of 7
+pair of 4 starts with 4 other
+ann is 30 other
+a thousand other
+sum 12
+outer 1
+["none", "few", "many"]
+no arm matches 42
+b
+`)
+}
+
+//go:embed forin.lox
+var forin string
+
+func TestForIn(t *testing.T) {
+	assertOutput(t, "forin.lox", forin, `10
+ann 30
+bob 25
+["h", "é", "l", "l", "o"]
+[0, 2, 4, 6, 8] [3, 2, 1] range(0, 5, 1)
+[1, 3, 5, 7]
+1 3
+[3, 2, 1]
+[1, 2, 3, 4]
+can't iterate over 42
+`)
+}
+
+//go:embed generator.lox
+var generator string
+
+func TestGenerator(t *testing.T) {
+	assertOutput(t, "generator.lox", generator, `1 2 3 done done
+[0, 1, 4, 9, 16]
+[1, 3, 5, 7]
+1
+cleanup
+1
+cleanup
+done
+[1, 2, 3, 4]
+1
+caught boom
+done <generator failing>
+`)
+}
+
+func TestAbandonedGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+
 	r := runner.Runner{}
-	var b bytes.Buffer
-	err := r.Run(fib, io.Writer(&b))
-	if err != nil {
-		t.Fatalf("running fib.lox: %s", err)
+	err := r.Run(`
+fun numbers() {
+  var n = 0;
+  while (true) {
+    yield n;
+    n++;
+  }
+}
+for (var i = 0; i < 100; i++) {
+  numbers().next();
+}`, io.Discard)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// the goroutines of the suspended generators exit once the generators are collected.
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected at most %d goroutines, got %d", before, runtime.NumGoroutine())
+}
+
+func TestSuspendedGeneratorsInGlobals(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	// a generator in a global is never collected, so it is closed once the program is done.
+	source := `
+fun numbers() {
+  try {
+    var n = 0;
+    while (true) {
+      yield n;
+      n++;
+    }
+  } finally {
+    print("closed");
+  }
+}
+var g = numbers();
+print(g.next());`
+	for i := 0; i < 20; i++ {
+		var out bytes.Buffer
+		r := runner.Runner{}
+		if !assert.NoError(t, r.Run(source, &out)) {
+			return
+		}
+		assert.Equal(t, "0\nclosed\n", out.String())
 	}
-	assert.Equal(t, "0\n1\n1\n2\n3\n5\n8\n13\n21\n34\n", b.String())
+
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if runtime.NumGoroutine() <= before {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("expected at most %d goroutines, got %d", before, runtime.NumGoroutine())
+}
+
+//go:embed locals.lox
+var locals string
+
+func TestAssignLocals(t *testing.T) {
+	assertOutput(t, "locals.lox", locals, `[1, [2]]
+{"a": [1]}
+4
+5
+2
+6
+3
+7 7
+7!
+yes
+-9
+fallback
+false
+3
+4
+`)
 }
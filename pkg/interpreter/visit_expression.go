@@ -1,9 +1,13 @@
 package interpreter
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
+	"strings"
 
 	expressions "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
@@ -13,15 +17,18 @@ func (i *Interpreter) VisitAssignment(e expressions.Assignment) (any, error) {
 		return nil, err
 	}
 
-	if distance, ok := i.Locals[e]; ok {
+	if distance, ok := i.Locals[e.Name]; ok {
 		i.env.AssignAt(distance, e.Name.Lexeme, v)
-	} else {
-		i.global.Assign(e.Name.Lexeme, v)
+	} else if err := i.global.Assign(e.Name.Lexeme, v); err != nil {
+		return nil, i.undefinedVariable(e.Name)
 	}
 	return v, nil
 }
 
 func (i *Interpreter) VisitLiteral(e expressions.Literal) (any, error) {
+	if r, ok := e.Value.(*big.Rat); ok {
+		return Decimal{r}, nil
+	}
 	return e.Value, nil
 }
 
@@ -30,7 +37,7 @@ func (i *Interpreter) VisitGrouping(e expressions.Grouping) (any, error) {
 }
 
 func (i *Interpreter) VisitVariable(e expressions.Variable) (any, error) {
-	return i.lookup(e)
+	return i.lookup(e.Name)
 }
 
 func (i *Interpreter) VisitUnary(e expressions.Unary) (any, error) {
@@ -41,10 +48,18 @@ func (i *Interpreter) VisitUnary(e expressions.Unary) (any, error) {
 
 	switch e.Operator.Type {
 	case token.MINUS:
-		if n, ok := right.(float64); ok {
-			return -n, nil
+		if n, ok := negate(right); ok {
+			return n, nil
+		}
+		return nil, diagnostic.NewRuntimeError(e.Operator, "operand must be a number, got %v", right)
+	case token.TILDE:
+		switch n := right.(type) {
+		case int64:
+			return ^n, nil
+		case *big.Int:
+			return normalizeInt(new(big.Int).Not(n)), nil
 		}
-		return nil, fmt.Errorf("expected numbers, got %T", right)
+		return nil, diagnostic.NewRuntimeError(e.Operator, "operand must be an integer, got %v", right)
 	case token.BANG:
 		if right == nil { // nil is falsy
 			return true, nil
@@ -62,27 +77,110 @@ func (i *Interpreter) VisitUnary(e expressions.Unary) (any, error) {
 	}
 }
 
+// VisitPostUnary evaluates to the value of the operand before it is incremented or decremented.
 func (i *Interpreter) VisitPostUnary(e expressions.PostUnary) (any, error) {
-	v, err := i.Eval(e.Left)
-	if err != nil {
-		return nil, err
+	old, _, err := i.update(e.Left, func(v any) (any, error) {
+		return i.increment(e.Operator, v)
+	})
+	return old, err
+}
+
+// VisitPreUnary evaluates to the value of the operand after it is incremented or decremented.
+func (i *Interpreter) VisitPreUnary(e expressions.PreUnary) (any, error) {
+	_, v, err := i.update(e.Right, func(v any) (any, error) {
+		return i.increment(e.Operator, v)
+	})
+	return v, err
+}
+
+// compoundOperators are the operators that compound assignments apply.
+var compoundOperators = map[token.Type]token.Type{
+	token.PLUSEQUAL:    token.PLUS,
+	token.MINUSEQUAL:   token.MINUS,
+	token.STAREQUAL:    token.STAR,
+	token.SLASHEQUAL:   token.SLASH,
+	token.PERCENTEQUAL: token.PERCENT,
+}
+
+func (i *Interpreter) VisitCompound(e expressions.Compound) (any, error) {
+	op := e.Operator
+	op.Type = compoundOperators[e.Operator.Type]
+	_, v, err := i.update(e.Target, func(old any) (any, error) {
+		v, err := i.Eval(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		return i.binary(op, old, v)
+	})
+	return v, err
+}
+
+// increment adds 1 to the number for ++, and subtracts it for --.
+func (i *Interpreter) increment(operator token.Token, v any) (any, error) {
+	if !isNumber(v) {
+		return nil, diagnostic.NewRuntimeError(operator, "operand must be a number, got %v", v)
 	}
+	op := operator
+	if operator.Type == token.PLUSPLUS {
+		op.Type = token.PLUS
+	} else {
+		op.Type = token.MINUS
+	}
+	return i.arithmetic(op, v, int64(1))
+}
 
-	switch e.Operator.Type {
-	case token.PLUSPLUS:
-		if n, ok := v.(float64); ok {
-			i.env.Assign(e.Left.(expressions.Variable).Name.Lexeme, n+1)
-			return n + 1, nil
-		}
-		return nil, fmt.Errorf("expected numbers, got %T", v)
-	case token.MINUSMINUS:
-		if n, ok := v.(float64); ok {
-			i.env.Assign(e.Left.(expressions.Variable).Name.Lexeme, n-1)
-			return n - 1, nil
-		}
-		return nil, fmt.Errorf("expected numbers, got %T", v)
+// update replaces the value of the target, a variable, a property or an index, with the result of f on it.
+// the object and the index of the target are evaluated once, and it returns the old value and the new one.
+func (i *Interpreter) update(target expressions.Expr, f func(old any) (any, error)) (old any, v any, err error) {
+	switch t := target.(type) {
+	case expressions.Variable:
+		if old, err = i.lookup(t.Name); err != nil {
+			return nil, nil, err
+		}
+		if v, err = f(old); err != nil {
+			return nil, nil, err
+		}
+		// assign through the distance the variable was resolved at, like reading it did.
+		if distance, ok := i.Locals[t.Name]; ok {
+			i.env.AssignAt(distance, t.Name.Lexeme, v)
+		} else if err := i.global.Assign(t.Name.Lexeme, v); err != nil {
+			return nil, nil, i.undefinedVariable(t.Name)
+		}
+		return old, v, nil
+	case expressions.Get:
+		obj, err := i.Eval(t.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		object, ok := obj.(Object)
+		if !ok {
+			return nil, nil, diagnostic.NewRuntimeError(t.Name, "only instances have fields")
+		}
+		if old, err = object.Get(t.Name); err != nil {
+			return nil, nil, err
+		}
+		if v, err = f(old); err != nil {
+			return nil, nil, err
+		}
+		return old, v, object.Set(t.Name, v)
+	case expressions.Index:
+		obj, err := i.Eval(t.Object)
+		if err != nil {
+			return nil, nil, err
+		}
+		idx, err := i.Eval(t.Index)
+		if err != nil {
+			return nil, nil, err
+		}
+		if old, err = index(t.Bracket, obj, idx); err != nil {
+			return nil, nil, err
+		}
+		if v, err = f(old); err != nil {
+			return nil, nil, err
+		}
+		return old, v, setIndex(t.Bracket, obj, idx, v)
 	default:
-		return nil, fmt.Errorf("unknown expression %T", e)
+		return nil, nil, fmt.Errorf("can't assign to %T", target)
 	}
 }
 
@@ -95,45 +193,31 @@ func (i *Interpreter) VisitBinary(e expressions.Binary) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	return i.binary(e.Operator, l, r)
+}
 
-	switch e.Operator.Type {
-	case token.MINUS:
-		if !checkNumberOperands(l, r) {
-			return nil, fmt.Errorf("operands must be numbers")
-		}
-		return l.(float64) - r.(float64), nil
-	case token.SLASH:
-		if !checkNumberOperands(l, r) {
-			return nil, fmt.Errorf("operands must be numbers")
-		}
-		return l.(float64) / r.(float64), nil
-	case token.STAR:
-		if !checkNumberOperands(l, r) {
-			return nil, fmt.Errorf("operands must be numbers")
-		}
-		return l.(float64) * r.(float64), nil
-	case token.PLUS: // todo: tidy
-		if checkNumberOperands(l, r) {
-			return l.(float64) + r.(float64), nil
-		} else if checkStringOperands(l, r) {
+// binary applies the operator to the operands, for binary expressions and compound assignments alike.
+func (i *Interpreter) binary(op token.Token, l, r any) (any, error) {
+	switch op.Type {
+	case token.PLUS:
+		if checkStringOperands(l, r) {
 			return l.(string) + r.(string), nil
-		} else {
-			return nil, fmt.Errorf("operands must be numbers or strings")
-		}
-	case token.GREATER:
-		return l.(float64) > r.(float64), nil
-	case token.GREATEREQUAL:
-		return l.(float64) >= r.(float64), nil
-	case token.LESS:
-		return l.(float64) < r.(float64), nil
-	case token.LESSEQUAL:
-		return l.(float64) <= r.(float64), nil
+		}
+		if !isNumber(l) || !isNumber(r) {
+			return nil, diagnostic.NewRuntimeError(op, "operands must be two numbers or two strings, got %v and %v", l, r)
+		}
+		return i.arithmetic(op, l, r)
+	case token.MINUS, token.STAR, token.SLASH, token.SLASHSLASH, token.PERCENT, token.STARSTAR,
+		token.AMPERSAND, token.PIPE, token.CARET, token.LESSLESS, token.GREATERGREATER:
+		return i.arithmetic(op, l, r)
+	case token.GREATER, token.GREATEREQUAL, token.LESS, token.LESSEQUAL:
+		return comparison(op, l, r)
 	case token.BANGEQUAL: // deep equality for numbers, bools, strings
-		return l != r, nil
+		return !equal(l, r), nil
 	case token.EQUALEQUAL: // deep equality for numbers, bools, strings
-		return l == r, nil
+		return equal(l, r), nil
 	default:
-		return nil, fmt.Errorf("unknown binary expression %T", e)
+		return nil, fmt.Errorf("unknown binary operator %s", op.Lexeme)
 	}
 }
 
@@ -142,11 +226,17 @@ func (i *Interpreter) VisitLogical(e expressions.Logical) (any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if e.Operator.Type == token.OR {
+	switch e.Operator.Type {
+	case token.OR:
 		if truthy(lv) {
 			return lv, nil
 		}
-	} else {
+	case token.QUESTIONQUESTION:
+		// only nil falls back, unlike or, so that false and 0 are kept.
+		if lv != nil {
+			return lv, nil
+		}
+	default:
 		if !truthy(lv) {
 			return lv, nil
 		}
@@ -154,6 +244,17 @@ func (i *Interpreter) VisitLogical(e expressions.Logical) (any, error) {
 	return i.Eval(e.Right)
 }
 
+func (i *Interpreter) VisitConditional(e expressions.Conditional) (any, error) {
+	cond, err := i.Eval(e.Condition)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(cond) {
+		return i.Eval(e.Then)
+	}
+	return i.Eval(e.Else)
+}
+
 func (i *Interpreter) VisitCall(e expressions.Call) (any, error) {
 	callee, err := i.Eval(e.Callee)
 	if err != nil {
@@ -170,19 +271,237 @@ func (i *Interpreter) VisitCall(e expressions.Call) (any, error) {
 	}
 
 	if fn, ok := callee.(Callable); ok {
-		if fn.Arity() != -1 && len(args) != fn.Arity() {
-			return nil, fmt.Errorf("expected %d arguments, got %d", fn.Arity(), len(args))
+		return i.call(e.Paren, fn, args)
+	} else {
+		return nil, diagnostic.NewRuntimeError(e.Paren, "can only call functions and classes, got %v", callee)
+	}
+}
+
+// call calls the callable with the arguments, as if called at the token.
+func (i *Interpreter) call(paren token.Token, fn Callable, args []any) (any, error) {
+	if fn.Arity() != -1 && len(args) != fn.Arity() {
+		return nil, diagnostic.NewRuntimeError(paren, "expected %d arguments, got %d", fn.Arity(), len(args))
+	}
+	i.frames = append(i.frames, newFrame(fn, paren))
+	defer func() {
+		i.frames = i.frames[:len(i.frames)-1]
+	}()
+
+	v, err := fn.Call(i, args)
+	if err == nil {
+		return v, nil
+	}
+	var rerr *diagnostic.RuntimeError
+	if !errors.As(err, &rerr) {
+		// natives don't know where they are called from, so their errors are positioned at the call.
+		rerr = diagnostic.WrapRuntimeError(paren, err)
+		err = rerr
+	}
+	if rerr.Stack == nil {
+		// the innermost call sees the error first, while the stack is still complete.
+		rerr.Stack = i.stack(rerr.Position)
+	}
+	return nil, err
+}
+
+func (i *Interpreter) VisitLambda(e expressions.Lambda) (any, error) {
+	return Function{def: e, closure: i.env, globals: i.global}, nil
+}
+
+func (i *Interpreter) VisitGet(e expressions.Get) (any, error) {
+	obj, err := i.Eval(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := obj.(Object)
+	if !ok {
+		return nil, diagnostic.NewRuntimeError(e.Name, "only instances have properties")
+	}
+	return object.Get(e.Name)
+}
+
+func (i *Interpreter) VisitSet(e expressions.Set) (any, error) {
+	obj, err := i.Eval(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := obj.(Object)
+	if !ok {
+		return nil, diagnostic.NewRuntimeError(e.Name, "only instances have fields")
+	}
+
+	v, err := i.Eval(e.Value)
+	if err != nil {
+		return nil, err
+	}
+	if err := object.Set(e.Name, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (i *Interpreter) VisitThis(e expressions.This) (any, error) {
+	return i.lookup(e.Keyword)
+}
+
+func (i *Interpreter) VisitSuper(e expressions.Super) (any, error) {
+	distance, ok := i.Locals[e.Keyword]
+	if !ok {
+		return nil, diagnostic.NewRuntimeError(e.Keyword, "unresolved 'super'")
+	}
+	v, err := i.env.GetAt(distance, "super")
+	if err != nil {
+		return nil, err
+	}
+	superclass := v.(*Class)
+
+	// 'this' is always bound right inside the environment that binds 'super'.
+	v, err = i.env.GetAt(distance-1, "this")
+	if err != nil {
+		return nil, err
+	}
+	instance := v.(*Instance)
+
+	method, ok := superclass.findMethod(e.Method.Lexeme)
+	if !ok {
+		return nil, diagnostic.NewRuntimeError(e.Method, "undefined property '%s'", e.Method.Lexeme)
+	}
+	return method.bind(instance), nil
+}
+
+func (i *Interpreter) VisitList(e expressions.List) (any, error) {
+	elements := make([]any, len(e.Elements))
+	for idx, el := range e.Elements {
+		v, err := i.Eval(el)
+		if err != nil {
+			return nil, err
 		}
-		v, err := fn.Call(i, args)
+		elements[idx] = v
+	}
+	return &List{Elements: elements}, nil
+}
+
+func (i *Interpreter) VisitInterpolation(e expressions.Interpolation) (any, error) {
+	var b strings.Builder
+	for _, part := range e.Parts {
+		v, err := i.Eval(part)
 		if err != nil {
-			return nil, fmt.Errorf("line %d: %w", e.Paren.Ln, err)
+			return nil, err
 		}
-		return v, nil
-	} else {
-		return nil, fmt.Errorf("can only call functions and classes")
+		// values are formatted as print does.
+		fmt.Fprint(&b, v)
 	}
+	return b.String(), nil
 }
 
-func (i *Interpreter) VisitLambda(e expressions.Lambda) (any, error) {
-	return Function{def: e, closure: i.env}, nil
+func (i *Interpreter) VisitIndex(e expressions.Index) (any, error) {
+	obj, err := i.Eval(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	v, err := i.Eval(e.Index)
+	if err != nil {
+		return nil, err
+	}
+	return index(e.Bracket, obj, v)
+}
+
+// index returns the element of the list, the value of the map, or the code point of the string at the index.
+func index(bracket token.Token, obj any, v any) (any, error) {
+	switch o := obj.(type) {
+	case *List:
+		idx, err := o.index(bracket, v)
+		if err != nil {
+			return nil, err
+		}
+		return o.Elements[idx], nil
+	case *Map:
+		if err := checkKey(v); err != nil {
+			return nil, diagnostic.WrapRuntimeError(bracket, err)
+		}
+		// missing keys evaluate to nil
+		res, _ := o.Get(v)
+		return res, nil
+	case string:
+		return stringIndex(bracket, o, v)
+	default:
+		return nil, diagnostic.NewRuntimeError(bracket, "can only index lists, maps and strings, got %v", obj)
+	}
+}
+
+// setIndex sets the element of the list, or the value of the map, at the index.
+func setIndex(bracket token.Token, obj any, v any, value any) error {
+	switch o := obj.(type) {
+	case *List:
+		idx, err := o.index(bracket, v)
+		if err != nil {
+			return err
+		}
+		o.Elements[idx] = value
+		return nil
+	case *Map:
+		if err := checkKey(v); err != nil {
+			return diagnostic.WrapRuntimeError(bracket, err)
+		}
+		o.Set(v, value)
+		return nil
+	default:
+		return diagnostic.NewRuntimeError(bracket, "can only index lists and maps, got %v", obj)
+	}
+}
+
+func (i *Interpreter) VisitIndexSet(e expressions.IndexSet) (any, error) {
+	obj, err := i.Eval(e.Object)
+	if err != nil {
+		return nil, err
+	}
+	v, err := i.Eval(e.Index)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := obj.(type) {
+	case *List:
+		idx, err := o.index(e.Bracket, v)
+		if err != nil {
+			return nil, err
+		}
+		value, err := i.Eval(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		o.Elements[idx] = value
+		return value, nil
+	case *Map:
+		if err := checkKey(v); err != nil {
+			return nil, diagnostic.WrapRuntimeError(e.Bracket, err)
+		}
+		value, err := i.Eval(e.Value)
+		if err != nil {
+			return nil, err
+		}
+		o.Set(v, value)
+		return value, nil
+	default:
+		return nil, diagnostic.NewRuntimeError(e.Bracket, "can only index lists and maps, got %v", obj)
+	}
+}
+
+func (i *Interpreter) VisitMap(e expressions.Map) (any, error) {
+	m := NewMap()
+	for idx := range e.Keys {
+		k, err := i.Eval(e.Keys[idx])
+		if err != nil {
+			return nil, err
+		}
+		if err := checkKey(k); err != nil {
+			return nil, diagnostic.WrapRuntimeError(e.Brace, err)
+		}
+		v, err := i.Eval(e.Values[idx])
+		if err != nil {
+			return nil, err
+		}
+		m.Set(k, v)
+	}
+	return m, nil
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	statements "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/interpreter/environment"
 )
 
@@ -29,7 +30,7 @@ func (i *Interpreter) VisitBlock(stmt statements.Block) error {
 	}()
 	i.env = environment.NewEnclosedEnvironment(prev)
 	for _, stmt := range stmt.Stmts {
-		err := stmt.Accept(i)
+		err := i.execute(stmt)
 		if err != nil {
 			return err
 		}
@@ -43,10 +44,10 @@ func (i *Interpreter) VisitIf(stmt statements.If) error {
 		return err
 	}
 	if truthy(v) {
-		return stmt.Then.Accept(i)
+		return i.execute(stmt.Then)
 	}
 	if stmt.Else != nil {
-		return stmt.Else.Accept(i)
+		return i.execute(stmt.Else)
 	}
 	return nil
 }
@@ -60,7 +61,7 @@ func (i *Interpreter) VisitWhile(stmt statements.While) error {
 		if !truthy(v) {
 			break
 		}
-		err = stmt.Body.Accept(i)
+		err = i.execute(stmt.Body)
 		if errors.Is(err, ErrBreak) {
 			return nil
 		}
@@ -98,3 +99,48 @@ func (i *Interpreter) VisitReturn(stmt statements.Return) error {
 	}
 	return ErrReturn{Value: v}
 }
+
+func (i *Interpreter) VisitClass(stmt statements.Class) error {
+	var superclass *Class
+	if stmt.Superclass != nil {
+		v, err := i.Eval(*stmt.Superclass)
+		if err != nil {
+			return err
+		}
+		c, ok := v.(*Class)
+		if !ok {
+			return diagnostic.NewRuntimeError(stmt.Superclass.Name, "superclass must be a class")
+		}
+		superclass = c
+	}
+
+	// define first, so that methods can refer to the class.
+	i.env.Define(stmt.Name.Lexeme, nil)
+
+	if superclass != nil {
+		// methods are closed over an environment that binds 'super'
+		i.env = environment.NewEnclosedEnvironment(i.env)
+		i.env.Define("super", superclass)
+	}
+
+	methods := make(map[string]Function)
+	for _, m := range stmt.Methods {
+		methods[m.Name.Lexeme] = Function{
+			def:           m,
+			closure:       i.env,
+			globals:       i.global,
+			isInitializer: m.Name.Lexeme == "init",
+		}
+	}
+
+	if superclass != nil {
+		i.env = i.env.Enclosing()
+	}
+
+	i.env.Define(stmt.Name.Lexeme, &Class{
+		Name:       stmt.Name.Lexeme,
+		superclass: superclass,
+		methods:    methods,
+	})
+	return nil
+}
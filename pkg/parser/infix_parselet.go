@@ -1,11 +1,10 @@
 package parser
 
 import (
-	"errors"
-	"fmt"
 	"log/slog"
 
 	expressions "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
@@ -39,6 +38,82 @@ func (p FactorParselet) precedence() Precedence {
 	return PrecedenceFactor
 }
 
+// PowerParselet is right associative, so that 2 ** 3 ** 2 is 2 ** 9.
+type PowerParselet struct{}
+
+func (p PowerParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedencePower - 1)
+	return expressions.Binary{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p PowerParselet) precedence() Precedence {
+	return PrecedencePower
+}
+
+type ShiftParselet struct{}
+
+func (p ShiftParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceShift)
+	return expressions.Binary{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p ShiftParselet) precedence() Precedence {
+	return PrecedenceShift
+}
+
+type BitAndParselet struct{}
+
+func (p BitAndParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceBitAnd)
+	return expressions.Binary{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p BitAndParselet) precedence() Precedence {
+	return PrecedenceBitAnd
+}
+
+type BitXorParselet struct{}
+
+func (p BitXorParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceBitXor)
+	return expressions.Binary{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p BitXorParselet) precedence() Precedence {
+	return PrecedenceBitXor
+}
+
+type BitOrParselet struct{}
+
+func (p BitOrParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceBitOr)
+	return expressions.Binary{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p BitOrParselet) precedence() Precedence {
+	return PrecedenceBitOr
+}
+
 type ComparsionParselet struct{}
 
 func (p ComparsionParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
@@ -75,20 +150,93 @@ func (p AssignmentParselet) parse(parser *Parser, left expressions.Expr, token t
 	slog.Debug("assignment parselet: ", "left", left)
 	expr, err := parser.parseExpr(PrecedenceAssignment - 1)
 
-	variable, ok := left.(expressions.Variable)
-	if !ok {
-		return nil, fmt.Errorf("line %d's %s: left hand side of assignment must be a variable", token.Ln, token.Lexeme)
+	switch l := left.(type) {
+	case expressions.Variable:
+		return expressions.Assignment{
+			Name:  l.Name,
+			Value: expr,
+		}, err
+	case expressions.Get:
+		return expressions.Set{
+			Object: l.Object,
+			Name:   l.Name,
+			Value:  expr,
+		}, err
+	case expressions.Index:
+		return expressions.IndexSet{
+			Object:  l.Object,
+			Bracket: l.Bracket,
+			Index:   l.Index,
+			Value:   expr,
+		}, err
+	default:
+		return nil, diagnostic.NewParseError(token, "left hand side of assignment must be a variable, a property or an index")
 	}
-	return expressions.Assignment{
-		Name:  variable.Name,
-		Value: expr,
-	}, err
 }
 
 func (p AssignmentParselet) precedence() Precedence {
 	return PrecedenceAssignment
 }
 
+// CompoundAssignmentParselet parses the assignments combined with an operator, e.g. x += 1.
+type CompoundAssignmentParselet struct{}
+
+func (p CompoundAssignmentParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceAssignment - 1)
+	if !assignable(left) {
+		return nil, diagnostic.NewParseError(token, "left hand side of assignment must be a variable, a property or an index")
+	}
+	return expressions.Compound{
+		Target:   left,
+		Operator: token,
+		Value:    expr,
+	}, err
+}
+
+func (p CompoundAssignmentParselet) precedence() Precedence {
+	return PrecedenceAssignment
+}
+
+// ConditionalParselet is right associative, so that a ? b : c ? d : e is a ? b : (c ? d : e).
+type ConditionalParselet struct{}
+
+func (p ConditionalParselet) parse(parser *Parser, left expressions.Expr, tok token.Token) (expressions.Expr, error) {
+	then, err := parser.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := parser.consumeAndCheck(token.COLON, "expected ':' after then branch of conditional expression"); err != nil {
+		return nil, err
+	}
+	els, err := parser.parseExpr(PrecedenceConditional - 1)
+	return expressions.Conditional{
+		Condition: left,
+		Question:  tok,
+		Then:      then,
+		Else:      els,
+	}, err
+}
+
+func (p ConditionalParselet) precedence() Precedence {
+	return PrecedenceConditional
+}
+
+// CoalesceParselet parses a ?? b, which short-circuits like or, but only falls back when a is nil.
+type CoalesceParselet struct{}
+
+func (p CoalesceParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceCoalesce)
+	return expressions.Logical{
+		Left:     left,
+		Operator: token,
+		Right:    expr,
+	}, err
+}
+
+func (p CoalesceParselet) precedence() Precedence {
+	return PrecedenceCoalesce
+}
+
 type OrParselet struct{}
 
 func (p OrParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
@@ -133,7 +281,7 @@ func (p CallParselet) parse(parser *Parser, left expressions.Expr, tok token.Tok
 				return nil, err
 			}
 			if len(args) >= 255 {
-				return nil, errors.New("can't have more than 255 arguments")
+				return nil, diagnostic.NewParseError(tok, "can't have more than 255 arguments")
 			}
 			args = append(args, expr)
 
@@ -157,3 +305,44 @@ func (p CallParselet) parse(parser *Parser, left expressions.Expr, tok token.Tok
 func (p CallParselet) precedence() Precedence {
 	return PrecedenceCall
 }
+
+// GetParselet parses property accesses like a.b
+type GetParselet struct{}
+
+func (p GetParselet) parse(parser *Parser, left expressions.Expr, tok token.Token) (expressions.Expr, error) {
+	name, err := parser.consumeAndCheck(token.IDENTIFIER, "expected property name after '.'")
+	if err != nil {
+		return nil, err
+	}
+	return expressions.Get{
+		Object: left,
+		Name:   name,
+	}, nil
+}
+
+func (p GetParselet) precedence() Precedence {
+	return PrecedenceCall
+}
+
+// IndexParselet parses subscripts like a[b]
+type IndexParselet struct{}
+
+func (p IndexParselet) parse(parser *Parser, left expressions.Expr, tok token.Token) (expressions.Expr, error) {
+	index, err := parser.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	_, err = parser.consumeAndCheck(token.RIGHTBRACKET, "expected ']' after index")
+	if err != nil {
+		return nil, err
+	}
+	return expressions.Index{
+		Object:  left,
+		Bracket: tok,
+		Index:   index,
+	}, nil
+}
+
+func (p IndexParselet) precedence() Precedence {
+	return PrecedenceCall
+}
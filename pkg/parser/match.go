@@ -0,0 +1,177 @@
+package parser
+
+import (
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// parseMatch parses the value and the arms of a match whose keyword is already consumed,
+// e.g. (x) { case 1, 2 if y => body }. the body of each arm is left to parseBody.
+func parseMatch(parser *Parser, parseBody func() error) (ast.Expr, []ast.Arm, error) {
+	_, err := parser.consumeAndCheck(token.LEFTPAREN, "expected '(' after match")
+	if err != nil {
+		return nil, nil, err
+	}
+	value, err := parser.parseExpr(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = parser.consumeAndCheck(token.RIGHTPAREN, "expected ')' after the value of the match")
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = parser.consumeAndCheck(token.LEFTBRACE, "expected '{' before the arms of the match")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var arms []ast.Arm
+	for !parser.isAtEnd() && !parser.check(token.RIGHTBRACE) {
+		arm, err := parseArm(parser)
+		if err != nil {
+			return nil, nil, err
+		}
+		arms = append(arms, arm)
+		if err := parseBody(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	_, err = parser.consumeAndCheck(token.RIGHTBRACE, "expected '}' after the arms of the match")
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, arms, nil
+}
+
+// parseArm parses the case of an arm, up to and including the '=>'.
+func parseArm(parser *Parser) (ast.Arm, error) {
+	tok, err := parser.consumeAndCheck(token.CASE, "expected 'case' or '}' in match")
+	if err != nil {
+		return ast.Arm{}, err
+	}
+	arm := ast.Arm{Case: tok}
+	ok := true
+	for ok {
+		pattern, err := parsePattern(parser)
+		if err != nil {
+			return ast.Arm{}, err
+		}
+		arm.Patterns = append(arm.Patterns, pattern)
+
+		_, err = parser.consumeAndCheck(token.COMMA, "expected ',' after pattern")
+		ok = err == nil
+	}
+
+	if parser.check(token.IF) {
+		parser.consume() // consume IF
+		arm.Guard, err = parser.parseExpr(0)
+		if err != nil {
+			return ast.Arm{}, err
+		}
+	}
+
+	_, err = parser.consumeAndCheck(token.FATARROW, "expected '=>' after case")
+	if err != nil {
+		return ast.Arm{}, err
+	}
+	return arm, nil
+}
+
+func parsePattern(parser *Parser) (ast.Pattern, error) {
+	tok := parser.consume()
+	switch tok.Type {
+	case token.IDENTIFIER:
+		if tok.Lexeme == "_" {
+			return ast.WildcardPattern{Underscore: tok}, nil
+		}
+		return ast.BindingPattern{Name: tok}, nil
+	case token.LEFTBRACKET:
+		return parseListPattern(parser, tok)
+	case token.LEFTBRACE:
+		return parseMapPattern(parser, tok)
+	}
+
+	lit, err := parseLiteral(parser, tok)
+	if err != nil {
+		return nil, err
+	}
+	return ast.LiteralPattern{Value: lit}, nil
+}
+
+// parseLiteral parses the literal of a literal pattern or the key of a map pattern.
+// numbers may be negated, e.g. -1.
+func parseLiteral(parser *Parser, tok token.Token) (ast.Expr, error) {
+	switch tok.Type {
+	case token.NUMBER, token.STRING, token.NIL:
+		return LiteralParselet{}.parse(parser, tok)
+	case token.TRUE, token.FALSE:
+		return BoolParselet{}.parse(parser, tok)
+	case token.MINUS:
+		num, err := parser.consumeAndCheck(token.NUMBER, "expected a number after '-' in pattern")
+		if err != nil {
+			return nil, err
+		}
+		return ast.Unary{Operator: tok, Right: ast.Literal{Value: num.Literal}}, nil
+	default:
+		return nil, diagnostic.NewParseError(tok, "expected a pattern")
+	}
+}
+
+func parseListPattern(parser *Parser, tok token.Token) (ast.Pattern, error) {
+	p := ast.ListPattern{Bracket: tok}
+	// parse the comma-seperated element patterns until we hit a ']'
+	if !parser.check(token.RIGHTBRACKET) {
+		ok := true
+		for ok {
+			element, err := parsePattern(parser)
+			if err != nil {
+				return nil, err
+			}
+			p.Elements = append(p.Elements, element)
+
+			_, err = parser.consumeAndCheck(token.COMMA, "expected ',' after element")
+			ok = err == nil
+		}
+	}
+
+	_, err := parser.consumeAndCheck(token.RIGHTBRACKET, "expected ']' after list pattern")
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func parseMapPattern(parser *Parser, tok token.Token) (ast.Pattern, error) {
+	p := ast.MapPattern{Brace: tok}
+	// parse the comma-seperated entries until we hit a '}'
+	if !parser.check(token.RIGHTBRACE) {
+		ok := true
+		for ok {
+			key, err := parseLiteral(parser, parser.consume())
+			if err != nil {
+				return nil, err
+			}
+			_, err = parser.consumeAndCheck(token.COLON, "expected ':' after map key")
+			if err != nil {
+				return nil, err
+			}
+			value, err := parsePattern(parser)
+			if err != nil {
+				return nil, err
+			}
+			p.Keys = append(p.Keys, key)
+			p.Values = append(p.Values, value)
+
+			_, err = parser.consumeAndCheck(token.COMMA, "expected ',' after entry")
+			ok = err == nil
+		}
+	}
+
+	_, err := parser.consumeAndCheck(token.RIGHTBRACE, "expected '}' after map pattern")
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
@@ -1,15 +1,22 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
 
 	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
 type Parser struct {
 	tokens []token.Token
 	curr   int
+
+	// errors of the statements that were skipped while recovering
+	errors []error
+
+	// whether the function being parsed yields, which makes it a generator
+	yielded bool
 }
 
 /**
@@ -54,39 +61,71 @@ var statementParselets = map[token.Type]StatementParselet{
 	token.BREAK:    BreakStatementParselet{},
 	token.CONTINUE: ContinueStatementParslet{},
 	token.RETURN:   ReturnStatementParselet{},
+	token.YIELD:    YieldStatementParselet{},
+	token.CLASS:    ClassDeclarationStatementParselet{},
+	token.IMPORT:   ImportStatementParselet{},
+	token.THROW:    ThrowStatementParselet{},
+	token.TRY:      TryStatementParselet{},
+	token.MATCH:    MatchStatementParselet{},
 }
 
 var prefixPraseletsbyTokenType = map[token.Type]PrefixParselet{
-	token.PLUS:       UnaryOperatorParselet{},
-	token.MINUS:      UnaryOperatorParselet{},
-	token.BANG:       UnaryOperatorParselet{},
-	token.NUMBER:     LiteralParselet{},
-	token.STRING:     LiteralParselet{},
-	token.NIL:        LiteralParselet{},
-	token.IDENTIFIER: VariableParselet{},
-	token.TRUE:       BoolParselet{},
-	token.FALSE:      BoolParselet{},
-	token.LEFTPAREN:  GroupParselet{},
-	token.FUN:        LambdaParselet{},
+	token.PLUS:          UnaryOperatorParselet{},
+	token.MINUS:         UnaryOperatorParselet{},
+	token.BANG:          UnaryOperatorParselet{},
+	token.TILDE:         UnaryOperatorParselet{},
+	token.PLUSPLUS:      PrefixIncrementParselet{},
+	token.MINUSMINUS:    PrefixIncrementParselet{},
+	token.NUMBER:        LiteralParselet{},
+	token.STRING:        LiteralParselet{},
+	token.INTERPOLATION: InterpolationParselet{},
+	token.NIL:           LiteralParselet{},
+	token.IDENTIFIER:    VariableParselet{},
+	token.TRUE:          BoolParselet{},
+	token.FALSE:         BoolParselet{},
+	token.LEFTPAREN:     GroupParselet{},
+	token.FUN:           LambdaParselet{},
+	token.THIS:          ThisParselet{},
+	token.SUPER:         SuperParselet{},
+	token.LEFTBRACKET:   ListParselet{},
+	token.LEFTBRACE:     MapParselet{},
+	token.MATCH:         MatchParselet{},
 }
 
 var infixPraseletsbyTokenType = map[token.Type]InfixParselet{
-	token.EQUAL:        AssignmentParselet{},
-	token.PLUS:         TermParselet{},
-	token.MINUS:        TermParselet{},
-	token.STAR:         FactorParselet{},
-	token.SLASH:        FactorParselet{},
-	token.OR:           OrParselet{},
-	token.AND:          AndParselet{},
-	token.EQUALEQUAL:   EqualityParselet{},
-	token.BANGEQUAL:    EqualityParselet{},
-	token.LESS:         ComparsionParselet{},
-	token.LESSEQUAL:    ComparsionParselet{},
-	token.GREATER:      ComparsionParselet{},
-	token.GREATEREQUAL: ComparsionParselet{},
-	token.PLUSPLUS:     PostfixParselet{},
-	token.MINUSMINUS:   PostfixParselet{},
-	token.LEFTPAREN:    CallParselet{},
+	token.EQUAL:            AssignmentParselet{},
+	token.PLUSEQUAL:        CompoundAssignmentParselet{},
+	token.MINUSEQUAL:       CompoundAssignmentParselet{},
+	token.STAREQUAL:        CompoundAssignmentParselet{},
+	token.SLASHEQUAL:       CompoundAssignmentParselet{},
+	token.PERCENTEQUAL:     CompoundAssignmentParselet{},
+	token.PLUS:             TermParselet{},
+	token.MINUS:            TermParselet{},
+	token.STAR:             FactorParselet{},
+	token.SLASH:            FactorParselet{},
+	token.SLASHSLASH:       FactorParselet{},
+	token.PERCENT:          FactorParselet{},
+	token.STARSTAR:         PowerParselet{},
+	token.PIPE:             BitOrParselet{},
+	token.CARET:            BitXorParselet{},
+	token.AMPERSAND:        BitAndParselet{},
+	token.LESSLESS:         ShiftParselet{},
+	token.GREATERGREATER:   ShiftParselet{},
+	token.QUESTION:         ConditionalParselet{},
+	token.QUESTIONQUESTION: CoalesceParselet{},
+	token.OR:               OrParselet{},
+	token.AND:              AndParselet{},
+	token.EQUALEQUAL:       EqualityParselet{},
+	token.BANGEQUAL:        EqualityParselet{},
+	token.LESS:             ComparsionParselet{},
+	token.LESSEQUAL:        ComparsionParselet{},
+	token.GREATER:          ComparsionParselet{},
+	token.GREATEREQUAL:     ComparsionParselet{},
+	token.PLUSPLUS:         PostfixParselet{},
+	token.MINUSMINUS:       PostfixParselet{},
+	token.LEFTPAREN:        CallParselet{},
+	token.DOT:              GetParselet{},
+	token.LEFTBRACKET:      IndexParselet{},
 }
 
 func Parse(tokens []token.Token) ([]ast.Stmt, error) {
@@ -98,17 +137,50 @@ func Parse(tokens []token.Token) ([]ast.Stmt, error) {
 	return stmts, err
 }
 
+// Parse parses all statements, reporting every parse error at once.
+// statements with errors are left out of the returned statements,
+// and it's up to the caller whether to run what's left.
 func (p *Parser) Parse() ([]ast.Stmt, error) {
 	var stmts []ast.Stmt
 	for !p.isAtEnd() {
+		start := p.curr
 		stmt, err := p.parseSingleStatement()
 		if err != nil {
-			return stmts, err
+			p.recover(err, start)
+			continue
 		}
 		stmts = append(stmts, stmt)
 	}
 
-	return stmts, nil
+	return stmts, errors.Join(p.errors...)
+}
+
+// recover records the error of the statement starting at start, and skips to what's likely the next statement:
+// right after a ';', or at a keyword that starts a statement.
+// it stops at a '}' as well, so that the enclosing block can be closed.
+func (p *Parser) recover(err error, start int) {
+	p.errors = append(p.errors, err)
+
+	// make progress, even if the statement failed on its first token
+	if p.curr == start {
+		p.advance()
+	}
+	for !p.isAtEnd() {
+		if p.previous().Type == token.SEMICOLON {
+			return
+		}
+		switch p.peek().Type {
+		case token.RIGHTBRACE:
+			return
+		case token.LEFTBRACE:
+			// a '{' may as well start a map in the middle of an expression.
+		default:
+			if _, ok := statementParselets[p.peek().Type]; ok {
+				return
+			}
+		}
+		p.advance()
+	}
 }
 
 func (p *Parser) parseSingleStatement() (ast.Stmt, error) {
@@ -124,7 +196,7 @@ func (p *Parser) parseExpr(precendence Precedence) (ast.Expr, error) {
 	tok := p.consume()
 	prefix, ok := prefixPraseletsbyTokenType[tok.Type]
 	if !ok {
-		return nil, fmt.Errorf("line %d's %s: no prefix parselet for token type %s", tok.Ln, tok.Lexeme, tok.Type)
+		return nil, diagnostic.NewParseError(tok, "expected expression")
 	}
 
 	left, err := prefix.parse(p, tok)
@@ -137,7 +209,7 @@ func (p *Parser) parseExpr(precendence Precedence) (ast.Expr, error) {
 
 		infix, ok := infixPraseletsbyTokenType[tok.Type]
 		if !ok {
-			return nil, fmt.Errorf("line %d's %s: no infix parselet for token type %s", tok.Ln, tok.Lexeme, tok.Type)
+			return nil, diagnostic.NewParseError(tok, "no infix parselet for token type %s", tok.Type)
 		}
 
 		left, err = infix.parse(p, left, tok)
@@ -163,6 +235,14 @@ func (p *Parser) peek() token.Token {
 	return p.tokens[p.curr]
 }
 
+// lookahead of the distance, stopping at the end.
+func (p *Parser) peekAt(distance int) token.Token {
+	if p.curr+distance >= len(p.tokens) {
+		return p.tokens[len(p.tokens)-1]
+	}
+	return p.tokens[p.curr+distance]
+}
+
 func (p *Parser) consume() token.Token {
 	tok := p.tokens[p.curr]
 	p.curr++
@@ -174,7 +254,7 @@ func (p *Parser) consumeAndCheck(t token.Type, msg string) (token.Token, error)
 		return p.advance(), nil
 	}
 
-	return token.Token{}, fmt.Errorf("line %d's %s: %s", p.peek().Ln, p.peek().Lexeme, msg)
+	return token.Token{}, diagnostic.NewParseError(p.peek(), "%s", msg)
 }
 
 func (p *Parser) check(t token.Type) bool {
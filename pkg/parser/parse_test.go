@@ -1,10 +1,12 @@
 package parser
 
 import (
+	"errors"
 	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/scanner"
 )
 
@@ -15,7 +17,7 @@ func TestParser(t *testing.T) {
 		desc string
 	}{
 		{
-			in:   "print 1;",
+			in:   "print(1);",
 			out:  "(print 1)",
 			desc: "unary minus",
 		},
@@ -36,3 +38,41 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+func TestParserRecovery(t *testing.T) {
+	in := `var a = 1;
+var = 2;
+fun f() {
+  var b = ;
+  print(b);
+  print(1)
+}
+print(a);
+a + ;
+class C {}
+`
+	tokens, err := scanner.ScanTokens(in)
+	assert.NoError(t, err)
+
+	stmts, err := Parse(tokens)
+	if !assert.Error(t, err) {
+		return
+	}
+
+	// every error is reported, in order.
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !assert.True(t, ok) {
+		return
+	}
+	var lines []int
+	for _, e := range joined.Unwrap() {
+		var perr *diagnostic.ParseError
+		if assert.True(t, errors.As(e, &perr)) {
+			lines = append(lines, perr.Line)
+		}
+	}
+	assert.Equal(t, []int{2, 4, 7, 9}, lines)
+
+	// the statements without errors are kept.
+	assert.Len(t, stmts, 4)
+}
@@ -2,15 +2,29 @@ package parser
 
 import (
 	expressions "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
 type PostfixParselet struct{}
 
 func (p PostfixParselet) parse(parser *Parser, left expressions.Expr, token token.Token) (expressions.Expr, error) {
+	if !assignable(left) {
+		return nil, diagnostic.NewParseError(token, "operand of '%s' must be a variable, a property or an index", token.Lexeme)
+	}
 	return expressions.PostUnary{Left: left, Operator: token}, nil
 }
 
+// assignable reports whether the expression can be assigned to.
+func assignable(expr expressions.Expr) bool {
+	switch expr.(type) {
+	case expressions.Variable, expressions.Get, expressions.Index:
+		return true
+	default:
+		return false
+	}
+}
+
 func (p PostfixParselet) precedence() Precedence {
 	return PrecedencePostfix
 }
@@ -9,14 +9,21 @@ type Precedence int
  * precedence than "+" and "-". Here, bigger numbers mean higher precedence.
  */
 const (
-	PrecedenceAssignment Precedence = iota + 1 // =
-	PrecedenceOr                               // or
-	PrecedenceAnd                              // and
-	PrecedenceEquality                         // == !=
-	PrecedenceComparison                       // > >= < <=
-	PrecedenceTerm                             // - +
-	PrecedenceFactor                           // / *
-	PrecedenceUnary                            // ! -
-	PrecedencePostfix                          // ++ --
-	PrecedenceCall                             // . () []
+	PrecedenceAssignment  Precedence = iota + 1 // =
+	PrecedenceConditional                       // ?:
+	PrecedenceCoalesce                          // ??
+	PrecedenceOr                                // or
+	PrecedenceAnd                               // and
+	PrecedenceEquality                          // == !=
+	PrecedenceComparison                        // > >= < <=
+	PrecedenceBitOr                             // |
+	PrecedenceBitXor                            // ^
+	PrecedenceBitAnd                            // &
+	PrecedenceShift                             // << >>
+	PrecedenceTerm                              // - +
+	PrecedenceFactor                            // / * // %
+	PrecedenceUnary                             // ! - ~
+	PrecedencePower                             // **
+	PrecedencePostfix                           // ++ --
+	PrecedenceCall                              // . () []
 )
@@ -1,11 +1,9 @@
 package parser
 
 import (
-	"errors"
-	"fmt"
-
 	"github.com/taehioum/glox/pkg/ast"
 	expressions "github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
@@ -20,6 +18,23 @@ func (uop UnaryOperatorParselet) parse(parser *Parser, token token.Token) (expre
 	}, err
 }
 
+// PrefixIncrementParselet parses ++x and --x.
+type PrefixIncrementParselet struct{}
+
+func (p PrefixIncrementParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	expr, err := parser.parseExpr(PrecedenceUnary)
+	if err != nil {
+		return nil, err
+	}
+	if !assignable(expr) {
+		return nil, diagnostic.NewParseError(tok, "operand of '%s' must be a variable, a property or an index", tok.Lexeme)
+	}
+	return expressions.PreUnary{
+		Operator: tok,
+		Right:    expr,
+	}, nil
+}
+
 type LiteralParselet struct {
 }
 
@@ -39,7 +54,7 @@ func (bp BoolParselet) parse(parser *Parser, tok token.Token) (expressions.Expr,
 	case token.FALSE:
 		return expressions.Literal{Value: false}, nil
 	default:
-		return nil, fmt.Errorf("unexpected token type %s", tok.Type)
+		return nil, diagnostic.NewParseError(tok, "unexpected token type %s", tok.Type)
 	}
 }
 
@@ -69,9 +84,108 @@ func (p VariableParselet) parse(parser *Parser, tok token.Token) (expressions.Ex
 	}, nil
 }
 
+type ThisParselet struct{}
+
+func (p ThisParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	return expressions.This{
+		Keyword: tok,
+	}, nil
+}
+
+type SuperParselet struct{}
+
+func (p SuperParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	_, err := parser.consumeAndCheck(token.DOT, "expected '.' after 'super'")
+	if err != nil {
+		return nil, err
+	}
+	method, err := parser.consumeAndCheck(token.IDENTIFIER, "expected superclass method name")
+	if err != nil {
+		return nil, err
+	}
+	return expressions.Super{
+		Keyword: tok,
+		Method:  method,
+	}, nil
+}
+
+// ListParselet parses list literals like [a, b, c]
+type ListParselet struct{}
+
+func (p ListParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	var elements []expressions.Expr
+	// parse the comma-seperated elements until we hit a ']'
+	if !parser.check(token.RIGHTBRACKET) {
+		ok := true
+		for ok {
+			expr, err := parser.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, expr)
+
+			_, err = parser.consumeAndCheck(token.COMMA, "expected ',' after element")
+			ok = err == nil
+		}
+	}
+
+	_, err := parser.consumeAndCheck(token.RIGHTBRACKET, "expected ']' after list elements")
+	if err != nil {
+		return nil, err
+	}
+
+	return expressions.List{
+		Bracket:  tok,
+		Elements: elements,
+	}, nil
+}
+
+// MapParselet parses map literals like {a: b, c: d}
+// a '{' only starts a map in expression position; at the start of a statement, it starts a block.
+type MapParselet struct{}
+
+func (p MapParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	m := expressions.Map{Brace: tok}
+	// parse the comma-seperated entries until we hit a '}'
+	if !parser.check(token.RIGHTBRACE) {
+		ok := true
+		for ok {
+			key, err := parser.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			_, err = parser.consumeAndCheck(token.COLON, "expected ':' after map key")
+			if err != nil {
+				return nil, err
+			}
+			value, err := parser.parseExpr(0)
+			if err != nil {
+				return nil, err
+			}
+			m.Keys = append(m.Keys, key)
+			m.Values = append(m.Values, value)
+
+			_, err = parser.consumeAndCheck(token.COMMA, "expected ',' after entry")
+			ok = err == nil
+		}
+	}
+
+	_, err := parser.consumeAndCheck(token.RIGHTBRACE, "expected '}' after map entries")
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 type LambdaParselet struct{}
 
 func (p LambdaParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	enclosing := parser.yielded
+	parser.yielded = false
+	defer func() {
+		parser.yielded = enclosing
+	}()
+
 	_, err := parser.consumeAndCheck(token.LEFTPAREN, "Expect '(' after fun.")
 	if err != nil {
 		return nil, err
@@ -87,7 +201,7 @@ func (p LambdaParselet) parse(parser *Parser, tok token.Token) (expressions.Expr
 				return nil, err
 			}
 			if len(params) >= 255 {
-				return nil, errors.New("can't have more than 255 parameters")
+				return nil, diagnostic.NewParseError(id, "can't have more than 255 parameters")
 			}
 			params = append(params, id)
 
@@ -103,7 +217,7 @@ func (p LambdaParselet) parse(parser *Parser, tok token.Token) (expressions.Expr
 
 	ok := parser.check(token.LEFTBRACE)
 	if !ok {
-		return nil, errors.New("expected '{' after function declaration")
+		return nil, diagnostic.NewParseError(parser.peek(), "expected '{' before function body")
 	}
 
 	b, err := BlockStatementParselet{}.parse(parser)
@@ -113,12 +227,62 @@ func (p LambdaParselet) parse(parser *Parser, tok token.Token) (expressions.Expr
 
 	body, ok := b.(ast.Block)
 	if !ok {
-		return nil, errors.New("expected block statement")
+		return nil, diagnostic.NewParseError(tok, "expected block statement")
 	}
 
 	return expressions.Lambda{
-		Name:   tok,
-		Params: params,
-		Body:   body.Stmts,
+		Name:      tok,
+		Params:    params,
+		Body:      body.Stmts,
+		Generator: parser.yielded,
 	}, nil
 }
+
+type InterpolationParselet struct{}
+
+func (p InterpolationParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	interpolation := expressions.Interpolation{Quote: tok}
+	for part := tok; ; {
+		if s := part.Literal.(string); s != "" {
+			interpolation.Parts = append(interpolation.Parts, expressions.Literal{Value: s})
+		}
+		if part.Type == token.STRING {
+			return interpolation, nil
+		}
+
+		expr, err := parser.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+		interpolation.Parts = append(interpolation.Parts, expr)
+
+		// the scanner resumes the string after the '}' closing the expression.
+		if !parser.check(token.STRING) && !parser.check(token.INTERPOLATION) {
+			return nil, diagnostic.NewParseError(parser.peek(), "expected '}' after interpolated expression")
+		}
+		part = parser.consume()
+	}
+}
+
+// MatchParselet parses a match whose arms are expressions, separated by optional commas or semicolons.
+// e.g. match (x) { case 1 => "one", case _ => "many" }, or match (x) { case 1 => "one"; case _ => "many"; }
+type MatchParselet struct{}
+
+func (p MatchParselet) parse(parser *Parser, tok token.Token) (expressions.Expr, error) {
+	var bodies []expressions.Expr
+	value, arms, err := parseMatch(parser, func() error {
+		body, err := parser.parseExpr(0)
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, body)
+		if parser.check(token.COMMA) || parser.check(token.SEMICOLON) {
+			parser.consume()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expressions.Match{Keyword: tok, Value: value, Arms: arms, Bodies: bodies}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"log/slog"
 
 	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
@@ -59,13 +60,16 @@ func (p BlockStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 	parser.consume() // consume LEFTBRACE
 	var stmts []ast.Stmt
 	for !parser.isAtEnd() && !parser.check(token.RIGHTBRACE) {
+		start := parser.curr
 		stmt, err := parser.parseSingleStatement()
 		if err != nil {
-			return ast.Block{Stmts: stmts}, err
+			// keep parsing the rest of the block, to report its errors as well.
+			parser.recover(err, start)
+			continue
 		}
 		stmts = append(stmts, stmt)
 	}
-	_, err := parser.consumeAndCheck(token.RIGHTBRACE, "expected ';' after value")
+	_, err := parser.consumeAndCheck(token.RIGHTBRACE, "expected '}' after block")
 	if err != nil {
 		return ast.Block{Stmts: stmts}, err
 	}
@@ -76,12 +80,18 @@ type IfStatementParselet struct{}
 
 func (p IfStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 	parser.consume() // consume IF
-	parser.consumeAndCheck(token.LEFTPAREN, "expected '(' after if")
+	_, err := parser.consumeAndCheck(token.LEFTPAREN, "expected '(' after if")
+	if err != nil {
+		return nil, err
+	}
 	cond, err := parser.parseExpr(0)
 	if err != nil {
 		return ast.If{}, fmt.Errorf("if condition: %w", err)
 	}
-	parser.consumeAndCheck(token.RIGHTPAREN, "Expect ')' after if condition.")
+	_, err = parser.consumeAndCheck(token.RIGHTPAREN, "Expect ')' after if condition.")
+	if err != nil {
+		return nil, err
+	}
 	then, err := parser.parseSingleStatement()
 	if err != nil {
 		return ast.If{}, fmt.Errorf("if then: %w", err)
@@ -133,11 +143,14 @@ func (p WhileStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 type ForStatementParselet struct{}
 
 func (p ForStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
-	parser.consume() // consume FOR
+	keyword := parser.consume() // consume FOR
 	_, err := parser.consumeAndCheck(token.LEFTPAREN, "expected '(' after 'for'")
 	if err != nil {
 		return nil, err
 	}
+	if parser.check(token.VAR) && parser.peekAt(2).Type == token.IN {
+		return p.forIn(parser, keyword)
+	}
 
 	var init ast.Stmt
 	if parser.check(token.SEMICOLON) {
@@ -217,6 +230,29 @@ func (p ForStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 	return res, nil
 }
 
+// forIn parses the rest of for (var x in iterable) body, after the '('.
+func (p ForStatementParselet) forIn(parser *Parser, keyword token.Token) (ast.Stmt, error) {
+	parser.consume() // consume VAR
+	name, err := parser.consumeAndCheck(token.IDENTIFIER, "Expect variable name.")
+	if err != nil {
+		return nil, err
+	}
+	in := parser.consume() // consume IN
+	iterable, err := parser.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	_, err = parser.consumeAndCheck(token.RIGHTPAREN, "expected ')' after the iterable of the for loop")
+	if err != nil {
+		return nil, err
+	}
+	body, err := parser.parseSingleStatement()
+	if err != nil {
+		return nil, err
+	}
+	return ast.ForIn{Keyword: keyword, Name: name, In: in, Iterable: iterable, Body: body}, nil
+}
+
 type ExpressionStatementParselet struct{}
 
 func (p ExpressionStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
@@ -237,26 +273,26 @@ func (p ExpressionStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 type BreakStatementParselet struct{}
 
 func (p BreakStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
-	parser.consume() // consume BREAK
+	keyword := parser.consume() // consume BREAK
 	_, err := parser.consumeAndCheck(token.SEMICOLON, "expected ';' after break")
 	if err != nil {
 		return nil, err
 	}
-	// check that we are in a loop block
-	return ast.Break{}, nil
+	// the resolver checks that we are in a loop block
+	return ast.Break{Keyword: keyword}, nil
 }
 
 // TODO
 type ContinueStatementParslet struct{}
 
 func (p ContinueStatementParslet) parse(parser *Parser) (ast.Stmt, error) {
-	parser.consume() // consume CONTINUE
+	keyword := parser.consume() // consume CONTINUE
 	_, err := parser.consumeAndCheck(token.SEMICOLON, "expected ';' after continue")
 	if err != nil {
 		return nil, err
 	}
-	// check that we are in a loop block
-	return ast.Continue{}, nil
+	// the resolver checks that we are in a loop block
+	return ast.Continue{Keyword: keyword}, nil
 }
 
 type FunctionDeclarationStatementParselet struct{}
@@ -279,6 +315,55 @@ func (p FunctionDeclarationStatementParselet) parse(parser *Parser) (ast.Stmt, e
 	}, nil
 }
 
+type ClassDeclarationStatementParselet struct{}
+
+func (p ClassDeclarationStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	parser.consume() // consume CLASS
+	name, err := parser.consumeAndCheck(token.IDENTIFIER, "Expect class name.")
+	if err != nil {
+		return nil, err
+	}
+
+	var superclass *ast.Variable
+	if parser.check(token.LESS) {
+		parser.consume() // consume LESS
+		superName, err := parser.consumeAndCheck(token.IDENTIFIER, "Expect superclass name.")
+		if err != nil {
+			return nil, err
+		}
+		superclass = &ast.Variable{Name: superName}
+	}
+
+	_, err = parser.consumeAndCheck(token.LEFTBRACE, "expected '{' before class body")
+	if err != nil {
+		return nil, err
+	}
+
+	var methods []ast.Lambda
+	for !parser.isAtEnd() && !parser.check(token.RIGHTBRACE) {
+		methodName, err := parser.consumeAndCheck(token.IDENTIFIER, "Expect method name.")
+		if err != nil {
+			return nil, err
+		}
+		method, err := LambdaParselet{}.parse(parser, methodName)
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, method.(ast.Lambda))
+	}
+
+	_, err = parser.consumeAndCheck(token.RIGHTBRACE, "expected '}' after class body")
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Class{
+		Name:       name,
+		Superclass: superclass,
+		Methods:    methods,
+	}, nil
+}
+
 type ReturnStatementParselet struct{}
 
 func (p ReturnStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
@@ -297,3 +382,139 @@ func (p ReturnStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
 	}
 	return ast.Return{Keyword: t, Value: expr}, nil
 }
+
+type YieldStatementParselet struct{}
+
+func (p YieldStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	keyword := parser.consume() // consume YIELD
+	var expr ast.Expr
+	if !parser.check(token.SEMICOLON) {
+		var err error
+		expr, err = parser.parseExpr(0)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err := parser.consumeAndCheck(token.SEMICOLON, "expected ';' after yield")
+	if err != nil {
+		return nil, err
+	}
+	// the function the yield is in becomes a generator. the resolver checks that there is one.
+	parser.yielded = true
+	return ast.Yield{Keyword: keyword, Value: expr}, nil
+}
+
+type ImportStatementParselet struct{}
+
+func (p ImportStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	keyword := parser.consume() // consume IMPORT
+	path, err := parser.consumeAndCheck(token.STRING, "expected a path after import")
+	if err != nil {
+		return nil, err
+	}
+	_, err = parser.consumeAndCheck(token.AS, "expected 'as' after the path of the import")
+	if err != nil {
+		return nil, err
+	}
+	name, err := parser.consumeAndCheck(token.IDENTIFIER, "expected a module name after 'as'")
+	if err != nil {
+		return nil, err
+	}
+	_, err = parser.consumeAndCheck(token.SEMICOLON, "expected ';' after import")
+	if err != nil {
+		return nil, err
+	}
+	return ast.Import{Keyword: keyword, Path: path, Name: name}, nil
+}
+
+type ThrowStatementParselet struct{}
+
+func (p ThrowStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	keyword := parser.consume() // consume THROW
+	value, err := parser.parseExpr(0)
+	if err != nil {
+		return nil, err
+	}
+	_, err = parser.consumeAndCheck(token.SEMICOLON, "expected ';' after thrown value")
+	if err != nil {
+		return nil, err
+	}
+	return ast.Throw{Keyword: keyword, Value: value}, nil
+}
+
+type TryStatementParselet struct{}
+
+func (p TryStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	keyword := parser.consume() // consume TRY
+	body, err := p.block(parser, "expected '{' after try")
+	if err != nil {
+		return nil, err
+	}
+	stmt := ast.Try{Keyword: keyword, Body: *body}
+
+	if parser.check(token.CATCH) {
+		parser.consume() // consume CATCH
+		_, err := parser.consumeAndCheck(token.LEFTPAREN, "expected '(' after catch")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Name, err = parser.consumeAndCheck(token.IDENTIFIER, "expected a name for the thrown value")
+		if err != nil {
+			return nil, err
+		}
+		_, err = parser.consumeAndCheck(token.RIGHTPAREN, "expected ')' after the name of the thrown value")
+		if err != nil {
+			return nil, err
+		}
+		stmt.Catch, err = p.block(parser, "expected '{' after catch")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if parser.check(token.FINALLY) {
+		parser.consume() // consume FINALLY
+		stmt.Finally, err = p.block(parser, "expected '{' after finally")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if stmt.Catch == nil && stmt.Finally == nil {
+		return nil, diagnostic.NewParseError(parser.peek(), "expected catch or finally after try")
+	}
+	return stmt, nil
+}
+
+func (p TryStatementParselet) block(parser *Parser, msg string) (*ast.Block, error) {
+	if !parser.check(token.LEFTBRACE) {
+		return nil, diagnostic.NewParseError(parser.peek(), "%s", msg)
+	}
+	stmt, err := BlockStatementParselet{}.parse(parser)
+	if err != nil {
+		return nil, err
+	}
+	block := stmt.(ast.Block)
+	return &block, nil
+}
+
+// MatchStatementParselet parses a match whose arms are statements.
+// a match in expression position is parsed by MatchParselet instead.
+type MatchStatementParselet struct{}
+
+func (p MatchStatementParselet) parse(parser *Parser) (ast.Stmt, error) {
+	keyword := parser.consume() // consume MATCH
+	var bodies []ast.Stmt
+	value, arms, err := parseMatch(parser, func() error {
+		body, err := parser.parseSingleStatement()
+		if err != nil {
+			return err
+		}
+		bodies = append(bodies, body)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ast.MatchStmt{Keyword: keyword, Value: value, Arms: arms, Bodies: bodies}, nil
+}
@@ -1,17 +1,44 @@
 package resolver
 
 import (
-	"fmt"
-
 	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+type functionType int
+
+const (
+	functionTypeNone functionType = iota
+	functionTypeFunction
+	functionTypeMethod
+	functionTypeInitializer
+)
+
+type classType int
+
+const (
+	classTypeNone classType = iota
+	classTypeClass
+	classTypeSubclass
 )
 
 type Resolver struct {
 	interpreter *interpreter.Interpreter
 	envs        []map[string]bool
+
+	// what kind of function / class body we are resolving at the moment
+	currentFunction functionType
+	currentClass    classType
+	// number of loops enclosing the statement, within the current function
+	loopDepth int
+	// whether the current function is a generator
+	generator bool
 }
 
+// New returns a resolver that records the scope distance of locals in the interpreter.
+// a nil interpreter only checks the program, e.g. for backends that resolve variables themselves.
 func New(interpreter *interpreter.Interpreter) *Resolver {
 	return &Resolver{
 		interpreter: interpreter,
@@ -71,7 +98,7 @@ func (r *Resolver) VisitAssignment(a ast.Assignment) (any, error) {
 	if _, err := r.ResolveExpr(a.Value); err != nil {
 		return nil, err
 	}
-	if err := r.resolveLocal(a, a.Name.Lexeme); err != nil {
+	if err := r.resolveLocal(a.Name); err != nil {
 		return nil, err
 	}
 	return nil, nil
@@ -108,13 +135,23 @@ func (r *Resolver) VisitGrouping(g ast.Grouping) (any, error) {
 
 // VisitLambda implements ast.ExpressionVisitor.
 func (r *Resolver) VisitLambda(l ast.Lambda) (any, error) {
+	return nil, r.resolveFunction(l, functionTypeFunction)
+}
+
+func (r *Resolver) resolveFunction(l ast.Lambda, typ functionType) error {
+	enclosing, enclosingLoopDepth, enclosingGenerator := r.currentFunction, r.loopDepth, r.generator
+	r.currentFunction, r.loopDepth, r.generator = typ, 0, l.Generator
+	defer func() {
+		r.currentFunction, r.loopDepth, r.generator = enclosing, enclosingLoopDepth, enclosingGenerator
+	}()
+
 	r.BeginScope()
 	defer r.ExitScope()
 	for _, param := range l.Params {
 		r.Declare(param.Lexeme)
 		r.Define(param.Lexeme)
 	}
-	return nil, r.Resolve(l.Body)
+	return r.Resolve(l.Body)
 }
 
 // VisitLiteral implements ast.ExpressionVisitor.
@@ -133,6 +170,20 @@ func (r *Resolver) VisitLogical(l ast.Logical) (any, error) {
 	return nil, nil
 }
 
+// VisitConditional implements ast.ExpressionVisitor.
+func (r *Resolver) VisitConditional(c ast.Conditional) (any, error) {
+	if _, err := r.ResolveExpr(c.Condition); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(c.Then); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(c.Else); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 // VisitPostUnary implements ast.ExpressionVisitor.
 func (r *Resolver) VisitPostUnary(p ast.PostUnary) (any, error) {
 	if _, err := r.ResolveExpr(p.Left); err != nil {
@@ -141,6 +192,26 @@ func (r *Resolver) VisitPostUnary(p ast.PostUnary) (any, error) {
 	return nil, nil
 }
 
+// VisitPreUnary implements ast.ExpressionVisitor.
+func (r *Resolver) VisitPreUnary(p ast.PreUnary) (any, error) {
+	if _, err := r.ResolveExpr(p.Right); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// VisitCompound implements ast.ExpressionVisitor.
+func (r *Resolver) VisitCompound(c ast.Compound) (any, error) {
+	if _, err := r.ResolveExpr(c.Value); err != nil {
+		return nil, err
+	}
+	// the target is resolved like a read of it, which the assignment goes through as well.
+	if _, err := r.ResolveExpr(c.Target); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
 // VisitUnary implements ast.ExpressionVisitor.
 func (r *Resolver) VisitUnary(u ast.Unary) (any, error) {
 	if _, err := r.ResolveExpr(u.Right); err != nil {
@@ -154,17 +225,19 @@ func (r *Resolver) VisitVariable(v ast.Variable) (any, error) {
 	if len(r.envs) > 0 {
 		b, ok := r.envs[len(r.envs)-1][v.Name.Lexeme]
 		if ok && !b {
-			return nil, fmt.Errorf("cannot read local variable in its own initializer")
+			return nil, diagnostic.NewResolveError(v.Name, "cannot read local variable in its own initializer")
 		}
 	}
-	r.resolveLocal(v, v.Name.Lexeme)
+	r.resolveLocal(v.Name)
 	return nil, nil
 }
 
-func (r *Resolver) resolveLocal(expr ast.Expr, name string) error {
+func (r *Resolver) resolveLocal(name token.Token) error {
 	for i := len(r.envs) - 1; i >= 0; i-- {
-		if _, ok := r.envs[i][name]; ok {
-			r.interpreter.Resolve(expr, len(r.envs)-1-i)
+		if _, ok := r.envs[i][name.Lexeme]; ok {
+			if r.interpreter != nil {
+				r.interpreter.Resolve(name, len(r.envs)-1-i)
+			}
 			return nil
 		}
 	}
@@ -172,12 +245,18 @@ func (r *Resolver) resolveLocal(expr ast.Expr, name string) error {
 }
 
 // VisitBreak implements ast.StatementVistior.
-func (*Resolver) VisitBreak(ast.Break) error {
+func (r *Resolver) VisitBreak(b ast.Break) error {
+	if r.loopDepth == 0 {
+		return diagnostic.NewResolveError(b.Keyword, "can't use 'break' outside of a loop")
+	}
 	return nil
 }
 
 // VisitContinue implements ast.StatementVistior.
-func (*Resolver) VisitContinue(ast.Continue) error {
+func (r *Resolver) VisitContinue(c ast.Continue) error {
+	if r.loopDepth == 0 {
+		return diagnostic.NewResolveError(c.Keyword, "can't use 'continue' outside of a loop")
+	}
 	return nil
 }
 
@@ -226,9 +305,18 @@ func (r *Resolver) VisitIf(i ast.If) error {
 
 // VisitReturn implements ast.StatementVistior.
 func (r *Resolver) VisitReturn(ret ast.Return) error {
+	if r.currentFunction == functionTypeNone {
+		return diagnostic.NewResolveError(ret.Keyword, "can't return from top-level code")
+	}
 	if ret.Value == nil {
 		return nil
 	}
+	if r.currentFunction == functionTypeInitializer {
+		return diagnostic.NewResolveError(ret.Keyword, "can't return a value from an initializer")
+	}
+	if r.generator {
+		return diagnostic.NewResolveError(ret.Keyword, "can't return a value from a generator")
+	}
 
 	if _, err := r.ResolveExpr(ret.Value); err != nil {
 		return err
@@ -236,16 +324,299 @@ func (r *Resolver) VisitReturn(ret ast.Return) error {
 	return nil
 }
 
+// VisitYield implements ast.StatementVistior.
+func (r *Resolver) VisitYield(y ast.Yield) error {
+	switch r.currentFunction {
+	case functionTypeNone:
+		return diagnostic.NewResolveError(y.Keyword, "can't yield outside of a function")
+	case functionTypeInitializer:
+		return diagnostic.NewResolveError(y.Keyword, "can't yield from an initializer")
+	}
+	if y.Value == nil {
+		return nil
+	}
+	_, err := r.ResolveExpr(y.Value)
+	return err
+}
+
 // VisitWhile implements ast.StatementVistior.
 func (r *Resolver) VisitWhile(w ast.While) error {
 	if _, err := r.ResolveExpr(w.Cond); err != nil {
 		return err
 	}
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
 	if err := r.ResolveStmt(w.Body); err != nil {
 		return err
 	}
 	return nil
 }
 
+// VisitForIn implements ast.StatementVistior.
+func (r *Resolver) VisitForIn(f ast.ForIn) error {
+	if _, err := r.ResolveExpr(f.Iterable); err != nil {
+		return err
+	}
+	// the name is bound anew for each value, in a scope around the body.
+	r.BeginScope()
+	defer r.ExitScope()
+	r.Declare(f.Name.Lexeme)
+	r.Define(f.Name.Lexeme)
+
+	r.loopDepth++
+	defer func() { r.loopDepth-- }()
+	return r.ResolveStmt(f.Body)
+}
+
+// VisitImport implements ast.StatementVistior.
+func (r *Resolver) VisitImport(i ast.Import) error {
+	r.Declare(i.Name.Lexeme)
+	r.Define(i.Name.Lexeme)
+	return nil
+}
+
+// VisitThrow implements ast.StatementVistior.
+func (r *Resolver) VisitThrow(t ast.Throw) error {
+	_, err := r.ResolveExpr(t.Value)
+	return err
+}
+
+// VisitTry implements ast.StatementVistior.
+func (r *Resolver) VisitTry(t ast.Try) error {
+	if err := r.ResolveStmt(t.Body); err != nil {
+		return err
+	}
+	if t.Catch != nil {
+		// the thrown value is bound in a scope around the catch block.
+		r.BeginScope()
+		r.Declare(t.Name.Lexeme)
+		r.Define(t.Name.Lexeme)
+		err := r.ResolveStmt(*t.Catch)
+		r.ExitScope()
+		if err != nil {
+			return err
+		}
+	}
+	if t.Finally != nil {
+		return r.ResolveStmt(*t.Finally)
+	}
+	return nil
+}
+
+// VisitClass implements ast.StatementVistior.
+func (r *Resolver) VisitClass(c ast.Class) error {
+	enclosing := r.currentClass
+	r.currentClass = classTypeClass
+	defer func() {
+		r.currentClass = enclosing
+	}()
+
+	r.Declare(c.Name.Lexeme)
+	r.Define(c.Name.Lexeme)
+
+	if c.Superclass != nil {
+		if c.Superclass.Name.Lexeme == c.Name.Lexeme {
+			return diagnostic.NewResolveError(c.Superclass.Name, "a class can't inherit from itself")
+		}
+		r.currentClass = classTypeSubclass
+		if _, err := r.ResolveExpr(*c.Superclass); err != nil {
+			return err
+		}
+
+		// methods of a subclass are closed over a scope that binds 'super'
+		r.BeginScope()
+		defer r.ExitScope()
+		r.envs[len(r.envs)-1]["super"] = true
+	}
+
+	// methods are closed over a scope that binds 'this'
+	r.BeginScope()
+	defer r.ExitScope()
+	r.envs[len(r.envs)-1]["this"] = true
+
+	for _, method := range c.Methods {
+		typ := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			typ = functionTypeInitializer
+		}
+		if err := r.resolveFunction(method, typ); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VisitGet implements ast.ExpressionVisitor.
+func (r *Resolver) VisitGet(g ast.Get) (any, error) {
+	// properties are looked up dynamically, so only the object is resolved.
+	return r.ResolveExpr(g.Object)
+}
+
+// VisitSet implements ast.ExpressionVisitor.
+func (r *Resolver) VisitSet(s ast.Set) (any, error) {
+	if _, err := r.ResolveExpr(s.Value); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(s.Object); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// VisitThis implements ast.ExpressionVisitor.
+func (r *Resolver) VisitThis(t ast.This) (any, error) {
+	if r.currentClass == classTypeNone {
+		return nil, diagnostic.NewResolveError(t.Keyword, "can't use 'this' outside of a class")
+	}
+	return nil, r.resolveLocal(t.Keyword)
+}
+
+// VisitSuper implements ast.ExpressionVisitor.
+func (r *Resolver) VisitSuper(s ast.Super) (any, error) {
+	switch r.currentClass {
+	case classTypeNone:
+		return nil, diagnostic.NewResolveError(s.Keyword, "can't use 'super' outside of a class")
+	case classTypeClass:
+		return nil, diagnostic.NewResolveError(s.Keyword, "can't use 'super' in a class with no superclass")
+	}
+	return nil, r.resolveLocal(s.Keyword)
+}
+
+// VisitList implements ast.ExpressionVisitor.
+func (r *Resolver) VisitList(l ast.List) (any, error) {
+	for _, e := range l.Elements {
+		if _, err := r.ResolveExpr(e); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// VisitInterpolation implements ast.ExpressionVisitor.
+func (r *Resolver) VisitInterpolation(i ast.Interpolation) (any, error) {
+	for _, e := range i.Parts {
+		if _, err := r.ResolveExpr(e); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// VisitIndex implements ast.ExpressionVisitor.
+func (r *Resolver) VisitIndex(i ast.Index) (any, error) {
+	if _, err := r.ResolveExpr(i.Object); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(i.Index); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// VisitIndexSet implements ast.ExpressionVisitor.
+func (r *Resolver) VisitIndexSet(i ast.IndexSet) (any, error) {
+	if _, err := r.ResolveExpr(i.Object); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(i.Index); err != nil {
+		return nil, err
+	}
+	if _, err := r.ResolveExpr(i.Value); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// VisitMap implements ast.ExpressionVisitor.
+func (r *Resolver) VisitMap(m ast.Map) (any, error) {
+	for idx := range m.Keys {
+		if _, err := r.ResolveExpr(m.Keys[idx]); err != nil {
+			return nil, err
+		}
+		if _, err := r.ResolveExpr(m.Values[idx]); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// VisitMatch implements ast.ExpressionVisitor.
+func (r *Resolver) VisitMatch(m ast.Match) (any, error) {
+	if _, err := r.ResolveExpr(m.Value); err != nil {
+		return nil, err
+	}
+	for idx, arm := range m.Arms {
+		err := r.resolveArm(arm, func() error {
+			_, err := r.ResolveExpr(m.Bodies[idx])
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+// VisitMatchStmt implements ast.StatementVistior.
+func (r *Resolver) VisitMatchStmt(m ast.MatchStmt) error {
+	if _, err := r.ResolveExpr(m.Value); err != nil {
+		return err
+	}
+	for idx, arm := range m.Arms {
+		err := r.resolveArm(arm, func() error {
+			return r.ResolveStmt(m.Bodies[idx])
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveArm binds the names of the patterns of the arm in a scope around its guard and body.
+func (r *Resolver) resolveArm(arm ast.Arm, resolveBody func() error) error {
+	r.BeginScope()
+	defer r.ExitScope()
+
+	var names []token.Token
+	for _, p := range arm.Patterns {
+		names = bindings(p, names)
+	}
+	if len(names) > 0 && len(arm.Patterns) > 1 {
+		return diagnostic.NewResolveError(names[0], "alternative patterns can't bind variables")
+	}
+	for _, name := range names {
+		if _, ok := r.envs[len(r.envs)-1][name.Lexeme]; ok {
+			return diagnostic.NewResolveError(name, "duplicate binding '%s' in pattern", name.Lexeme)
+		}
+		r.Declare(name.Lexeme)
+		r.Define(name.Lexeme)
+	}
+
+	if arm.Guard != nil {
+		if _, err := r.ResolveExpr(arm.Guard); err != nil {
+			return err
+		}
+	}
+	return resolveBody()
+}
+
+// bindings appends the names the pattern binds.
+func bindings(p ast.Pattern, names []token.Token) []token.Token {
+	switch p := p.(type) {
+	case ast.BindingPattern:
+		names = append(names, p.Name)
+	case ast.ListPattern:
+		for _, e := range p.Elements {
+			names = bindings(e, names)
+		}
+	case ast.MapPattern:
+		for _, v := range p.Values {
+			names = bindings(v, names)
+		}
+	}
+	return names
+}
+
 var _ ast.ExpressionVisitor = (*Resolver)(nil)
 var _ ast.StatementVistior = (*Resolver)(nil)
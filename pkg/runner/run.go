@@ -6,15 +6,42 @@ import (
 	"io"
 	"log/slog"
 	"os"
+	"strings"
 
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/interpreter"
 	"github.com/taehioum/glox/pkg/parser"
 	"github.com/taehioum/glox/pkg/resolver"
 	"github.com/taehioum/glox/pkg/scanner"
+	"github.com/taehioum/glox/pkg/vm"
+)
+
+// Backend selects how the parsed program is executed.
+type Backend int
+
+const (
+	// BackendInterpreter walks the ast.
+	BackendInterpreter Backend = iota
+	// BackendVM compiles the ast into bytecode, and runs it on a stack vm.
+	BackendVM
 )
 
 type Runner struct {
 	// HadError bool
+	Backend Backend
+	// Setup, if set, prepares the interpreter before each program is run,
+	// e.g. for a host embedding glox to define its own functions and globals.
+	// it is only supported by the tree-walking interpreter.
+	Setup func(*interpreter.Interpreter)
+	// Options configures the interpreter, whose output goes to the writer of each run regardless of Options.Stdout.
+	// by default, scripts are trusted with all modules, and the standard input and error of the process.
+	// imports are read from files if the fs module is enabled, unless Options.Loader is set.
+	// it is only supported by the tree-walking interpreter.
+	Options *interpreter.Options
+
+	// sources run so far, by file name
+	sources map[string]string
 }
 
 func (i *Runner) Runfile(path string) error {
@@ -23,24 +50,44 @@ func (i *Runner) Runfile(path string) error {
 		return fmt.Errorf("running file: %w", err)
 	}
 
-	return i.Run(string(contents), os.Stdout)
+	return i.run(path, string(contents), os.Stdout)
 }
 
+// RunPrompt reads and runs input line by line, keeping the state of the session in between.
+// the prompt always runs on the tree-walking interpreter.
 func (i *Runner) RunPrompt() error {
+	session := NewSession(os.Stdout)
+	color := diagnostic.IsTerminal(os.Stdout)
 	sc := bufio.NewScanner(os.Stdin)
+
+	var input strings.Builder
 	for {
-		fmt.Print("> ")
+		if input.Len() == 0 {
+			fmt.Print("> ")
+		} else {
+			fmt.Print(". ")
+		}
 		b := sc.Scan()
 		if !b {
 			break
 		}
-		err := i.Run(sc.Text(), os.Stdout)
+		input.WriteString(sc.Text())
+		input.WriteString("\n")
+		if !Complete(input.String()) {
+			continue
+		}
+
+		err := session.Run(input.String())
 		if err != nil {
 			// return fmt.Errorf("running prompt: %w", err)
-			fmt.Printf("running prompt %s: %s\n", sc.Text(), err)
+			session.Diagnostics(color).Render(os.Stdout, err)
 		}
+		input.Reset()
 	}
 
+	if err := session.Close(); err != nil {
+		session.Diagnostics(color).Render(os.Stdout, err)
+	}
 	if sc.Err() != nil {
 		return fmt.Errorf("running prompt: %w", sc.Err())
 	}
@@ -49,7 +96,17 @@ func (i *Runner) RunPrompt() error {
 
 // the main logic
 func (i *Runner) Run(source string, writer io.Writer) error {
-	tokens, err := scanner.ScanTokens(source)
+	return i.run("", source, writer)
+}
+
+// run runs the source read from the file. the file is empty for sources that are not files.
+func (i *Runner) run(file string, source string, writer io.Writer) error {
+	if i.sources == nil {
+		i.sources = make(map[string]string)
+	}
+	i.sources[file] = source
+
+	tokens, err := scanner.ScanFile(file, source)
 	if err != nil {
 		return fmt.Errorf("running: %w", err)
 	}
@@ -61,7 +118,25 @@ func (i *Runner) Run(source string, writer io.Writer) error {
 	}
 
 	slog.Debug("stmts", slog.Attr{Key: "stmts", Value: slog.AnyValue(stmts)})
-	intpr := interpreter.New(writer)
+	if i.Backend == BackendVM {
+		if i.Setup != nil || i.Options != nil {
+			return fmt.Errorf("running: setting up the interpreter is not supported by the vm backend")
+		}
+		return runVM(stmts, writer, compileFile(i.sources))
+	}
+
+	opts := TrustedOptions()
+	if i.Options != nil {
+		opts = *i.Options
+	}
+	opts.Stdout = writer
+	if opts.Loader == nil && opts.Modules&interpreter.ModuleFS != 0 {
+		opts.Loader = loadFile(i.sources)
+	}
+	intpr := interpreter.New(opts)
+	if i.Setup != nil {
+		i.Setup(intpr)
+	}
 
 	resolver := resolver.New(intpr)
 	err = resolver.Resolve(stmts)
@@ -70,9 +145,89 @@ func (i *Runner) Run(source string, writer io.Writer) error {
 	}
 
 	err = intpr.Interprete(stmts...)
+	// generators left suspended are closed even if the program failed, so that their goroutines exit.
+	if cerr := intpr.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// loadFile returns a loader of imported files, which records their sources in the map, to render their errors.
+func loadFile(sources map[string]string) interpreter.Loader {
+	return func(intpr *interpreter.Interpreter, path string) ([]ast.Stmt, error) {
+		return parseFile(sources, path, resolver.New(intpr))
+	}
+}
+
+// compileFile returns a loader of imported files for the vm, which records their sources in the map, to render their errors.
+func compileFile(sources map[string]string) vm.Loader {
+	return func(path string) (*vm.Function, error) {
+		stmts, err := parseFile(sources, path, resolver.New(nil))
+		if err != nil {
+			return nil, err
+		}
+		fn, err := vm.Compile(stmts)
+		if err != nil {
+			return nil, fmt.Errorf("compiling: %w", err)
+		}
+		return fn, nil
+	}
+}
+
+// parseFile reads, parses and resolves the imported file, recording its source in the map.
+func parseFile(sources map[string]string, path string, r *resolver.Resolver) ([]ast.Stmt, error) {
+	contents, err := os.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("importing: %w", err)
 	}
+	sources[path] = string(contents)
 
-	return nil
+	tokens, err := scanner.ScanFile(path, string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("importing: %w", err)
+	}
+	stmts, err := parser.Parse(tokens)
+	if err != nil {
+		return nil, fmt.Errorf("importing: %w", err)
+	}
+	if err := r.Resolve(stmts); err != nil {
+		return nil, fmt.Errorf("resolving: %w", err)
+	}
+	return stmts, nil
+}
+
+// TrustedOptions gives scripts all modules, and the standard input and error of the process, as the command line does.
+func TrustedOptions() interpreter.Options {
+	return interpreter.Options{
+		Modules: interpreter.ModuleAll,
+		Stdin:   os.Stdin,
+		Stderr:  os.Stderr,
+	}
+}
+
+// Diagnostics returns a renderer for the errors of the sources run so far.
+func (i *Runner) Diagnostics(color bool) diagnostic.Renderer {
+	return diagnostic.Renderer{Sources: i.sources, Color: color}
+}
+
+func runVM(stmts []ast.Stmt, writer io.Writer, loader vm.Loader) error {
+	// the compiler resolves variables by itself, the resolver only checks the program.
+	err := resolver.New(nil).Resolve(stmts)
+	if err != nil {
+		return fmt.Errorf("resolving: %w", err)
+	}
+
+	fn, err := vm.Compile(stmts)
+	if err != nil {
+		return fmt.Errorf("compiling: %w", err)
+	}
+
+	machine := vm.New(writer)
+	machine.SetLoader(loader)
+	err = machine.Interprete(fn)
+	// like the interpreter, the vm runs the finally blocks of the generators left suspended, even if the program failed.
+	if cerr := machine.Close(); err == nil {
+		err = cerr
+	}
+	return err
 }
@@ -0,0 +1,33 @@
+package runner
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderAllErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		kind   string
+	}{
+		{"var = 1;\nvar = 2;", "parse error:"},
+		{"var a = @;\nvar b = #;", "scan error:"},
+	}
+	for _, tt := range tests {
+		var r Runner
+		err := r.Run(tt.source, io.Discard)
+		if !assert.Error(t, err, tt.source) {
+			continue
+		}
+
+		var b bytes.Buffer
+		r.Diagnostics(false).Render(&b, err)
+		assert.Equal(t, 2, strings.Count(b.String(), tt.kind), b.String())
+		assert.Contains(t, b.String(), " 1 | ")
+		assert.Contains(t, b.String(), " 2 | ")
+	}
+}
@@ -0,0 +1,145 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/interpreter"
+	"github.com/taehioum/glox/pkg/parser"
+	"github.com/taehioum/glox/pkg/resolver"
+	"github.com/taehioum/glox/pkg/scanner"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// Session runs consecutive inputs against the same interpreter, for the prompt.
+// variables, functions and classes defined by an input are visible to the following ones.
+type Session struct {
+	interpreter *interpreter.Interpreter
+	resolver    *resolver.Resolver
+	writer      io.Writer
+
+	// line the next input starts on.
+	// each input continues the line numbers of the previous ones, so that resolved locals never collide.
+	line int
+	// history holds all inputs so far, so that errors can be shown along with the line they are on.
+	history strings.Builder
+	// sources of the imported files, by path
+	sources map[string]string
+}
+
+func NewSession(writer io.Writer) *Session {
+	sources := make(map[string]string)
+	opts := TrustedOptions()
+	opts.Stdout = writer
+	opts.Loader = loadFile(sources)
+	intpr := interpreter.New(opts)
+	return &Session{
+		interpreter: intpr,
+		resolver:    resolver.New(intpr),
+		writer:      writer,
+		line:        1,
+		sources:     sources,
+	}
+}
+
+// Run runs a single input.
+// the value of each bare expression statement is printed, unless it is nil.
+// a missing semicolon after the last expression is tolerated.
+func (s *Session) Run(source string) error {
+	tokens, err := scanner.ScanTokensFromLine(source, s.line)
+	s.record(source)
+	if err != nil {
+		return fmt.Errorf("running: %w", err)
+	}
+
+	tokens = terminate(tokens)
+	slog.Debug("tokens", slog.Attr{Key: "tokens", Value: slog.AnyValue(tokens)})
+	stmts, err := parser.Parse(tokens)
+	if err != nil {
+		return fmt.Errorf("running: %w", err)
+	}
+
+	slog.Debug("stmts", slog.Attr{Key: "stmts", Value: slog.AnyValue(stmts)})
+	err = s.resolver.Resolve(stmts)
+	if err != nil {
+		return fmt.Errorf("resolving: %w", err)
+	}
+
+	for _, stmt := range stmts {
+		expr, ok := stmt.(ast.Expression)
+		if !ok {
+			if err := s.interpreter.Interprete(stmt); err != nil {
+				return err
+			}
+			continue
+		}
+
+		v, err := s.interpreter.Eval(expr.Expr)
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			fmt.Fprintln(s.writer, v)
+		}
+	}
+	return nil
+}
+
+// Close closes the generators left suspended by the inputs, once the session is over.
+func (s *Session) Close() error {
+	return s.interpreter.Close()
+}
+
+// record appends the input to the history, and moves on to the line after it.
+func (s *Session) record(source string) {
+	if !strings.HasSuffix(source, "\n") {
+		source += "\n"
+	}
+	s.history.WriteString(source)
+	s.line += strings.Count(source, "\n")
+}
+
+// Diagnostics returns a renderer for the errors of the inputs run so far.
+func (s *Session) Diagnostics(color bool) diagnostic.Renderer {
+	sources := map[string]string{"": s.history.String()}
+	for path, source := range s.sources {
+		sources[path] = source
+	}
+	return diagnostic.Renderer{Sources: sources, Color: color}
+}
+
+// terminate appends a semicolon to input that doesn't end with one, or with a block.
+func terminate(tokens []token.Token) []token.Token {
+	if len(tokens) < 2 {
+		return tokens
+	}
+	eof := tokens[len(tokens)-1]
+	last := tokens[len(tokens)-2]
+	if last.Type == token.SEMICOLON || last.Type == token.RIGHTBRACE {
+		return tokens
+	}
+
+	semicolon := token.Token{Type: token.SEMICOLON, Lexeme: ";", Ln: last.Ln, Col: last.Col + utf8.RuneCountInString(last.Lexeme)}
+	return append(tokens[:len(tokens)-1], semicolon, eof)
+}
+
+// Complete reports whether the input can be run, or whether the prompt should keep reading lines,
+// as it has unclosed parentheses, braces or brackets.
+func Complete(source string) bool {
+	sc := scanner.NewScanner(source)
+	depth := 0
+	for tok := sc.Scan(); tok.Type != token.EOF; tok = sc.Scan() {
+		switch tok.Type {
+		case token.LEFTPAREN, token.LEFTBRACE, token.LEFTBRACKET:
+			depth++
+		case token.RIGHTPAREN, token.RIGHTBRACE, token.RIGHTBRACKET:
+			depth--
+		}
+	}
+	return depth <= 0
+}
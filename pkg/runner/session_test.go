@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSession(t *testing.T) {
+	var b bytes.Buffer
+	s := NewSession(&b)
+
+	inputs := []string{
+		"var x = 1;",
+		"fun add(a) { return a + x; }",
+		"add(2)",
+		"{ var y = 10; fun get() { return y; } x = get; }",
+		"x();",
+		"print(\"printed\");",
+		"nil",
+	}
+	for _, in := range inputs {
+		err := s.Run(in)
+		assert.NoError(t, err, in)
+	}
+	assert.Equal(t, "3\n10\nprinted\n", b.String())
+}
+
+func TestComplete(t *testing.T) {
+	testCases := []struct {
+		in       string
+		complete bool
+	}{
+		{in: "var x = 1;", complete: true},
+		{in: "fun f() {", complete: false},
+		{in: "fun f() {\n  return (1 +\n", complete: false},
+		{in: "fun f() {\n  return (1 +\n 2);\n}", complete: true},
+		{in: "}", complete: true},
+		{in: "var xs = [\n 1,", complete: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.in, func(t *testing.T) {
+			assert.Equal(t, tc.complete, Complete(tc.in))
+		})
+	}
+}
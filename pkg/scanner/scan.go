@@ -3,19 +3,45 @@ package scanner
 import (
 	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
+	"strings"
 	"unicode"
+	"unicode/utf8"
 
+	"github.com/taehioum/glox/pkg/diagnostic"
 	"github.com/taehioum/glox/pkg/token"
 )
 
 type Scanner struct {
 	source string
+	file   string
 	errors []error
 
 	start int
 	curr  int
 	line  int
+
+	// lineCol is the number of characters consumed on the current line, i.e. runes rather than bytes.
+	lineCol int
+	// col is the column of the token being scanned, starting from 1.
+	col int
+
+	// interpolations holds the number of unclosed braces in each interpolated expression being scanned, innermost last.
+	// the '}' that closes an interpolation resumes its string.
+	interpolations []int
+
+	// the last two tokens scanned, and whether the last one is a ')' closing a header, to tell integer divisions from comments.
+	prev, beforePrev token.Token
+	closedHeader     bool
+	// parens holds whether each unclosed parenthesis, innermost last, encloses a header rather than an operand, e.g. `if (x)`.
+	parens []bool
+	// classHeader is whether a class is being declared, up to its body, e.g. `class A < B`.
+	classHeader bool
+	// braces is the number of unclosed braces, and classBodies the number each unclosed class body was opened at, innermost last,
+	// to tell the declarations of methods from calls.
+	braces      int
+	classBodies []int
 }
 
 func NewScanner(source string) Scanner {
@@ -24,17 +50,36 @@ func NewScanner(source string) Scanner {
 		start:  0,
 		curr:   0,
 		line:   1,
+		col:    1,
 	}
 }
 
 func ScanTokens(source string) ([]token.Token, error) {
+	return ScanTokensFromLine(source, 1)
+}
+
+// ScanTokensFromLine scans the source as if it started on the given line,
+// e.g. for the prompt, where each input continues the previous ones.
+func ScanTokensFromLine(source string, line int) ([]token.Token, error) {
 	sc := NewScanner(source)
+	sc.line = line
+	return sc.scanAll()
+}
 
+// ScanFile scans the source read from the file, so that the tokens and errors carry its name.
+func ScanFile(file string, source string) ([]token.Token, error) {
+	sc := NewScanner(source)
+	sc.file = file
+	return sc.scanAll()
+}
+
+func (sc *Scanner) scanAll() ([]token.Token, error) {
 	var tokens []token.Token
 	for tok := sc.Scan(); tok.Type != token.EOF; tok = sc.Scan() {
+		tok.File = sc.file
 		tokens = append(tokens, tok)
 	}
-	tokens = append(tokens, token.Token{Type: token.EOF, Lexeme: sc.lexeme(), Ln: sc.line})
+	tokens = append(tokens, token.Token{Type: token.EOF, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col, File: sc.file})
 
 	if err := sc.Err(); err != nil {
 		return nil, fmt.Errorf("scanning tokens: %w", err)
@@ -42,69 +87,187 @@ func ScanTokens(source string) ([]token.Token, error) {
 	return tokens, nil
 }
 
+// Scan scans the next token.
 func (sc *Scanner) Scan() token.Token {
+	tok := sc.scan()
+	sc.closedHeader = false
+	switch tok.Type {
+	case token.CLASS:
+		sc.classHeader = true
+	case token.LEFTBRACE:
+		sc.braces++
+		if sc.classHeader {
+			sc.classBodies = append(sc.classBodies, sc.braces)
+			sc.classHeader = false
+		}
+	case token.RIGHTBRACE:
+		if n := len(sc.classBodies); n > 0 && sc.classBodies[n-1] == sc.braces {
+			sc.classBodies = sc.classBodies[:n-1]
+		}
+		sc.braces--
+	case token.LEFTPAREN:
+		sc.parens = append(sc.parens, sc.opensHeader())
+	case token.RIGHTPAREN:
+		if n := len(sc.parens); n > 0 {
+			sc.closedHeader = sc.parens[n-1]
+			sc.parens = sc.parens[:n-1]
+		}
+	}
+	sc.beforePrev, sc.prev = sc.prev, tok
+	return tok
+}
+
+// opensHeader reports whether the parenthesis being opened encloses the condition of a statement,
+// or the parameters of a function, rather than an operand.
+func (sc *Scanner) opensHeader() bool {
+	switch sc.prev.Type {
+	case token.IF, token.WHILE, token.FOR, token.MATCH, token.CATCH, token.FUN:
+		return true
+	case token.IDENTIFIER:
+		// the name of a function, or of a method, which is declared right in the body of a class.
+		if sc.beforePrev.Type == token.FUN {
+			return true
+		}
+		n := len(sc.classBodies)
+		inClass := n > 0 && sc.classBodies[n-1] == sc.braces
+		return inClass && (sc.beforePrev.Type == token.LEFTBRACE || sc.beforePrev.Type == token.RIGHTBRACE)
+	}
+	return false
+}
+
+// divides reports whether the "//" being scanned divides the operand before it, rather than starting a comment.
+// it does right after an operand, on the same line.
+func (sc *Scanner) divides() bool {
+	if sc.prev.Ln != sc.line || sc.classHeader {
+		return false
+	}
+	switch sc.prev.Type {
+	case token.NUMBER, token.STRING, token.IDENTIFIER, token.TRUE, token.FALSE, token.NIL, token.THIS, token.RIGHTBRACKET:
+		return true
+	case token.RIGHTPAREN:
+		return !sc.closedHeader
+	default:
+		return false
+	}
+}
+
+func (sc *Scanner) scan() token.Token {
 	sc.start = sc.curr
+	sc.col = sc.lineCol + 1
 	if sc.curr >= len(sc.source) {
-		return token.Token{Type: token.EOF, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.EOF, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	}
 
 	c := sc.advance()
 
 	switch c {
 	case '(':
-		return token.Token{Type: token.LEFTPAREN, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.LEFTPAREN, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case ')':
-		return token.Token{Type: token.RIGHTPAREN, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.RIGHTPAREN, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case '{':
-		return token.Token{Type: token.LEFTBRACE, Lexeme: sc.lexeme(), Ln: sc.line}
+		if len(sc.interpolations) > 0 {
+			sc.interpolations[len(sc.interpolations)-1]++
+		}
+		return token.Token{Type: token.LEFTBRACE, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case '}':
-		return token.Token{Type: token.RIGHTBRACE, Lexeme: sc.lexeme(), Ln: sc.line}
+		if n := len(sc.interpolations); n > 0 {
+			if sc.interpolations[n-1] == 0 {
+				sc.interpolations = sc.interpolations[:n-1]
+				return sc.scanString()
+			}
+			sc.interpolations[n-1]--
+		}
+		return token.Token{Type: token.RIGHTBRACE, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '[':
+		return token.Token{Type: token.LEFTBRACKET, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case ']':
+		return token.Token{Type: token.RIGHTBRACKET, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case ',':
-		return token.Token{Type: token.COMMA, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.COMMA, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case '.':
-		return token.Token{Type: token.DOT, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.DOT, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case '-':
 		if sc.match('-') {
-			return token.Token{Type: token.MINUSMINUS, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.MINUSMINUS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('=') {
+			return token.Token{Type: token.MINUSEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.MINUS, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.MINUS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '+':
 		if sc.match('+') {
-			return token.Token{Type: token.PLUSPLUS, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.PLUSPLUS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('=') {
+			return token.Token{Type: token.PLUSEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.PLUS, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.PLUS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '*':
-		return token.Token{Type: token.STAR, Lexeme: sc.lexeme(), Ln: sc.line}
+		if sc.match('*') {
+			return token.Token{Type: token.STARSTAR, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('=') {
+			return token.Token{Type: token.STAREQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else {
+			return token.Token{Type: token.STAR, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		}
+	case '%':
+		if sc.match('=') {
+			return token.Token{Type: token.PERCENTEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		}
+		return token.Token{Type: token.PERCENT, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '&':
+		return token.Token{Type: token.AMPERSAND, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '|':
+		return token.Token{Type: token.PIPE, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '^':
+		return token.Token{Type: token.CARET, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '~':
+		return token.Token{Type: token.TILDE, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case ';':
-		return token.Token{Type: token.SEMICOLON, Lexeme: sc.lexeme(), Ln: sc.line}
+		return token.Token{Type: token.SEMICOLON, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case ':':
+		return token.Token{Type: token.COLON, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+	case '?':
+		if sc.match('?') {
+			return token.Token{Type: token.QUESTIONQUESTION, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		}
+		return token.Token{Type: token.QUESTION, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 	case '!':
 		if sc.match('=') {
-			return token.Token{Type: token.BANGEQUAL, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.BANGEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.BANG, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.BANG, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '=':
 		if sc.match('=') {
-			return token.Token{Type: token.EQUALEQUAL, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.EQUALEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('>') {
+			return token.Token{Type: token.FATARROW, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.EQUAL, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.EQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '<':
-		if sc.match('=') {
-			return token.Token{Type: token.LESSEQUAL, Lexeme: sc.lexeme(), Ln: sc.line}
+		if sc.match('<') {
+			return token.Token{Type: token.LESSLESS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('=') {
+			return token.Token{Type: token.LESSEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.LESS, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.LESS, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '>':
-		if sc.match('=') {
-			return token.Token{Type: token.GREATEREQUAL, Lexeme: sc.lexeme(), Ln: sc.line}
+		if sc.match('>') {
+			return token.Token{Type: token.GREATERGREATER, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('=') {
+			return token.Token{Type: token.GREATEREQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.GREATER, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.GREATER, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case '/':
-		if sc.match('/') { // a comment string
+		if sc.peek() == '/' && sc.divides() {
+			sc.advance()
+			return token.Token{Type: token.SLASHSLASH, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if sc.match('/') { // a comment string
 			for {
 				// don't consume the newline, so that the line number is incremented upon newline.
 				if sc.peek() == '\n' || sc.atEnd() {
@@ -112,44 +275,58 @@ func (sc *Scanner) Scan() token.Token {
 				}
 				sc.advance()
 			}
-			return sc.Scan()
+			return sc.scan()
+		} else if sc.match('=') {
+			return token.Token{Type: token.SLASHEQUAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		} else {
-			return token.Token{Type: token.SLASH, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: token.SLASH, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
 		}
 	case ' ', '\r', '\t':
-		return sc.Scan()
+		return sc.scan()
 	case '\n':
-		sc.line++
-		return sc.Scan()
+		sc.newline()
+		return sc.scan()
 	case '"':
-		val, err := sc.readString()
-		if err != nil {
-			sc.errors = append(sc.errors, err)
-			return sc.Scan()
+		return sc.scanString()
+	case '`':
+		// raw strings have no escapes nor interpolations, and may span lines as well.
+		ln, col := sc.line, sc.col
+		for sc.peek() != '`' && !sc.atEnd() {
+			if sc.advance() == '\n' {
+				sc.newline()
+			}
+		}
+		if sc.atEnd() {
+			sc.errors = append(sc.errors, diagnostic.NewScanError(token.Token{Type: token.ILLEGAL, Lexeme: sc.lexeme(), Ln: ln, Col: col, File: sc.file}, "unterminated raw string"))
+			return sc.scan()
 		}
-		return token.Token{Type: token.STRING, Lexeme: sc.lexeme(), Literal: val, Ln: sc.line}
+		sc.advance() // the closing `
+		return token.Token{Type: token.STRING, Lexeme: sc.lexeme(), Literal: sc.source[sc.start+1 : sc.curr-1], Ln: ln, Col: col}
 	// numbers
 	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 		val, err := sc.readNumber()
 		if err != nil {
-			sc.errors = append(sc.errors, err)
-			return sc.Scan()
+			sc.errorf("invalid number: %s", err)
+			return sc.scan()
 		}
-		return token.Token{Type: token.NUMBER, Lexeme: sc.lexeme(), Literal: val, Ln: sc.line}
+		return token.Token{Type: token.NUMBER, Lexeme: sc.lexeme(), Literal: val, Ln: sc.line, Col: sc.col}
 	default:
-		if unicode.IsLetter(rune(c)) {
+		if unicode.IsLetter(c) || c == '_' {
 			tok := sc.readIdentifierOrKeyword()
-			return token.Token{Type: tok, Lexeme: sc.lexeme(), Ln: sc.line}
+			return token.Token{Type: tok, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col}
+		} else if c == utf8.RuneError {
+			sc.errorf("invalid utf-8 encoding")
+			return sc.scan()
 		} else {
-			sc.errors = append(sc.errors, fmt.Errorf("unexpected character: %c", c))
-			return sc.Scan()
+			sc.errorf("unexpected character: %c", c)
+			return sc.scan()
 		}
 	}
 }
 
 // readIdentifierOrKeyword consumes the rest of the identifier / keyword by advancing.
 func (sc *Scanner) readIdentifierOrKeyword() token.Type {
-	for (unicode.IsLetter(rune(sc.peek())) || unicode.IsDigit(rune(sc.peek()))) && !sc.atEnd() {
+	for isIdentifier(sc.peek()) && !sc.atEnd() {
 		sc.advance()
 	}
 
@@ -160,84 +337,248 @@ func (sc *Scanner) readIdentifierOrKeyword() token.Type {
 	return token.IDENTIFIER
 }
 
-// readString consumes the rest of the string by advancing, and returns its literal value
-func (sc *Scanner) readString() (literal string, err error) {
+// scanString scans a string, or the part of it up to an interpolation, after its opening quote or the '}' of an interpolation.
+func (sc *Scanner) scanString() token.Token {
+	// a string may span lines, so keep the position of its opening quote.
+	ln, col := sc.line, sc.col
+	val, interpolation, err := sc.readString()
+	if err != nil {
+		sc.errors = append(sc.errors, diagnostic.NewScanError(token.Token{Type: token.ILLEGAL, Lexeme: sc.lexeme(), Ln: ln, Col: col, File: sc.file}, "%s", err))
+		return sc.scan()
+	}
+	if interpolation {
+		sc.interpolations = append(sc.interpolations, 0)
+		return token.Token{Type: token.INTERPOLATION, Lexeme: sc.lexeme(), Literal: val, Ln: ln, Col: col}
+	}
+	return token.Token{Type: token.STRING, Lexeme: sc.lexeme(), Literal: val, Ln: ln, Col: col}
+}
+
+// readString consumes the rest of the string by advancing, and returns its literal value, with its escapes decoded.
+// it stops after the "${" of an interpolation, if any, and reports that the string resumes after the expression.
+// invalid escapes are reported without stopping the string, so that the characters after them are not scanned as code.
+func (sc *Scanner) readString() (literal string, interpolation bool, err error) {
+	var b strings.Builder
 	for sc.peek() != '"' && !sc.atEnd() {
-		if sc.peek() == '\n' {
-			sc.line++
+		c := sc.advance()
+		switch {
+		case c == '\n':
+			sc.newline()
+			b.WriteRune(c)
+		case c == '$' && sc.peek() == '{':
+			sc.advance()
+			return b.String(), true, nil
+		case c == '\\':
+			start, col := sc.curr-1, sc.lineCol
+			if err := sc.readEscape(&b); err != nil {
+				// point at the escape, rather than at the whole string.
+				tok := token.Token{Type: token.ILLEGAL, Lexeme: sc.source[start:sc.curr], Ln: sc.line, Col: col, File: sc.file}
+				sc.errors = append(sc.errors, diagnostic.NewScanError(tok, "%s", err))
+			}
+		default:
+			b.WriteRune(c)
 		}
-		sc.advance()
 	}
 
 	if sc.atEnd() {
-		return "", fmt.Errorf("unterminated string")
+		return "", false, fmt.Errorf("unterminated string")
 	}
 
 	// the closing "
 	sc.advance()
+	return b.String(), false, nil
+}
 
-	// trim the surrounding quotes
-	value := sc.source[sc.start+1 : sc.curr-1]
-	return value, nil
+var escapes = map[rune]rune{
+	'n':  '\n',
+	't':  '\t',
+	'r':  '\r',
+	'0':  0,
+	'\\': '\\',
+	'"':  '"',
+	'$':  '$',
 }
 
-// readNumber consumes the rest of the number by advancing, and returns its literal value
-func (sc *Scanner) readNumber() (literal float64, err error) {
-	for (unicode.IsDigit(rune(sc.peek()))) && !sc.atEnd() {
+// readEscape consumes the escape sequence after a backslash, and writes the character it stands for.
+// besides the usual ones, \u{...} stands for the unicode code point of 1 to 6 hex digits.
+func (sc *Scanner) readEscape(b *strings.Builder) error {
+	if sc.atEnd() {
+		return nil
+	}
+	c := sc.advance()
+	if e, ok := escapes[c]; ok {
+		b.WriteRune(e)
+		return nil
+	}
+	if c != 'u' {
+		return fmt.Errorf("invalid escape sequence '\\%c'", c)
+	}
+
+	if !sc.match('{') {
+		return fmt.Errorf("expected '{' after '\\u'")
+	}
+	start := sc.curr
+	for sc.peek() != '}' && sc.peek() != '"' && !sc.atEnd() {
 		sc.advance()
 	}
+	digits := sc.source[start:sc.curr]
+	if !sc.match('}') {
+		return fmt.Errorf("unterminated unicode escape")
+	}
+	code, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || len(digits) > 6 || !utf8.ValidRune(rune(code)) {
+		return fmt.Errorf("invalid unicode escape '\\u{%s}'", digits)
+	}
+	b.WriteRune(rune(code))
+	return nil
+}
+
+var bases = map[rune]int{'x': 16, 'X': 16, 'o': 8, 'O': 8, 'b': 2, 'B': 2}
+
+// readNumber consumes the rest of the number by advancing, and returns its literal value:
+// an int64 for integers, written in decimal, or in hex, octal or binary after 0x, 0o or 0b,
+// or a *big.Int for integers that don't fit in an int64,
+// a *big.Rat for decimals, which are suffixed with d, e.g. 0.10d,
+// and a float64 for numbers with a fractional part.
+// digits may be separated by underscores, e.g. 1_000_000.
+func (sc *Scanner) readNumber() (literal any, err error) {
+	if base, ok := bases[sc.peek()]; ok && sc.lexeme() == "0" {
+		sc.advance() // the base
+		// letters are consumed as well, so that invalid digits are reported rather than scanned as identifiers.
+		for unicode.IsLetter(sc.peek()) || isDigit(sc.peek()) || sc.peek() == '_' {
+			sc.advance()
+		}
+		digits := sc.lexeme()[2:]
+		if err := checkSeparators(digits); err != nil {
+			return nil, err
+		}
+		return parseInt(strings.ReplaceAll(digits, "_", ""), base)
+	}
 
-	// look for a fractional part
-	if sc.peek() == '.' && unicode.IsDigit(rune(sc.peekNext())) {
+	sc.readDigits()
+	fraction := sc.peek() == '.' && isDigit(sc.peekNext())
+	if fraction {
 		// consume the '.'
 		sc.advance()
+		sc.readDigits()
+	}
 
-		for (unicode.IsDigit(rune(sc.peek()))) && !sc.atEnd() {
-			sc.advance()
+	whole, frac, _ := strings.Cut(sc.lexeme(), ".")
+	if err := checkSeparators(whole); err != nil {
+		return nil, err
+	}
+	if err := checkSeparators(frac); err != nil {
+		return nil, err
+	}
+	text := strings.ReplaceAll(sc.lexeme(), "_", "")
+	if sc.peek() == 'd' && !isIdentifier(sc.peekNext()) {
+		sc.advance() // the suffix
+		r, ok := new(big.Rat).SetString(text)
+		if !ok {
+			return nil, fmt.Errorf("invalid decimal")
 		}
+		return r, nil
+	}
+	if fraction {
+		return strconv.ParseFloat(text, 64)
+	}
+	return parseInt(text, 10)
+}
+
+// parseInt parses the digits into an int64, or a *big.Int if they don't fit in one.
+func parseInt(digits string, base int) (any, error) {
+	n, err := strconv.ParseInt(digits, base, 64)
+	if err == nil {
+		return n, nil
+	}
+	if !errors.Is(err, strconv.ErrRange) {
+		return nil, fmt.Errorf("invalid digits for base %d", base)
 	}
+	b, ok := new(big.Int).SetString(digits, base)
+	if !ok {
+		return nil, fmt.Errorf("invalid digits for base %d", base)
+	}
+	return b, nil
+}
+
+// isIdentifier reports whether the character may be part of an identifier.
+func isIdentifier(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_'
+}
+
+// readDigits consumes decimal digits, and the underscores separating them.
+func (sc *Scanner) readDigits() {
+	for isDigit(sc.peek()) || sc.peek() == '_' {
+		sc.advance()
+	}
+}
+
+// checkSeparators checks that the underscores of the digits are in between two digits.
+func checkSeparators(digits string) error {
+	if strings.HasPrefix(digits, "_") || strings.HasSuffix(digits, "_") || strings.Contains(digits, "__") {
+		return fmt.Errorf("'_' must separate digits")
+	}
+	return nil
+}
 
-	return strconv.ParseFloat(sc.source[sc.start:sc.curr], 64)
+// advance consumes the next character, decoding it from utf-8.
+func (sc *Scanner) advance() rune {
+	c, size := utf8.DecodeRuneInString(sc.source[sc.curr:])
+	sc.curr += size
+	sc.lineCol++
+	return c
 }
 
-func (sc *Scanner) advance() byte {
-	sc.curr++
-	return sc.source[sc.curr-1]
+// newline moves on to the next line, after its newline has been consumed.
+func (sc *Scanner) newline() {
+	sc.line++
+	sc.lineCol = 0
 }
 
 // match is a conditional advance.
 // if the next character is not expected, do not advance
-func (sc *Scanner) match(expected byte) bool {
-	if sc.atEnd() {
+func (sc *Scanner) match(expected rune) bool {
+	if sc.atEnd() || sc.peek() != expected {
 		return false
 	}
-	if sc.source[sc.curr] != expected {
-		return false
-	}
-
-	sc.curr++
+	sc.advance()
 	return true
 }
 
+// errorf records an error at the characters scanned so far.
+func (sc *Scanner) errorf(format string, args ...any) {
+	tok := token.Token{Type: token.ILLEGAL, Lexeme: sc.lexeme(), Ln: sc.line, Col: sc.col, File: sc.file}
+	sc.errors = append(sc.errors, diagnostic.NewScanError(tok, format, args...))
+}
+
 func (sc *Scanner) Err() error {
 	return errors.Join(sc.errors...)
 }
 
 // peek is lookahead once
-func (sc *Scanner) peek() byte {
+func (sc *Scanner) peek() rune {
 	if sc.atEnd() {
 		return '\000'
 	}
-
-	return sc.source[sc.curr]
+	c, _ := utf8.DecodeRuneInString(sc.source[sc.curr:])
+	return c
 }
 
 // peekNext is lookahead twice.
-func (sc *Scanner) peekNext() byte {
-	if sc.curr+1 >= len(sc.source) {
+func (sc *Scanner) peekNext() rune {
+	if sc.atEnd() {
+		return '\000'
+	}
+	_, size := utf8.DecodeRuneInString(sc.source[sc.curr:])
+	if sc.curr+size >= len(sc.source) {
 		return '\000'
 	}
-	return sc.source[sc.curr+1]
+	c, _ := utf8.DecodeRuneInString(sc.source[sc.curr+size:])
+	return c
+}
+
+// isDigit reports whether the character is an ascii digit. other unicode digits don't make numbers.
+func isDigit(c rune) bool {
+	return c >= '0' && c <= '9'
 }
 
 func (sc *Scanner) atEnd() bool {
@@ -249,22 +590,32 @@ func (sc *Scanner) lexeme() string {
 }
 
 var keywords = map[string]token.Type{
-	"and":   token.AND,
-	"class": token.CLASS,
-	"else":  token.ELSE,
-	"false": token.FALSE,
-	"for":   token.FOR,
-	"fun":   token.FUN,
-	"if":    token.IF,
-	"nil":   token.NIL,
-	"or":    token.OR,
+	"and":     token.AND,
+	"as":      token.AS,
+	"case":    token.CASE,
+	"catch":   token.CATCH,
+	"class":   token.CLASS,
+	"else":    token.ELSE,
+	"false":   token.FALSE,
+	"finally": token.FINALLY,
+	"for":     token.FOR,
+	"fun":     token.FUN,
+	"if":      token.IF,
+	"import":  token.IMPORT,
+	"in":      token.IN,
+	"match":   token.MATCH,
+	"nil":     token.NIL,
+	"or":      token.OR,
 	// "print":    token.PRINT,
+	"throw":    token.THROW,
+	"try":      token.TRY,
 	"return":   token.RETURN,
 	"super":    token.SUPER,
 	"this":     token.THIS,
 	"true":     token.TRUE,
 	"var":      token.VAR,
 	"while":    token.WHILE,
+	"yield":    token.YIELD,
 	"break":    token.BREAK,
 	"continue": token.CONTINUE,
 }
@@ -1,6 +1,7 @@
 package scanner
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -19,6 +20,7 @@ func TestScannerValidCode(t *testing.T) {
 				{
 					Type: token.EOF,
 					Ln:   1,
+					Col:  1,
 				},
 			},
 			desc: "empty file, no newline at end",
@@ -30,6 +32,7 @@ func TestScannerValidCode(t *testing.T) {
 				{
 					Type: token.EOF,
 					Ln:   2,
+					Col:  4,
 				},
 			},
 			desc: "empty file with newline",
@@ -41,12 +44,14 @@ func TestScannerValidCode(t *testing.T) {
 				{
 					Type:    token.NUMBER,
 					Lexeme:  "123",
-					Literal: float64(123),
+					Literal: int64(123),
 					Ln:      1,
+					Col:     1,
 				},
 				{
 					Type: token.EOF,
 					Ln:   2,
+					Col:  4,
 				},
 			},
 			desc: "a number",
@@ -57,12 +62,14 @@ func TestScannerValidCode(t *testing.T) {
 				{
 					Type:    token.NUMBER,
 					Lexeme:  "123",
-					Literal: float64(123),
+					Literal: int64(123),
 					Ln:      1,
+					Col:     1,
 				},
 				{
 					Type: token.EOF,
 					Ln:   1,
+					Col:  4,
 				},
 			},
 			desc: "a number, no newline",
@@ -75,26 +82,31 @@ func TestScannerValidCode(t *testing.T) {
 					Type:   token.VAR,
 					Lexeme: "var",
 					Ln:     1,
+					Col:    1,
 				},
 				{
 					Type:   token.IDENTIFIER,
 					Lexeme: "x",
 					Ln:     1,
+					Col:    5,
 				},
 				{
 					Type:   token.EQUAL,
 					Lexeme: "=",
 					Ln:     1,
+					Col:    6,
 				},
 				{
 					Type:    token.NUMBER,
 					Lexeme:  "3.3",
 					Literal: float64(3.3),
 					Ln:      1,
+					Col:     7,
 				},
 				{
 					Type: token.EOF,
 					Ln:   2,
+					Col:  4,
 				},
 			},
 			desc: "var assignment (number)",
@@ -109,67 +121,80 @@ func TestScannerValidCode(t *testing.T) {
 					Type:   token.VAR,
 					Lexeme: "var",
 					Ln:     1,
+					Col:    1,
 				},
 				{
 					Type:   token.IDENTIFIER,
 					Lexeme: "x",
 					Ln:     1,
+					Col:    5,
 				},
 				{
 					Type:   token.EQUAL,
 					Lexeme: "=",
 					Ln:     1,
+					Col:    6,
 				},
 				{
 					Type:    token.NUMBER,
 					Lexeme:  "3.3",
 					Literal: float64(3.3),
 					Ln:      1,
+					Col:     7,
 				},
 				{
 					Type:   token.VAR,
 					Lexeme: "var",
 					Ln:     2,
+					Col:    5,
 				},
 				{
 					Type:   token.IDENTIFIER,
 					Lexeme: "y",
 					Ln:     2,
+					Col:    9,
 				},
 				{
 					Type:   token.EQUAL,
 					Lexeme: "=",
 					Ln:     2,
+					Col:    11,
 				},
 				{
 					Type:    token.NUMBER,
 					Lexeme:  "4",
-					Literal: float64(4),
+					Literal: int64(4),
 					Ln:      2,
+					Col:     13,
 				},
 				{
-					Type:   token.PRINT,
+					Type:   token.IDENTIFIER,
 					Lexeme: "print",
 					Ln:     3,
+					Col:    5,
 				},
 				{
 					Type:   token.IDENTIFIER,
 					Lexeme: "x",
 					Ln:     3,
+					Col:    11,
 				},
 				{
 					Type:   token.PLUS,
 					Lexeme: "+",
 					Ln:     3,
+					Col:    13,
 				},
 				{
 					Type:   token.IDENTIFIER,
 					Lexeme: "y",
 					Ln:     3,
+					Col:    15,
 				},
 				{
 					Type: token.EOF,
 					Ln:   4,
+					Col:  4,
 				},
 			},
 			desc: "var assignment and addition",
@@ -179,19 +204,22 @@ func TestScannerValidCode(t *testing.T) {
 			`,
 			expected: []token.Token{
 				{
-					Type:   token.PRINT,
+					Type:   token.IDENTIFIER,
 					Lexeme: "print",
 					Ln:     1,
+					Col:    1,
 				},
 				{
 					Type:    token.STRING,
 					Lexeme:  "\"hello\"",
 					Literal: "hello",
 					Ln:      1,
+					Col:     7,
 				},
 				{
 					Type: token.EOF,
 					Ln:   2,
+					Col:  4,
 				},
 			},
 			desc: "print string",
@@ -208,62 +236,74 @@ func TestScannerValidCode(t *testing.T) {
 					Type:   token.IF,
 					Lexeme: "if",
 					Ln:     1,
+					Col:    1,
 				},
 				{
 					Type:   token.TRUE,
 					Lexeme: "true",
 					Ln:     1,
+					Col:    4,
 				},
 				{
 					Type:   token.LEFTBRACE,
 					Lexeme: "{",
 					Ln:     1,
+					Col:    9,
 				},
 				{
-					Type:   token.PRINT,
+					Type:   token.IDENTIFIER,
 					Lexeme: "print",
 					Ln:     2,
+					Col:    6,
 				},
 				{
 					Type:    token.STRING,
 					Lexeme:  "\"true\"",
 					Literal: "true",
 					Ln:      2,
+					Col:     12,
 				},
 				{
 					Type:   token.RIGHTBRACE,
 					Lexeme: "}",
 					Ln:     3,
+					Col:    5,
 				},
 				{
 					Type:   token.ELSE,
 					Lexeme: "else",
 					Ln:     3,
+					Col:    7,
 				},
 				{
 					Type:   token.LEFTBRACE,
 					Lexeme: "{",
 					Ln:     3,
+					Col:    12,
 				},
 				{
-					Type:   token.PRINT,
+					Type:   token.IDENTIFIER,
 					Lexeme: "print",
 					Ln:     4,
+					Col:    6,
 				},
 				{
 					Type:    token.STRING,
 					Lexeme:  "\"false\"",
 					Literal: "false",
 					Ln:      4,
+					Col:     12,
 				},
 				{
 					Type:   token.RIGHTBRACE,
 					Lexeme: "}",
 					Ln:     5,
+					Col:    5,
 				},
 				{
 					Type: token.EOF,
 					Ln:   6,
+					Col:  4,
 				},
 			},
 			desc: "print string",
@@ -296,6 +336,34 @@ func TestScannerInvalidCode(t *testing.T) {
 			`,
 			desc: "unterminated string",
 		},
+		{
+			input: `"a \q b"`,
+			desc:  "invalid escape",
+		},
+		{
+			input: `"\u{110000}"`,
+			desc:  "invalid unicode escape",
+		},
+		{
+			input: "`raw",
+			desc:  "unterminated raw string",
+		},
+		{
+			input: "0b102",
+			desc:  "invalid binary digit",
+		},
+		{
+			input: "1__000",
+			desc:  "doubled separator",
+		},
+		{
+			input: "1_000_",
+			desc:  "trailing separator",
+		},
+		{
+			input: `"a ${b"`,
+			desc:  "unterminated interpolation",
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -304,3 +372,154 @@ func TestScannerInvalidCode(t *testing.T) {
 		})
 	}
 }
+
+func TestScannerStrings(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []any
+		desc     string
+	}{
+		{`"a\tb\n\"c\"\\"`, []any{"a\tb\n\"c\"\\"}, "escapes"},
+		{`"\u{48}\u{e9}\u{1F600}"`, []any{"Hé😀"}, "unicode escapes"},
+		{"`raw \\n ${x}\nline`", []any{"raw \\n ${x}\nline"}, "raw string"},
+		{`"\${x}"`, []any{"${x}"}, "escaped interpolation"},
+		{`"a ${b} c ${ {"d": 1} } e"`, []any{"a ", "b", " c ", "{", "d", ":", "1", "}", " e"}, "interpolation"},
+		{`"${"${x}"}"`, []any{"", "", "x", "", ""}, "nested interpolation"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			out, err := ScanTokens(tc.input)
+			if !assert.NoError(t, err) {
+				return
+			}
+			var values []any
+			for _, tok := range out[:len(out)-1] {
+				if tok.Type == token.STRING || tok.Type == token.INTERPOLATION {
+					values = append(values, tok.Literal)
+				} else {
+					values = append(values, tok.Lexeme)
+				}
+			}
+			assert.Equal(t, tc.expected, values)
+		})
+	}
+}
+
+func TestScannerUnicode(t *testing.T) {
+	out, err := ScanTokens("var ñame_1 = \"日本\"; _x\n  héllo")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	type pos struct {
+		Type   token.Type
+		Lexeme string
+		Col    int
+	}
+	var got []pos
+	for _, tok := range out {
+		got = append(got, pos{tok.Type, tok.Lexeme, tok.Col})
+	}
+	assert.Equal(t, []pos{
+		{token.VAR, "var", 1},
+		{token.IDENTIFIER, "ñame_1", 5},
+		{token.EQUAL, "=", 12},
+		{token.STRING, "\"日本\"", 14},
+		{token.SEMICOLON, ";", 18},
+		{token.IDENTIFIER, "_x", 20},
+		{token.IDENTIFIER, "héllo", 3},
+		{token.EOF, "", 8},
+	}, got)
+}
+
+func TestScannerNumbers(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected any
+	}{
+		{"42", int64(42)},
+		{"1_000_000", int64(1000000)},
+		{"0xFF_ff", int64(0xffff)},
+		{"0o755", int64(0o755)},
+		{"0b1010", int64(10)},
+		{"010", int64(10)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"9223372036854775808", new(big.Int).Lsh(big.NewInt(1), 63)},
+		{"0x1_0000_0000_0000_0000", new(big.Int).Lsh(big.NewInt(1), 64)},
+		{"3.25", 3.25},
+		{"1_000.5", 1000.5},
+		{"0.10d", big.NewRat(1, 10)},
+		{"12d", big.NewRat(12, 1)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			out, err := ScanTokens(tc.input)
+			if assert.NoError(t, err) && assert.Len(t, out, 2) {
+				assert.Equal(t, token.NUMBER, out[0].Type)
+				assert.Equal(t, tc.expected, out[0].Literal)
+			}
+		})
+	}
+}
+
+func TestScannerOperators(t *testing.T) {
+	out, err := ScanTokens("+= -= *= /= %= ++ -- ** x // => // a comment")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var got []token.Type
+	for _, tok := range out {
+		got = append(got, tok.Type)
+	}
+	assert.Equal(t, []token.Type{
+		token.PLUSEQUAL, token.MINUSEQUAL, token.STAREQUAL, token.SLASHEQUAL, token.PERCENTEQUAL,
+		token.PLUSPLUS, token.MINUSMINUS, token.STARSTAR, token.IDENTIFIER, token.SLASHSLASH, token.FATARROW, token.EOF,
+	}, got)
+}
+
+func TestScannerIntegerDivision(t *testing.T) {
+	testcases := []struct {
+		input   string
+		divides bool
+	}{
+		{"7 // 2", true},
+		{"a[0] // 2", true},
+		{"f(x) // 2", true},
+		{"x = f(x) // 2", true},
+		{"len(\"abc\") // 2", true},
+		{"print(len(\"abc\") // 2)", true},
+		{"(a + b) // 2", true},
+		{"this.n // 2", true},
+		{"7\n// a comment", false},
+		{"x = 7; // a comment", false},
+		{"if (x) // a comment", false},
+		{"while (true) // a comment", false},
+		{"for (var i = 0; i < n; i++) // a comment", false},
+		{"fun f(a, b) // a comment", false},
+		{"var f = fun (a) // a comment", false},
+		{"class A { area(w) // a comment", false},
+		{"class A { init() {} area(w) // a comment", false},
+		{"class A { area(w) { return f(w) // 2; } }", true},
+		{"class A {} f(x) // 2", true},
+		{"class A < B // a comment", false},
+		{"{ // a comment", false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.input, func(t *testing.T) {
+			out, err := ScanTokens(tc.input)
+			if !assert.NoError(t, err) {
+				return
+			}
+			divides := false
+			for _, tok := range out {
+				if tok.Type == token.SLASHSLASH {
+					divides = true
+				}
+				assert.NotEqual(t, "comment", tok.Lexeme)
+			}
+			assert.Equal(t, tc.divides, divides)
+		})
+	}
+}
@@ -6,17 +6,26 @@ type Type string
 
 const (
 	// Single-character tokens.
-	LEFTPAREN  Type = "LEFTPAREN"
-	RIGHTPAREN Type = "RIGHTPAREN"
-	LEFTBRACE  Type = "LEFTBRACE"
-	RIGHTBRACE Type = "RIGHTBRACE"
-	COMMA      Type = "COMMA"
-	DOT        Type = "DOT"
-	MINUS      Type = "MINUS"
-	PLUS       Type = "PLUS"
-	SEMICOLON  Type = "SEMICOLON"
-	SLASH      Type = "SLASH"
-	STAR       Type = "STAR"
+	LEFTPAREN    Type = "LEFTPAREN"
+	RIGHTPAREN   Type = "RIGHTPAREN"
+	LEFTBRACE    Type = "LEFTBRACE"
+	RIGHTBRACE   Type = "RIGHTBRACE"
+	LEFTBRACKET  Type = "LEFTBRACKET"
+	RIGHTBRACKET Type = "RIGHTBRACKET"
+	COMMA        Type = "COMMA"
+	DOT          Type = "DOT"
+	MINUS        Type = "MINUS"
+	PLUS         Type = "PLUS"
+	SEMICOLON    Type = "SEMICOLON"
+	COLON        Type = "COLON"
+	QUESTION     Type = "QUESTION"
+	SLASH        Type = "SLASH"
+	STAR         Type = "STAR"
+	PERCENT      Type = "PERCENT"
+	AMPERSAND    Type = "AMPERSAND"
+	PIPE         Type = "PIPE"
+	CARET        Type = "CARET"
+	TILDE        Type = "TILDE"
 
 	// One or two character tokens.
 	BANG         Type = "BANG"
@@ -29,11 +38,28 @@ const (
 	GREATEREQUAL Type = "GREATEREQUAL"
 	LESS         Type = "LESS"
 	LESSEQUAL    Type = "LESSEQUAL"
+	STARSTAR     Type = "STARSTAR"
+	// SLASHSLASH is integer division. "//" only divides right after an operand, elsewhere it starts a comment.
+	SLASHSLASH     Type = "SLASHSLASH"
+	LESSLESS       Type = "LESSLESS"
+	GREATERGREATER Type = "GREATERGREATER"
+	PLUSEQUAL      Type = "PLUSEQUAL"
+	MINUSEQUAL     Type = "MINUSEQUAL"
+	STAREQUAL      Type = "STAREQUAL"
+	SLASHEQUAL     Type = "SLASHEQUAL"
+	PERCENTEQUAL   Type = "PERCENTEQUAL"
+	// FATARROW separates the case of a match arm from its body.
+	FATARROW Type = "FATARROW"
+	// QUESTIONQUESTION falls back to its right operand when the left one is nil.
+	QUESTIONQUESTION Type = "QUESTIONQUESTION"
 
 	// Literals.
 	IDENTIFIER Type = "IDENTIFIER"
 	STRING     Type = "STRING"
-	NUMBER     Type = "NUMBER"
+	// INTERPOLATION is the part of a string up to an interpolated expression, e.g. `"a ${`.
+	// the expression follows, and the string resumes with either another INTERPOLATION, or a STRING for its end.
+	INTERPOLATION Type = "INTERPOLATION"
+	NUMBER        Type = "NUMBER"
 
 	AND      Type = "AND"
 	CLASS    Type = "CLASS"
@@ -53,9 +79,22 @@ const (
 	WHILE    Type = "WHILE"
 	BREAK    Type = "BREAK"
 	CONTINUE Type = "CONTINUE"
+	IMPORT   Type = "IMPORT"
+	AS       Type = "AS"
+	THROW    Type = "THROW"
+	TRY      Type = "TRY"
+	CATCH    Type = "CATCH"
+	FINALLY  Type = "FINALLY"
+	MATCH    Type = "MATCH"
+	CASE     Type = "CASE"
+	IN       Type = "IN"
+	YIELD    Type = "YIELD"
 
 	EOF Type = "EOF"
 
+	// ILLEGAL is assigned to the characters that could not be scanned, to report them in errors.
+	ILLEGAL Type = "ILLEGAL"
+
 	// IGNORE is assigned to tokens that are not needed for the interpreter
 	// e.g. whitespace, comments...
 	IGNORE Type = "IGNORE"
@@ -67,6 +106,10 @@ type Token struct {
 	Literal any
 	// Line Number
 	Ln int
+	// Column Number, starting from 1
+	Col int
+	// File the token was scanned from. empty for sources that are not files, e.g. the prompt.
+	File string
 }
 
 func (t Token) String() string {
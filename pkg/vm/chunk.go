@@ -0,0 +1,198 @@
+package vm
+
+import "github.com/taehioum/glox/pkg/token"
+
+type OpCode byte
+
+const (
+	OpConstant OpCode = iota // u16 constant index
+	OpNil
+	OpTrue
+	OpFalse
+	OpPop
+	OpDup          // duplicates the top of the stack
+	OpDup2         // duplicates the top two values of the stack, e.g. a list and an index
+	OpTuck         // u8 depth, copies the top of the stack below the depth values under it
+	OpGetLocal     // u8 stack slot
+	OpSetLocal     // u8 stack slot
+	OpGetGlobal    // u16 constant index of the name
+	OpDefineGlobal // u16 constant index of the name
+	OpSetGlobal    // u16 constant index of the name
+	OpGetUpvalue   // u8 upvalue index
+	OpSetUpvalue   // u8 upvalue index
+	OpGetProperty  // u16 constant index of the name
+	OpSetProperty  // u16 constant index of the name
+	OpGetSuper     // u16 constant index of the name
+	OpEqual
+	OpNotEqual
+	OpGreater
+	OpGreaterEqual
+	OpLess
+	OpLessEqual
+	OpAdd
+	OpSubtract
+	OpMultiply
+	OpDivide
+	OpFloorDivide
+	OpModulo
+	OpPower
+	OpBitAnd
+	OpBitOr
+	OpBitXor
+	OpShiftLeft
+	OpShiftRight
+	OpNot
+	OpNegate
+	OpBitNot
+	OpIncrement   // adds 1 to the number on top of the stack, for ++
+	OpDecrement   // subtracts 1 from the number on top of the stack, for --
+	OpJump        // u16 forward offset
+	OpJumpIfFalse // u16 forward offset
+	OpLoop        // u16 backward offset
+	OpCall        // u8 argument count
+	OpClosure     // u16 constant index of the function, followed by (isLocal, index) byte pairs for each upvalue
+	OpCloseUpvalue
+	OpReturn
+	OpClass    // u16 constant index of the name
+	OpInherit  // superclass and subclass on the stack
+	OpMethod   // u16 constant index of the name
+	OpList     // u16 element count
+	OpGetIndex // list and index on the stack
+	OpSetIndex // list, index and value on the stack
+	OpMap      // u16 entry count
+	OpConcat   // u16 value count, formats and concatenates the values of a string interpolation
+	OpThrow    // throws the value on top of the stack
+	OpTry      // u16 forward offset of the handler, which runs with the error on the stack if the body throws
+	OpPopHandler
+	OpCatch      // replaces the error on top of the stack with the value the catch block sees
+	OpRethrow    // throws the error on top of the stack again, after a finally block ran
+	OpMatchList  // u16 length, replaces the value on top of the stack with whether it is a list of that length
+	OpMatchMap   // replaces the value on top of the stack with whether it is a map
+	OpHasKey     // map and key on the stack, replaced with whether the map has the key
+	OpNoMatch    // fails with the error that no arm of a match matches the value on top of the stack
+	OpIter       // replaces the value on top of the stack with an iterator over it, calling its iter() method if it has one
+	OpIterCheck  // checks that the value on top of the stack, as returned by an iter() method, is an iterator
+	OpIterNext   // replaces the iterator on top of the stack with its next value, or done
+	OpJumpIfDone // u16 forward offset, taken if the value on top of the stack is done
+	OpIterClose  // replaces the iterator on top of the stack with nil, once it is closed
+	OpYield      // suspends the generator running, handing over the value on top of the stack to whoever resumed it
+	OpImport     // u16 path, replaces the name on top of the stack with the module, run above it unless it was imported already
+	OpImported   // pops the result of running the module below it, which is then imported
+)
+
+var opNames = map[OpCode]string{
+	OpConstant:     "OP_CONSTANT",
+	OpNil:          "OP_NIL",
+	OpTrue:         "OP_TRUE",
+	OpFalse:        "OP_FALSE",
+	OpPop:          "OP_POP",
+	OpDup:          "OP_DUP",
+	OpDup2:         "OP_DUP2",
+	OpTuck:         "OP_TUCK",
+	OpGetLocal:     "OP_GET_LOCAL",
+	OpSetLocal:     "OP_SET_LOCAL",
+	OpGetGlobal:    "OP_GET_GLOBAL",
+	OpDefineGlobal: "OP_DEFINE_GLOBAL",
+	OpSetGlobal:    "OP_SET_GLOBAL",
+	OpGetUpvalue:   "OP_GET_UPVALUE",
+	OpSetUpvalue:   "OP_SET_UPVALUE",
+	OpGetProperty:  "OP_GET_PROPERTY",
+	OpSetProperty:  "OP_SET_PROPERTY",
+	OpGetSuper:     "OP_GET_SUPER",
+	OpEqual:        "OP_EQUAL",
+	OpNotEqual:     "OP_NOT_EQUAL",
+	OpGreater:      "OP_GREATER",
+	OpGreaterEqual: "OP_GREATER_EQUAL",
+	OpLess:         "OP_LESS",
+	OpLessEqual:    "OP_LESS_EQUAL",
+	OpAdd:          "OP_ADD",
+	OpSubtract:     "OP_SUBTRACT",
+	OpMultiply:     "OP_MULTIPLY",
+	OpDivide:       "OP_DIVIDE",
+	OpFloorDivide:  "OP_FLOOR_DIVIDE",
+	OpModulo:       "OP_MODULO",
+	OpPower:        "OP_POWER",
+	OpBitAnd:       "OP_BIT_AND",
+	OpBitOr:        "OP_BIT_OR",
+	OpBitXor:       "OP_BIT_XOR",
+	OpShiftLeft:    "OP_SHIFT_LEFT",
+	OpShiftRight:   "OP_SHIFT_RIGHT",
+	OpNot:          "OP_NOT",
+	OpNegate:       "OP_NEGATE",
+	OpBitNot:       "OP_BIT_NOT",
+	OpIncrement:    "OP_INCREMENT",
+	OpDecrement:    "OP_DECREMENT",
+	OpJump:         "OP_JUMP",
+	OpJumpIfFalse:  "OP_JUMP_IF_FALSE",
+	OpLoop:         "OP_LOOP",
+	OpCall:         "OP_CALL",
+	OpClosure:      "OP_CLOSURE",
+	OpCloseUpvalue: "OP_CLOSE_UPVALUE",
+	OpReturn:       "OP_RETURN",
+	OpClass:        "OP_CLASS",
+	OpInherit:      "OP_INHERIT",
+	OpMethod:       "OP_METHOD",
+	OpList:         "OP_LIST",
+	OpGetIndex:     "OP_GET_INDEX",
+	OpSetIndex:     "OP_SET_INDEX",
+	OpMap:          "OP_MAP",
+	OpConcat:       "OP_CONCAT",
+	OpThrow:        "OP_THROW",
+	OpTry:          "OP_TRY",
+	OpPopHandler:   "OP_POP_HANDLER",
+	OpCatch:        "OP_CATCH",
+	OpRethrow:      "OP_RETHROW",
+	OpMatchList:    "OP_MATCH_LIST",
+	OpMatchMap:     "OP_MATCH_MAP",
+	OpHasKey:       "OP_HAS_KEY",
+	OpNoMatch:      "OP_NO_MATCH",
+	OpIter:         "OP_ITER",
+	OpIterCheck:    "OP_ITER_CHECK",
+	OpIterNext:     "OP_ITER_NEXT",
+	OpJumpIfDone:   "OP_JUMP_IF_DONE",
+	OpIterClose:    "OP_ITER_CLOSE",
+	OpYield:        "OP_YIELD",
+	OpImport:       "OP_IMPORT",
+	OpImported:     "OP_IMPORTED",
+}
+
+func (op OpCode) String() string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	return "OP_UNKNOWN"
+}
+
+// Chunk is a sequence of bytecode, along with the constants it refers to.
+type Chunk struct {
+	Code      []byte
+	Constants []Value
+	// Tokens are the source tokens the code was compiled from.
+	// consecutive bytes mostly come from the same token, so each token is stored once.
+	Tokens []token.Token
+	// positions[i] is the index in Tokens of the token Code[i] was compiled from
+	positions []int
+}
+
+func (c *Chunk) write(b byte, tok token.Token) {
+	if n := len(c.Tokens); n == 0 || !samePosition(c.Tokens[n-1], tok) {
+		c.Tokens = append(c.Tokens, tok)
+	}
+	c.Code = append(c.Code, b)
+	c.positions = append(c.positions, len(c.Tokens)-1)
+}
+
+// TokenAt returns the source token the byte at the offset was compiled from.
+func (c *Chunk) TokenAt(offset int) token.Token {
+	return c.Tokens[c.positions[offset]]
+}
+
+func samePosition(a, b token.Token) bool {
+	return a.File == b.File && a.Ln == b.Ln && a.Col == b.Col && a.Lexeme == b.Lexeme
+}
+
+// addConstant appends the value to the constant pool, and returns its index.
+func (c *Chunk) addConstant(v Value) int {
+	c.Constants = append(c.Constants, v)
+	return len(c.Constants) - 1
+}
@@ -0,0 +1,1298 @@
+package vm
+
+import (
+	"log/slog"
+	"math"
+	"math/big"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+type functionType int
+
+const (
+	functionTypeScript functionType = iota
+	functionTypeFunction
+	functionTypeMethod
+	functionTypeInitializer
+)
+
+type local struct {
+	name string
+	// -1 while the variable is declared, but not yet initialized
+	depth      int
+	isCaptured bool
+}
+
+type upvalueRef struct {
+	index   uint8
+	isLocal bool
+}
+
+type loop struct {
+	start      int
+	scopeDepth int
+	// number of try statements entered before the loop, whose finally blocks a break or continue doesn't run
+	tries int
+	// offsets of the jumps emitted by break statements, patched when the loop ends
+	breaks []int
+}
+
+// compiler compiles a single function body. nested functions get their own compiler.
+type compiler struct {
+	enclosing *compiler
+	fn        *Function
+	typ       functionType
+
+	locals     []local
+	upvalues   []upvalueRef
+	scopeDepth int
+	loops      []*loop
+	// finally blocks of the try statements whose bodies are being compiled, innermost last, as functions compiling them.
+	// each has a handler installed while its body runs. the blocks are nil for try statements without one.
+	tries []func() error
+	// number of values the enclosing expressions left on the stack above the locals,
+	// e.g. the left operand of a binary expression while its right one is compiled.
+	temps int
+
+	// the last token seen, recorded along with each emitted byte
+	tok token.Token
+}
+
+// Compile compiles a resolved program into the bytecode of a top-level script function.
+func Compile(stmts []ast.Stmt) (*Function, error) {
+	c := newCompiler(nil, functionTypeScript, "")
+	for _, stmt := range stmts {
+		if err := stmt.Accept(c); err != nil {
+			return nil, err
+		}
+	}
+	return c.end(), nil
+}
+
+func newCompiler(enclosing *compiler, typ functionType, name string) *compiler {
+	c := &compiler{
+		enclosing: enclosing,
+		fn:        &Function{Name: name},
+		typ:       typ,
+	}
+	if enclosing != nil {
+		c.tok = enclosing.tok
+	}
+
+	// slot zero holds the callee, or 'this' in methods.
+	slotZero := ""
+	if typ == functionTypeMethod || typ == functionTypeInitializer {
+		slotZero = "this"
+	}
+	c.locals = append(c.locals, local{name: slotZero, depth: 0})
+	return c
+}
+
+func (c *compiler) end() *Function {
+	c.emitReturn()
+	c.fn.UpvalueCount = len(c.upvalues)
+	slog.Debug("compiled", slog.String("chunk", Disassemble(&c.fn.Chunk, c.fn.String())))
+	return c.fn
+}
+
+func (c *compiler) errorAt(tok token.Token, msg string) error {
+	return diagnostic.NewCompileError(tok, "%s", msg)
+}
+
+func (c *compiler) at(tok token.Token) {
+	c.tok = tok
+}
+
+func (c *compiler) emit(bytes ...byte) {
+	for _, b := range bytes {
+		c.fn.Chunk.write(b, c.tok)
+	}
+}
+
+func (c *compiler) emitOp(op OpCode, operands ...byte) {
+	c.emit(byte(op))
+	c.emit(operands...)
+}
+
+func (c *compiler) emitShortOp(op OpCode, operand int) {
+	c.emitOp(op, byte(operand>>8), byte(operand))
+}
+
+func (c *compiler) emitReturn() {
+	c.emitImplicitValue()
+	c.emitOp(OpReturn)
+}
+
+// emitImplicitValue pushes the value returned by a function that doesn't return one: 'this' in initializers, nil otherwise.
+func (c *compiler) emitImplicitValue() {
+	if c.typ == functionTypeInitializer {
+		c.emitOp(OpGetLocal, 0)
+	} else {
+		c.emitOp(OpNil)
+	}
+}
+
+func (c *compiler) makeConstant(v Value) (int, error) {
+	idx := c.fn.Chunk.addConstant(v)
+	if idx > math.MaxUint16 {
+		return 0, c.errorAt(c.tok, "too many constants in one chunk")
+	}
+	return idx, nil
+}
+
+func (c *compiler) emitConstant(v Value) error {
+	idx, err := c.makeConstant(v)
+	if err != nil {
+		return err
+	}
+	c.emitShortOp(OpConstant, idx)
+	return nil
+}
+
+// emitNameOp emits an instruction whose operand is a name in the constant pool.
+func (c *compiler) emitNameOp(op OpCode, name string) error {
+	idx, err := c.makeConstant(objValue(name))
+	if err != nil {
+		return err
+	}
+	c.emitShortOp(op, idx)
+	return nil
+}
+
+// emitJump emits a jump with a placeholder offset, and returns the offset to patch.
+func (c *compiler) emitJump(op OpCode) int {
+	c.emitOp(op, 0xff, 0xff)
+	return len(c.fn.Chunk.Code) - 2
+}
+
+func (c *compiler) patchJump(offset int) error {
+	jump := len(c.fn.Chunk.Code) - offset - 2
+	if jump > math.MaxUint16 {
+		return c.errorAt(c.tok, "too much code to jump over")
+	}
+	c.fn.Chunk.Code[offset] = byte(jump >> 8)
+	c.fn.Chunk.Code[offset+1] = byte(jump)
+	return nil
+}
+
+func (c *compiler) emitLoop(start int) error {
+	jump := len(c.fn.Chunk.Code) - start + 3
+	if jump > math.MaxUint16 {
+		return c.errorAt(c.tok, "loop body too large")
+	}
+	c.emitShortOp(OpLoop, jump)
+	return nil
+}
+
+func (c *compiler) beginScope() {
+	c.scopeDepth++
+}
+
+func (c *compiler) endScope() {
+	c.scopeDepth--
+	for len(c.locals) > 0 && c.locals[len(c.locals)-1].depth > c.scopeDepth {
+		c.popLocal(c.locals[len(c.locals)-1])
+		c.locals = c.locals[:len(c.locals)-1]
+	}
+}
+
+// popLocal emits the instruction discarding a local going out of scope.
+func (c *compiler) popLocal(l local) {
+	if l.isCaptured {
+		c.emitOp(OpCloseUpvalue)
+	} else {
+		c.emitOp(OpPop)
+	}
+}
+
+func (c *compiler) addLocal(name token.Token) error {
+	if len(c.locals) > math.MaxUint8 {
+		return c.errorAt(name, "too many local variables in function")
+	}
+	c.locals = append(c.locals, local{name: name.Lexeme, depth: -1})
+	return nil
+}
+
+// addHiddenLocal declares an initialized local that no name resolves to, for a value kept on the stack, and returns its slot.
+func (c *compiler) addHiddenLocal(tok token.Token) (int, error) {
+	tok.Lexeme = ""
+	if err := c.addLocal(tok); err != nil {
+		return 0, err
+	}
+	c.markInitialized()
+	return len(c.locals) - 1, nil
+}
+
+// dropHiddenLocal ends the scope of a hidden local, without popping it, after code that doesn't fall through.
+func (c *compiler) dropHiddenLocal() {
+	c.locals = c.locals[:len(c.locals)-1]
+	c.scopeDepth--
+}
+
+func (c *compiler) markInitialized() {
+	c.locals[len(c.locals)-1].depth = c.scopeDepth
+}
+
+func (c *compiler) resolveLocal(name string) int {
+	for i := len(c.locals) - 1; i >= 0; i-- {
+		if c.locals[i].name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *compiler) resolveUpvalue(name token.Token) (int, error) {
+	if c.enclosing == nil {
+		return -1, nil
+	}
+
+	if l := c.enclosing.resolveLocal(name.Lexeme); l != -1 {
+		c.enclosing.locals[l].isCaptured = true
+		return c.addUpvalue(name, uint8(l), true)
+	}
+
+	up, err := c.enclosing.resolveUpvalue(name)
+	if err != nil || up == -1 {
+		return up, err
+	}
+	return c.addUpvalue(name, uint8(up), false)
+}
+
+func (c *compiler) addUpvalue(name token.Token, index uint8, isLocal bool) (int, error) {
+	for i, up := range c.upvalues {
+		if up.index == index && up.isLocal == isLocal {
+			return i, nil
+		}
+	}
+	if len(c.upvalues) > math.MaxUint8 {
+		return 0, c.errorAt(name, "too many closure variables in function")
+	}
+	c.upvalues = append(c.upvalues, upvalueRef{index: index, isLocal: isLocal})
+	return len(c.upvalues) - 1, nil
+}
+
+// getVariable pushes the value of the variable.
+func (c *compiler) getVariable(name token.Token) error {
+	if l := c.resolveLocal(name.Lexeme); l != -1 {
+		c.emitOp(OpGetLocal, byte(l))
+		return nil
+	}
+	up, err := c.resolveUpvalue(name)
+	if err != nil {
+		return err
+	}
+	if up != -1 {
+		c.emitOp(OpGetUpvalue, byte(up))
+		return nil
+	}
+	return c.emitNameOp(OpGetGlobal, name.Lexeme)
+}
+
+// setVariable assigns the value on top of the stack to the variable, leaving it on the stack.
+func (c *compiler) setVariable(name token.Token) error {
+	if l := c.resolveLocal(name.Lexeme); l != -1 {
+		c.emitOp(OpSetLocal, byte(l))
+		return nil
+	}
+	up, err := c.resolveUpvalue(name)
+	if err != nil {
+		return err
+	}
+	if up != -1 {
+		c.emitOp(OpSetUpvalue, byte(up))
+		return nil
+	}
+	return c.emitNameOp(OpSetGlobal, name.Lexeme)
+}
+
+// defineVariable binds the value on top of the stack to a new variable in the current scope.
+// locals simply stay on the stack.
+func (c *compiler) defineVariable(name token.Token) error {
+	if c.scopeDepth > 0 {
+		c.markInitialized()
+		return nil
+	}
+	return c.emitNameOp(OpDefineGlobal, name.Lexeme)
+}
+
+func (c *compiler) declareVariable(name token.Token) error {
+	if c.scopeDepth == 0 {
+		return nil
+	}
+	return c.addLocal(name)
+}
+
+func (c *compiler) expr(e ast.Expr) error {
+	_, err := e.Accept(c)
+	return err
+}
+
+// function compiles the lambda in a nested compiler, and emits the closure creation.
+func (c *compiler) function(l ast.Lambda, typ functionType) error {
+	fc := newCompiler(c, typ, l.Name.Lexeme)
+	fc.at(l.Name)
+	fc.beginScope()
+	for _, param := range l.Params {
+		if err := fc.addLocal(param); err != nil {
+			return err
+		}
+		fc.markInitialized()
+	}
+	fc.fn.Arity = len(l.Params)
+	fc.fn.Generator = l.Generator
+
+	for _, stmt := range l.Body {
+		if err := stmt.Accept(fc); err != nil {
+			return err
+		}
+	}
+	fn := fc.end()
+
+	idx, err := c.makeConstant(objValue(fn))
+	if err != nil {
+		return err
+	}
+	c.emitShortOp(OpClosure, idx)
+	for _, up := range fc.upvalues {
+		isLocal := byte(0)
+		if up.isLocal {
+			isLocal = 1
+		}
+		c.emit(isLocal, up.index)
+	}
+	return nil
+}
+
+// VisitAssignment implements ast.ExpressionVisitor.
+func (c *compiler) VisitAssignment(e ast.Assignment) (any, error) {
+	if err := c.expr(e.Value); err != nil {
+		return nil, err
+	}
+	c.at(e.Name)
+	return nil, c.setVariable(e.Name)
+}
+
+// VisitBinary implements ast.ExpressionVisitor.
+func (c *compiler) VisitBinary(e ast.Binary) (any, error) {
+	if err := c.expr(e.Left); err != nil {
+		return nil, err
+	}
+	c.temps++
+	if err := c.expr(e.Right); err != nil {
+		return nil, err
+	}
+	c.temps--
+
+	c.at(e.Operator)
+	switch e.Operator.Type {
+	case token.PLUS:
+		c.emitOp(OpAdd)
+	case token.MINUS:
+		c.emitOp(OpSubtract)
+	case token.STAR:
+		c.emitOp(OpMultiply)
+	case token.SLASH:
+		c.emitOp(OpDivide)
+	case token.GREATER:
+		c.emitOp(OpGreater)
+	case token.GREATEREQUAL:
+		c.emitOp(OpGreaterEqual)
+	case token.LESS:
+		c.emitOp(OpLess)
+	case token.LESSEQUAL:
+		c.emitOp(OpLessEqual)
+	case token.EQUALEQUAL:
+		c.emitOp(OpEqual)
+	case token.BANGEQUAL:
+		c.emitOp(OpNotEqual)
+	case token.SLASHSLASH:
+		c.emitOp(OpFloorDivide)
+	case token.PERCENT:
+		c.emitOp(OpModulo)
+	case token.STARSTAR:
+		c.emitOp(OpPower)
+	case token.AMPERSAND:
+		c.emitOp(OpBitAnd)
+	case token.PIPE:
+		c.emitOp(OpBitOr)
+	case token.CARET:
+		c.emitOp(OpBitXor)
+	case token.LESSLESS:
+		c.emitOp(OpShiftLeft)
+	case token.GREATERGREATER:
+		c.emitOp(OpShiftRight)
+	default:
+		return nil, c.errorAt(e.Operator, "unknown binary operator")
+	}
+	return nil, nil
+}
+
+// VisitGrouping implements ast.ExpressionVisitor.
+func (c *compiler) VisitGrouping(e ast.Grouping) (any, error) {
+	return nil, c.expr(e.Expr)
+}
+
+// VisitLiteral implements ast.ExpressionVisitor.
+func (c *compiler) VisitLiteral(e ast.Literal) (any, error) {
+	switch v := e.Value.(type) {
+	case nil:
+		c.emitOp(OpNil)
+	case bool:
+		if v {
+			c.emitOp(OpTrue)
+		} else {
+			c.emitOp(OpFalse)
+		}
+	case float64:
+		return nil, c.emitConstant(floatValue(v))
+	case int64:
+		return nil, c.emitConstant(intValue(v))
+	case *big.Int:
+		return nil, c.emitConstant(objValue(v))
+	case *big.Rat:
+		return nil, c.emitConstant(objValue(Decimal{v}))
+	case string:
+		return nil, c.emitConstant(objValue(v))
+	default:
+		return nil, diagnostic.NewCompileError(c.tok, "unsupported literal %v", v)
+	}
+	return nil, nil
+}
+
+// VisitUnary implements ast.ExpressionVisitor.
+func (c *compiler) VisitUnary(e ast.Unary) (any, error) {
+	if err := c.expr(e.Right); err != nil {
+		return nil, err
+	}
+
+	c.at(e.Operator)
+	switch e.Operator.Type {
+	case token.MINUS:
+		c.emitOp(OpNegate)
+	case token.BANG:
+		c.emitOp(OpNot)
+	case token.TILDE:
+		c.emitOp(OpBitNot)
+	default:
+		return nil, c.errorAt(e.Operator, "unknown unary operator")
+	}
+	return nil, nil
+}
+
+// VisitVariable implements ast.ExpressionVisitor.
+func (c *compiler) VisitVariable(e ast.Variable) (any, error) {
+	c.at(e.Name)
+	return nil, c.getVariable(e.Name)
+}
+
+// VisitLogical implements ast.ExpressionVisitor.
+func (c *compiler) VisitLogical(e ast.Logical) (any, error) {
+	if err := c.expr(e.Left); err != nil {
+		return nil, err
+	}
+
+	c.at(e.Operator)
+	if e.Operator.Type == token.QUESTIONQUESTION {
+		// compare a copy of the left operand with nil, and keep the left operand unless it is.
+		c.emitOp(OpDup)
+		c.emitOp(OpNil)
+		c.emitOp(OpEqual)
+		keepJump := c.emitJump(OpJumpIfFalse)
+		c.emitOp(OpPop)
+		c.emitOp(OpPop)
+		if err := c.expr(e.Right); err != nil {
+			return nil, err
+		}
+		endJump := c.emitJump(OpJump)
+		if err := c.patchJump(keepJump); err != nil {
+			return nil, err
+		}
+		c.emitOp(OpPop)
+		return nil, c.patchJump(endJump)
+	}
+	if e.Operator.Type == token.OR {
+		// skip the right operand if the left one is truthy
+		elseJump := c.emitJump(OpJumpIfFalse)
+		endJump := c.emitJump(OpJump)
+		if err := c.patchJump(elseJump); err != nil {
+			return nil, err
+		}
+		c.emitOp(OpPop)
+		if err := c.expr(e.Right); err != nil {
+			return nil, err
+		}
+		return nil, c.patchJump(endJump)
+	}
+
+	// skip the right operand if the left one is falsy
+	endJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := c.expr(e.Right); err != nil {
+		return nil, err
+	}
+	return nil, c.patchJump(endJump)
+}
+
+// VisitConditional implements ast.ExpressionVisitor.
+func (c *compiler) VisitConditional(e ast.Conditional) (any, error) {
+	if err := c.expr(e.Condition); err != nil {
+		return nil, err
+	}
+
+	c.at(e.Question)
+	elseJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := c.expr(e.Then); err != nil {
+		return nil, err
+	}
+	endJump := c.emitJump(OpJump)
+	if err := c.patchJump(elseJump); err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPop)
+	if err := c.expr(e.Else); err != nil {
+		return nil, err
+	}
+	return nil, c.patchJump(endJump)
+}
+
+// VisitPostUnary implements ast.ExpressionVisitor.
+func (c *compiler) VisitPostUnary(e ast.PostUnary) (any, error) {
+	// the old value is copied below the object and the index of the target, if any,
+	// so that it is left on the stack once the new value is assigned and popped.
+	var operands byte
+	switch e.Left.(type) {
+	case ast.Get:
+		operands = 1
+	case ast.Index:
+		operands = 2
+	}
+	err := c.update(e.Left, func() error {
+		c.at(e.Operator)
+		c.emitOp(OpTuck, operands)
+		return c.increment(e.Operator)
+	})
+	if err != nil {
+		return nil, err
+	}
+	c.emitOp(OpPop)
+	return nil, nil
+}
+
+// VisitPreUnary implements ast.ExpressionVisitor.
+func (c *compiler) VisitPreUnary(e ast.PreUnary) (any, error) {
+	return nil, c.update(e.Right, func() error {
+		c.at(e.Operator)
+		return c.increment(e.Operator)
+	})
+}
+
+// VisitCompound implements ast.ExpressionVisitor.
+func (c *compiler) VisitCompound(e ast.Compound) (any, error) {
+	var op OpCode
+	switch e.Operator.Type {
+	case token.PLUSEQUAL:
+		op = OpAdd
+	case token.MINUSEQUAL:
+		op = OpSubtract
+	case token.STAREQUAL:
+		op = OpMultiply
+	case token.SLASHEQUAL:
+		op = OpDivide
+	case token.PERCENTEQUAL:
+		op = OpModulo
+	default:
+		return nil, c.errorAt(e.Operator, "unknown compound assignment operator")
+	}
+	return nil, c.update(e.Target, func() error {
+		if err := c.expr(e.Value); err != nil {
+			return err
+		}
+		c.at(e.Operator)
+		c.emitOp(op)
+		return nil
+	})
+}
+
+// increment adds 1 to the number on top of the stack for ++, and subtracts it for --.
+func (c *compiler) increment(operator token.Token) error {
+	if operator.Type == token.PLUSPLUS {
+		c.emitOp(OpIncrement)
+	} else {
+		c.emitOp(OpDecrement)
+	}
+	return nil
+}
+
+// update assigns to the target, a variable, a property or an index, the value that emit computes from its old value.
+// the object and the index of the target are evaluated once, and duplicated to both get and set it.
+func (c *compiler) update(target ast.Expr, emit func() error) error {
+	switch t := target.(type) {
+	case ast.Variable:
+		c.at(t.Name)
+		if err := c.getVariable(t.Name); err != nil {
+			return err
+		}
+		c.temps++
+		if err := emit(); err != nil {
+			return err
+		}
+		c.temps--
+		c.at(t.Name)
+		return c.setVariable(t.Name)
+	case ast.Get:
+		if err := c.expr(t.Object); err != nil {
+			return err
+		}
+		c.at(t.Name)
+		c.emitOp(OpDup)
+		if err := c.emitNameOp(OpGetProperty, t.Name.Lexeme); err != nil {
+			return err
+		}
+		c.temps += 2
+		if err := emit(); err != nil {
+			return err
+		}
+		c.temps -= 2
+		c.at(t.Name)
+		return c.emitNameOp(OpSetProperty, t.Name.Lexeme)
+	case ast.Index:
+		if err := c.expr(t.Object); err != nil {
+			return err
+		}
+		c.temps++
+		if err := c.expr(t.Index); err != nil {
+			return err
+		}
+		c.at(t.Bracket)
+		c.emitOp(OpDup2)
+		c.emitOp(OpGetIndex)
+		c.temps += 3
+		if err := emit(); err != nil {
+			return err
+		}
+		c.temps -= 4
+		c.at(t.Bracket)
+		c.emitOp(OpSetIndex)
+		return nil
+	default:
+		return c.errorAt(c.tok, "invalid assignment target")
+	}
+}
+
+// VisitCall implements ast.ExpressionVisitor.
+func (c *compiler) VisitCall(e ast.Call) (any, error) {
+	if err := c.expr(e.Callee); err != nil {
+		return nil, err
+	}
+	c.temps++
+	for _, arg := range e.Args {
+		if err := c.expr(arg); err != nil {
+			return nil, err
+		}
+		c.temps++
+	}
+	c.temps -= len(e.Args) + 1
+
+	c.at(e.Paren)
+	c.emitOp(OpCall, byte(len(e.Args)))
+	return nil, nil
+}
+
+// VisitLambda implements ast.ExpressionVisitor.
+func (c *compiler) VisitLambda(e ast.Lambda) (any, error) {
+	return nil, c.function(e, functionTypeFunction)
+}
+
+// VisitGet implements ast.ExpressionVisitor.
+func (c *compiler) VisitGet(e ast.Get) (any, error) {
+	if err := c.expr(e.Object); err != nil {
+		return nil, err
+	}
+	c.at(e.Name)
+	return nil, c.emitNameOp(OpGetProperty, e.Name.Lexeme)
+}
+
+// VisitSet implements ast.ExpressionVisitor.
+func (c *compiler) VisitSet(e ast.Set) (any, error) {
+	if err := c.expr(e.Object); err != nil {
+		return nil, err
+	}
+	c.temps++
+	if err := c.expr(e.Value); err != nil {
+		return nil, err
+	}
+	c.temps--
+	c.at(e.Name)
+	return nil, c.emitNameOp(OpSetProperty, e.Name.Lexeme)
+}
+
+// VisitThis implements ast.ExpressionVisitor.
+func (c *compiler) VisitThis(e ast.This) (any, error) {
+	c.at(e.Keyword)
+	return nil, c.getVariable(e.Keyword)
+}
+
+// VisitSuper implements ast.ExpressionVisitor.
+func (c *compiler) VisitSuper(e ast.Super) (any, error) {
+	c.at(e.Keyword)
+	if err := c.getVariable(token.Token{Type: token.THIS, Lexeme: "this", Ln: e.Keyword.Ln, Col: e.Keyword.Col, File: e.Keyword.File}); err != nil {
+		return nil, err
+	}
+	if err := c.getVariable(e.Keyword); err != nil {
+		return nil, err
+	}
+	return nil, c.emitNameOp(OpGetSuper, e.Method.Lexeme)
+}
+
+// VisitDeclaration implements ast.StatementVistior.
+func (c *compiler) VisitDeclaration(stmt ast.Declaration) error {
+	c.at(stmt.Name)
+	if err := c.declareVariable(stmt.Name); err != nil {
+		return err
+	}
+
+	if stmt.Initializer == nil {
+		c.emitOp(OpNil)
+		return c.defineVariable(stmt.Name)
+	}
+
+	if _, ok := stmt.Initializer.(ast.Lambda); ok && c.scopeDepth > 0 {
+		// to allow recursive local functions, the variable is initialized before its body
+		c.markInitialized()
+	}
+	if err := c.expr(stmt.Initializer); err != nil {
+		return err
+	}
+	return c.defineVariable(stmt.Name)
+}
+
+// VisitBlock implements ast.StatementVistior.
+func (c *compiler) VisitBlock(stmt ast.Block) error {
+	c.beginScope()
+	for _, s := range stmt.Stmts {
+		if err := s.Accept(c); err != nil {
+			return err
+		}
+	}
+	c.endScope()
+	return nil
+}
+
+// VisitIf implements ast.StatementVistior.
+func (c *compiler) VisitIf(stmt ast.If) error {
+	if err := c.expr(stmt.Cond); err != nil {
+		return err
+	}
+
+	thenJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := stmt.Then.Accept(c); err != nil {
+		return err
+	}
+	elseJump := c.emitJump(OpJump)
+
+	if err := c.patchJump(thenJump); err != nil {
+		return err
+	}
+	c.emitOp(OpPop)
+	if stmt.Else != nil {
+		if err := stmt.Else.Accept(c); err != nil {
+			return err
+		}
+	}
+	return c.patchJump(elseJump)
+}
+
+// VisitWhile implements ast.StatementVistior.
+func (c *compiler) VisitWhile(stmt ast.While) error {
+	l := &loop{
+		start:      len(c.fn.Chunk.Code),
+		scopeDepth: c.scopeDepth,
+		tries:      len(c.tries),
+	}
+	c.loops = append(c.loops, l)
+	defer func() {
+		c.loops = c.loops[:len(c.loops)-1]
+	}()
+
+	if err := c.expr(stmt.Cond); err != nil {
+		return err
+	}
+	exitJump := c.emitJump(OpJumpIfFalse)
+	c.emitOp(OpPop)
+	if err := stmt.Body.Accept(c); err != nil {
+		return err
+	}
+	if err := c.emitLoop(l.start); err != nil {
+		return err
+	}
+
+	if err := c.patchJump(exitJump); err != nil {
+		return err
+	}
+	c.emitOp(OpPop)
+
+	// break statements jump past the condition's pop, as they leave nothing on the stack
+	for _, b := range l.breaks {
+		if err := c.patchJump(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discardLoopLocals emits pops for the locals declared inside the innermost loop,
+// without removing them from the compiler's scope.
+func (c *compiler) discardLoopLocals(l *loop) {
+	for i := len(c.locals) - 1; i >= 0 && c.locals[i].depth > l.scopeDepth; i-- {
+		c.popLocal(c.locals[i])
+	}
+}
+
+// VisitBreak implements ast.StatementVistior.
+func (c *compiler) VisitBreak(stmt ast.Break) error {
+	if len(c.loops) == 0 {
+		return c.errorAt(stmt.Keyword, "can't use 'break' outside of a loop")
+	}
+	l := c.loops[len(c.loops)-1]
+	c.at(stmt.Keyword)
+	if err := c.exitTries(l.tries); err != nil {
+		return err
+	}
+	c.discardLoopLocals(l)
+	l.breaks = append(l.breaks, c.emitJump(OpJump))
+	return nil
+}
+
+// VisitContinue implements ast.StatementVistior.
+func (c *compiler) VisitContinue(stmt ast.Continue) error {
+	if len(c.loops) == 0 {
+		return c.errorAt(stmt.Keyword, "can't use 'continue' outside of a loop")
+	}
+	l := c.loops[len(c.loops)-1]
+	c.at(stmt.Keyword)
+	if err := c.exitTries(l.tries); err != nil {
+		return err
+	}
+	c.discardLoopLocals(l)
+	return c.emitLoop(l.start)
+}
+
+// VisitReturn implements ast.StatementVistior.
+func (c *compiler) VisitReturn(stmt ast.Return) error {
+	c.at(stmt.Keyword)
+	if stmt.Value == nil {
+		c.emitImplicitValue()
+	} else if err := c.expr(stmt.Value); err != nil {
+		return err
+	}
+	if len(c.tries) == 0 {
+		c.emitOp(OpReturn)
+		return nil
+	}
+
+	// the value is kept in a local while the finally blocks of the enclosing try statements run.
+	c.beginScope()
+	slot, err := c.addHiddenLocal(stmt.Keyword)
+	if err != nil {
+		return err
+	}
+	if err := c.exitTries(0); err != nil {
+		return err
+	}
+	c.at(stmt.Keyword)
+	c.emitOp(OpGetLocal, byte(slot))
+	c.emitOp(OpReturn)
+	c.dropHiddenLocal()
+	return nil
+}
+
+// VisitExpression implements ast.StatementVistior.
+func (c *compiler) VisitExpression(stmt ast.Expression) error {
+	if err := c.expr(stmt.Expr); err != nil {
+		return err
+	}
+	c.emitOp(OpPop)
+	return nil
+}
+
+// VisitImport implements ast.StatementVistior.
+func (c *compiler) VisitImport(stmt ast.Import) error {
+	if err := c.declareVariable(stmt.Name); err != nil {
+		return err
+	}
+	c.at(stmt.Keyword)
+	if err := c.emitConstant(objValue(stmt.Name.Lexeme)); err != nil {
+		return err
+	}
+	// the path is where import cycles are reported.
+	c.at(stmt.Path)
+	if err := c.emitNameOp(OpImport, stmt.Path.Literal.(string)); err != nil {
+		return err
+	}
+	c.emitOp(OpImported)
+	return c.defineVariable(stmt.Name)
+}
+
+// VisitThrow implements ast.StatementVistior.
+func (c *compiler) VisitThrow(stmt ast.Throw) error {
+	if err := c.expr(stmt.Value); err != nil {
+		return err
+	}
+	c.at(stmt.Keyword)
+	c.emitOp(OpThrow)
+	return nil
+}
+
+// VisitTry implements ast.StatementVistior.
+// a try statement with both a catch and a finally block is compiled as a try-catch inside of a try-finally,
+// so that the finally block runs after the catch block too.
+func (c *compiler) VisitTry(stmt ast.Try) error {
+	body := func() error {
+		return stmt.Body.Accept(c)
+	}
+	if stmt.Catch != nil {
+		protected := body
+		body = func() error {
+			return c.try(stmt.Keyword, nil, protected, func() error {
+				c.at(stmt.Name)
+				c.emitOp(OpCatch)
+				// the caught value stays on the stack as the local named by the catch clause.
+				c.beginScope()
+				if err := c.addLocal(stmt.Name); err != nil {
+					return err
+				}
+				c.markInitialized()
+				if err := stmt.Catch.Accept(c); err != nil {
+					return err
+				}
+				c.endScope()
+				return nil
+			})
+		}
+	}
+	if stmt.Finally == nil {
+		return body()
+	}
+
+	finally := func() error {
+		return stmt.Finally.Accept(c)
+	}
+	return c.try(stmt.Keyword, finally, body, func() error {
+		return c.rethrowAfter(stmt.Keyword, finally)
+	})
+}
+
+// rethrowAfter compiles the handler of a try statement with a finally block.
+// the error stays on the stack while the finally block runs, and is thrown again after it.
+func (c *compiler) rethrowAfter(keyword token.Token, finally func() error) error {
+	c.beginScope()
+	slot, err := c.addHiddenLocal(keyword)
+	if err != nil {
+		return err
+	}
+	if err := finally(); err != nil {
+		return err
+	}
+	c.at(keyword)
+	c.emitOp(OpGetLocal, byte(slot))
+	c.emitOp(OpRethrow)
+	c.dropHiddenLocal()
+	return nil
+}
+
+// try compiles the body with a handler installed, and the finally block, if any, after it.
+// if the body throws, the stack is unwound to where it was before the body, and the code emitted by handler runs
+// with the error on top of it.
+func (c *compiler) try(keyword token.Token, finally func() error, body func() error, handler func() error) error {
+	c.at(keyword)
+	handlerJump := c.emitJump(OpTry)
+	c.tries = append(c.tries, finally)
+	err := body()
+	c.tries = c.tries[:len(c.tries)-1]
+	if err != nil {
+		return err
+	}
+
+	c.at(keyword)
+	c.emitOp(OpPopHandler)
+	if finally != nil {
+		if err := finally(); err != nil {
+			return err
+		}
+	}
+	endJump := c.emitJump(OpJump)
+
+	if err := c.patchJump(handlerJump); err != nil {
+		return err
+	}
+	if err := handler(); err != nil {
+		return err
+	}
+	return c.patchJump(endJump)
+}
+
+// exitTries emits the code leaving the try statements entered after the given number of them, for a break, continue or return.
+// their handlers are removed, and their finally blocks run, innermost first.
+func (c *compiler) exitTries(depth int) error {
+	tries, loops := c.tries, c.loops
+	defer func() {
+		c.tries, c.loops = tries, loops
+	}()
+
+	for idx := len(tries) - 1; idx >= depth; idx-- {
+		c.emitOp(OpPopHandler)
+		if tries[idx] == nil {
+			continue
+		}
+		// the finally block is outside of its try statement, and of the loops inside of it.
+		c.tries = tries[:idx]
+		c.loops = loops
+		for len(c.loops) > 0 && c.loops[len(c.loops)-1].tries > idx {
+			c.loops = c.loops[:len(c.loops)-1]
+		}
+		if err := tries[idx](); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VisitYield implements ast.StatementVistior.
+func (c *compiler) VisitYield(stmt ast.Yield) error {
+	c.at(stmt.Keyword)
+	if stmt.Value == nil {
+		c.emitOp(OpNil)
+	} else if err := c.expr(stmt.Value); err != nil {
+		return err
+	}
+	c.at(stmt.Keyword)
+	c.emitOp(OpYield)
+	return nil
+}
+
+// VisitForIn implements ast.StatementVistior.
+// the iterator is kept in a hidden local, and closed however the loop is left: once it runs out, or by a break, a return or an error,
+// as if the loop was the body of a try statement closing it in its finally block.
+func (c *compiler) VisitForIn(stmt ast.ForIn) error {
+	if err := c.expr(stmt.Iterable); err != nil {
+		return err
+	}
+	c.at(stmt.In)
+	c.emitOp(OpIter)
+	c.emitOp(OpIterCheck)
+	c.beginScope()
+	slot, err := c.addHiddenLocal(stmt.In)
+	if err != nil {
+		return err
+	}
+
+	closeIterator := func() error {
+		c.at(stmt.In)
+		c.emitOp(OpGetLocal, byte(slot))
+		c.emitOp(OpIterClose)
+		c.emitOp(OpPop)
+		return nil
+	}
+	err = c.try(stmt.In, closeIterator, func() error {
+		return c.forIn(stmt, slot)
+	}, func() error {
+		return c.rethrowAfter(stmt.In, closeIterator)
+	})
+	if err != nil {
+		return err
+	}
+	c.endScope()
+	return nil
+}
+
+// forIn compiles the loop over the iterator in the slot, with the name bound anew for each value, so that closures capture the value of their iteration.
+func (c *compiler) forIn(stmt ast.ForIn, slot int) error {
+	l := &loop{
+		start:      len(c.fn.Chunk.Code),
+		scopeDepth: c.scopeDepth,
+		tries:      len(c.tries),
+	}
+	c.loops = append(c.loops, l)
+	defer func() {
+		c.loops = c.loops[:len(c.loops)-1]
+	}()
+
+	c.at(stmt.In)
+	c.emitOp(OpGetLocal, byte(slot))
+	c.emitOp(OpIterNext)
+	exitJump := c.emitJump(OpJumpIfDone)
+
+	c.beginScope()
+	if err := c.addLocal(stmt.Name); err != nil {
+		return err
+	}
+	c.markInitialized()
+	if err := stmt.Body.Accept(c); err != nil {
+		return err
+	}
+	c.endScope()
+	if err := c.emitLoop(l.start); err != nil {
+		return err
+	}
+
+	if err := c.patchJump(exitJump); err != nil {
+		return err
+	}
+	c.emitOp(OpPop)
+	// break statements jump past the pop of done, as they leave nothing on the stack
+	for _, b := range l.breaks {
+		if err := c.patchJump(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// VisitClass implements ast.StatementVistior.
+func (c *compiler) VisitClass(stmt ast.Class) error {
+	c.at(stmt.Name)
+	if err := c.declareVariable(stmt.Name); err != nil {
+		return err
+	}
+	if err := c.emitNameOp(OpClass, stmt.Name.Lexeme); err != nil {
+		return err
+	}
+	if err := c.defineVariable(stmt.Name); err != nil {
+		return err
+	}
+
+	if stmt.Superclass != nil {
+		if err := c.getVariable(stmt.Superclass.Name); err != nil {
+			return err
+		}
+		// the superclass stays on the stack as the local 'super', for methods to capture.
+		c.beginScope()
+		defer c.endScope()
+		if err := c.addLocal(token.Token{Type: token.SUPER, Lexeme: "super", Ln: stmt.Name.Ln}); err != nil {
+			return err
+		}
+		c.markInitialized()
+
+		if err := c.getVariable(stmt.Name); err != nil {
+			return err
+		}
+		c.emitOp(OpInherit)
+	}
+
+	// the class stays on the stack while the methods are attached to it
+	if err := c.getVariable(stmt.Name); err != nil {
+		return err
+	}
+	for _, method := range stmt.Methods {
+		typ := functionTypeMethod
+		if method.Name.Lexeme == "init" {
+			typ = functionTypeInitializer
+		}
+		if err := c.function(method, typ); err != nil {
+			return err
+		}
+		if err := c.emitNameOp(OpMethod, method.Name.Lexeme); err != nil {
+			return err
+		}
+	}
+	c.emitOp(OpPop)
+	return nil
+}
+
+// VisitList implements ast.ExpressionVisitor.
+func (c *compiler) VisitList(e ast.List) (any, error) {
+	for _, el := range e.Elements {
+		if err := c.expr(el); err != nil {
+			return nil, err
+		}
+		c.temps++
+	}
+	c.temps -= len(e.Elements)
+	c.at(e.Bracket)
+	if len(e.Elements) > math.MaxUint16 {
+		return nil, c.errorAt(e.Bracket, "too many elements in list literal")
+	}
+	c.emitShortOp(OpList, len(e.Elements))
+	return nil, nil
+}
+
+// VisitIndex implements ast.ExpressionVisitor.
+func (c *compiler) VisitIndex(e ast.Index) (any, error) {
+	if err := c.expr(e.Object); err != nil {
+		return nil, err
+	}
+	c.temps++
+	if err := c.expr(e.Index); err != nil {
+		return nil, err
+	}
+	c.temps--
+	c.at(e.Bracket)
+	c.emitOp(OpGetIndex)
+	return nil, nil
+}
+
+// VisitIndexSet implements ast.ExpressionVisitor.
+func (c *compiler) VisitIndexSet(e ast.IndexSet) (any, error) {
+	if err := c.expr(e.Object); err != nil {
+		return nil, err
+	}
+	c.temps++
+	if err := c.expr(e.Index); err != nil {
+		return nil, err
+	}
+	c.temps++
+	if err := c.expr(e.Value); err != nil {
+		return nil, err
+	}
+	c.temps -= 2
+	c.at(e.Bracket)
+	c.emitOp(OpSetIndex)
+	return nil, nil
+}
+
+// VisitInterpolation implements ast.ExpressionVisitor.
+func (c *compiler) VisitInterpolation(e ast.Interpolation) (any, error) {
+	for _, part := range e.Parts {
+		if err := c.expr(part); err != nil {
+			return nil, err
+		}
+		c.temps++
+	}
+	c.temps -= len(e.Parts)
+	c.at(e.Quote)
+	if len(e.Parts) > math.MaxUint16 {
+		return nil, c.errorAt(e.Quote, "too many parts in string interpolation")
+	}
+	c.emitShortOp(OpConcat, len(e.Parts))
+	return nil, nil
+}
+
+// VisitMap implements ast.ExpressionVisitor.
+func (c *compiler) VisitMap(e ast.Map) (any, error) {
+	for idx := range e.Keys {
+		if err := c.expr(e.Keys[idx]); err != nil {
+			return nil, err
+		}
+		c.temps++
+		if err := c.expr(e.Values[idx]); err != nil {
+			return nil, err
+		}
+		c.temps++
+	}
+	c.temps -= 2 * len(e.Keys)
+	c.at(e.Brace)
+	if len(e.Keys) > math.MaxUint16 {
+		return nil, c.errorAt(e.Brace, "too many entries in map literal")
+	}
+	c.emitShortOp(OpMap, len(e.Keys))
+	return nil, nil
+}
+
+var _ ast.ExpressionVisitor = (*compiler)(nil)
+var _ ast.StatementVistior = (*compiler)(nil)
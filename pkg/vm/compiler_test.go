@@ -0,0 +1,186 @@
+package vm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/parser"
+	"github.com/taehioum/glox/pkg/scanner"
+)
+
+func compile(t *testing.T, source string) (*Function, error) {
+	t.Helper()
+	tokens, err := scanner.ScanTokens(source)
+	if err != nil {
+		t.Fatalf("scanning: %v", err)
+	}
+	stmts, err := parser.Parse(tokens)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	return Compile(stmts)
+}
+
+// disassemble renders the function, followed by the functions it declares, depth first.
+func disassemble(fn *Function) string {
+	var sb strings.Builder
+	sb.WriteString(Disassemble(&fn.Chunk, fn.String()))
+	for _, c := range fn.Chunk.Constants {
+		if nested, ok := c.obj.(*Function); ok {
+			sb.WriteString(disassemble(nested))
+		}
+	}
+	return sb.String()
+}
+
+func TestCompile(t *testing.T) {
+	testcases := []struct {
+		desc   string
+		source string
+		code   string
+	}{
+		{
+			desc:   "call",
+			source: "print(1 + 2);",
+			code: `== <script> ==
+0000    1 OP_GET_GLOBAL       0 'print'
+0003    1 OP_CONSTANT         1 '1'
+0006    1 OP_CONSTANT         2 '2'
+0009    1 OP_ADD
+0010    1 OP_CALL             1
+0012    1 OP_POP
+0013    1 OP_NIL
+0014    1 OP_RETURN
+`,
+		},
+		{
+			desc:   "postfix increment keeps the old value",
+			source: "var x = 1;\nx++;",
+			code: `== <script> ==
+0000    1 OP_CONSTANT         0 '1'
+0003    1 OP_DEFINE_GLOBAL    1 'x'
+0006    2 OP_GET_GLOBAL       2 'x'
+0009    2 OP_TUCK             0
+0011    2 OP_INCREMENT
+0012    2 OP_SET_GLOBAL       3 'x'
+0015    2 OP_POP
+0016    2 OP_POP
+0017    2 OP_NIL
+0018    2 OP_RETURN
+`,
+		},
+		{
+			desc:   "locals stay on the stack",
+			source: "{\n  var i = 0;\n  while (i < 2) i = i + 1;\n}",
+			code: `== <script> ==
+0000    2 OP_CONSTANT         0 '0'
+0003    3 OP_GET_LOCAL        1
+0005    3 OP_CONSTANT         1 '2'
+0008    3 OP_LESS
+0009    3 OP_JUMP_IF_FALSE    9 -> 25
+0012    3 OP_POP
+0013    3 OP_GET_LOCAL        1
+0015    3 OP_CONSTANT         2 '1'
+0018    3 OP_ADD
+0019    3 OP_SET_LOCAL        1
+0021    3 OP_POP
+0022    3 OP_LOOP            22 -> 3
+0025    3 OP_POP
+0026    3 OP_POP
+0027    3 OP_NIL
+0028    3 OP_RETURN
+`,
+		},
+		{
+			desc:   "closures capture locals as upvalues",
+			source: "fun f(a) {\n  var b = a;\n  return fun () { return b; };\n}",
+			code: `== <script> ==
+0000    1 OP_CLOSURE          0 <fn f>
+0003    1 OP_DEFINE_GLOBAL    1 'f'
+0006    1 OP_NIL
+0007    1 OP_RETURN
+== <fn f> ==
+0000    2 OP_GET_LOCAL        1
+0002    3 OP_CLOSURE          0 <fn fun>
+0005      |                     local 2
+0007    3 OP_RETURN
+0008    3 OP_NIL
+0009    3 OP_RETURN
+== <fn fun> ==
+0000    3 OP_GET_UPVALUE      0
+0002    3 OP_RETURN
+0003    3 OP_NIL
+0004    3 OP_RETURN
+`,
+		},
+		{
+			desc:   "match binds names as locals above the value",
+			source: "match (1) {\n  case 0 => nil;\n  case x if x > 0 => print(x);\n}",
+			code: `== <script> ==
+0000    1 OP_CONSTANT         0 '1'
+0003    2 OP_GET_LOCAL        1
+0005    2 OP_CONSTANT         1 '0'
+0008    2 OP_EQUAL
+0009    2 OP_JUMP_IF_FALSE    9 -> 18
+0012    2 OP_POP
+0013    2 OP_NIL
+0014    2 OP_POP
+0015    2 OP_JUMP            15 -> 48
+0018    2 OP_POP
+0019    3 OP_GET_LOCAL        1
+0021    3 OP_GET_LOCAL        2
+0023    3 OP_CONSTANT         2 '0'
+0026    3 OP_GREATER
+0027    3 OP_JUMP_IF_FALSE   27 -> 43
+0030    3 OP_POP
+0031    3 OP_GET_GLOBAL       3 'print'
+0034    3 OP_GET_LOCAL        2
+0036    3 OP_CALL             1
+0038    3 OP_POP
+0039    3 OP_POP
+0040    3 OP_JUMP            40 -> 48
+0043    3 OP_POP
+0044    3 OP_POP
+0045    1 OP_GET_LOCAL        1
+0047    1 OP_NO_MATCH
+0048    1 OP_POP
+0049    1 OP_NIL
+0050    1 OP_RETURN
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.desc, func(t *testing.T) {
+			fn, err := compile(t, tc.source)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.code, disassemble(fn))
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	testcases := []struct {
+		source string
+		msg    string
+		line   int
+	}{
+		{"break;", "can't use 'break' outside of a loop", 1},
+		{"while (true) {}\ncontinue;", "can't use 'continue' outside of a loop", 2},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.source, func(t *testing.T) {
+			_, err := compile(t, tc.source)
+			var cerr *diagnostic.CompileError
+			if assert.True(t, errors.As(err, &cerr), "expected a compile error, got %v", err) {
+				assert.Equal(t, tc.msg, cerr.Msg)
+				assert.Equal(t, tc.line, cerr.Line)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package vm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Disassemble renders the chunk in a human readable form, for debugging.
+func Disassemble(c *Chunk, name string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "== %s ==\n", name)
+	for offset := 0; offset < len(c.Code); {
+		offset = disassembleInstruction(&sb, c, offset)
+	}
+	return sb.String()
+}
+
+func disassembleInstruction(sb *strings.Builder, c *Chunk, offset int) int {
+	fmt.Fprintf(sb, "%04d %4d ", offset, c.TokenAt(offset).Ln)
+
+	op := OpCode(c.Code[offset])
+	switch op {
+	case OpList, OpMap, OpConcat, OpMatchList:
+		fmt.Fprintf(sb, "%-16s %4d\n", op, readShort(c.Code, offset+1))
+		return offset + 3
+	case OpConstant, OpGetGlobal, OpDefineGlobal, OpSetGlobal, OpGetProperty, OpSetProperty, OpGetSuper, OpClass, OpMethod, OpImport:
+		idx := readShort(c.Code, offset+1)
+		fmt.Fprintf(sb, "%-16s %4d '%s'\n", op, idx, c.Constants[idx])
+		return offset + 3
+	case OpGetLocal, OpSetLocal, OpGetUpvalue, OpSetUpvalue, OpCall, OpTuck:
+		fmt.Fprintf(sb, "%-16s %4d\n", op, c.Code[offset+1])
+		return offset + 2
+	case OpJump, OpJumpIfFalse, OpJumpIfDone, OpTry:
+		jump := readShort(c.Code, offset+1)
+		fmt.Fprintf(sb, "%-16s %4d -> %d\n", op, offset, offset+3+jump)
+		return offset + 3
+	case OpLoop:
+		jump := readShort(c.Code, offset+1)
+		fmt.Fprintf(sb, "%-16s %4d -> %d\n", op, offset, offset+3-jump)
+		return offset + 3
+	case OpClosure:
+		idx := readShort(c.Code, offset+1)
+		fn := c.Constants[idx].obj.(*Function)
+		fmt.Fprintf(sb, "%-16s %4d %s\n", op, idx, fn)
+		offset += 3
+		for i := 0; i < fn.UpvalueCount; i++ {
+			kind := "upvalue"
+			if c.Code[offset] == 1 {
+				kind = "local"
+			}
+			fmt.Fprintf(sb, "%04d      |                     %s %d\n", offset, kind, c.Code[offset+1])
+			offset += 2
+		}
+		return offset
+	default:
+		fmt.Fprintf(sb, "%s\n", op)
+		return offset + 1
+	}
+}
+
+func readShort(code []byte, offset int) int {
+	return int(code[offset])<<8 | int(code[offset+1])
+}
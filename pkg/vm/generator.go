@@ -0,0 +1,248 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+)
+
+// errClosed unwinds the body of a generator that is closed before it runs out, running its finally blocks.
+// it can't be caught.
+var errClosed = errors.New("generator closed")
+
+// Generator is returned by calling a function that yields. its body runs a piece at a time, up to the next yield,
+// whenever the generator is resumed by next(), or by a for-in loop.
+//
+// the body runs on top of the stack of whoever resumes it, as if called from there. as it can only yield from its own frame,
+// suspending it saves that frame and the part of the stack above its base, along with the handlers and the open upvalues
+// pointing into it, to be copied back on top of the stack of whoever resumes it next.
+// a generator that is abandoned before it runs out, e.g. by a loop that breaks early, is closed. those left suspended once the program is done
+// are closed by VM.Close.
+type Generator struct {
+	closure *Closure
+	state   generatorState
+	// whether the generator is being closed, so that the body can't yield anymore
+	closing bool
+
+	// while suspended: the frame, the stack slots from its base, the handlers of its try statements, and the open upvalues,
+	// with their stack positions relative to the base, and the upvalues pointing into the saved slots.
+	frame    callFrame
+	stack    []Value
+	handlers []handler
+	upvalues []*Upvalue
+}
+
+type generatorState int
+
+const (
+	generatorCreated generatorState = iota
+	generatorSuspended
+	generatorRunning
+	generatorDone
+)
+
+// resumed is a generator whose body is running, in the frame at frameCount.
+type resumed struct {
+	generator *Generator
+	// frameCount of whoever resumed it
+	frameCount int
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("<generator %s>", g.closure.fn.Name)
+}
+
+// generatorMethod is the next() or close() method of a generator.
+// next() returns the next value, or done when the generator has run out.
+type generatorMethod struct {
+	generator *Generator
+	name      string
+}
+
+func (m *generatorMethod) String() string {
+	return fmt.Sprintf("<native fn %s>", m.name)
+}
+
+func (g *Generator) property(name string) (Value, bool) {
+	switch name {
+	case "next", "close":
+		return objValue(&generatorMethod{generator: g, name: name}), true
+	default:
+		return nilValue, false
+	}
+}
+
+// newGenerator saves the callee and the arguments on top of the stack as the slots of the body, which hasn't started yet.
+func (vm *VM) newGenerator(closure *Closure, argCount int) *Generator {
+	g := &Generator{closure: closure, frame: callFrame{closure: closure}}
+	g.stack = make([]Value, argCount+1)
+	copy(g.stack, vm.stack[vm.sp-argCount-1:vm.sp])
+	vm.sp -= argCount + 1
+	return g
+}
+
+// next resumes the generator on top of the stack, which is replaced by the value it yields, or by done once it has run out.
+func (vm *VM) next(g *Generator) error {
+	switch g.state {
+	case generatorDone:
+		vm.stack[vm.sp-1] = doneValue
+		return nil
+	case generatorRunning:
+		return vm.runtimeError("generator %s is already running", g.closure.fn.Name)
+	}
+	return vm.resume(g)
+}
+
+// close unwinds the body of the suspended generator on top of the stack, so that its finally blocks run.
+// the generator is replaced by nil once it is done.
+func (vm *VM) close(g *Generator) error {
+	switch g.state {
+	case generatorCreated:
+		g.state = generatorDone
+		g.stack = nil
+	case generatorSuspended:
+		g.closing = true
+		if err := vm.resume(g); err != nil {
+			return err
+		}
+		// the body is unwound from where it yielded, by the handlers of its try statements.
+		return errClosed
+	}
+	vm.stack[vm.sp-1] = nilValue
+	return nil
+}
+
+// resume copies the saved frame and slots of the body on top of the stack, in place of the value on top of it, and runs it from there.
+func (vm *VM) resume(g *Generator) error {
+	if err := vm.checkDepth(); err != nil {
+		return err
+	}
+
+	base := vm.sp - 1
+	copy(vm.stack[base:], g.stack)
+	vm.sp = base + len(g.stack)
+	vm.frames[vm.frameCount] = g.frame
+	vm.frames[vm.frameCount].base = base
+	vm.resumed = append(vm.resumed, resumed{generator: g, frameCount: vm.frameCount})
+	vm.frameCount++
+	for _, h := range g.handlers {
+		vm.handlers = append(vm.handlers, handler{frameCount: vm.frameCount, sp: base + h.sp, ip: h.ip})
+	}
+	// the upvalues are above those still open, which point below the resumed generator.
+	for idx := len(g.upvalues) - 1; idx >= 0; idx-- {
+		up := g.upvalues[idx]
+		up.slot += base
+		up.location = &vm.stack[up.slot]
+		up.next = vm.openUpvalues
+		vm.openUpvalues = up
+	}
+
+	g.state = generatorRunning
+	g.stack, g.handlers, g.upvalues = nil, nil, nil
+	delete(vm.suspended, g)
+	return nil
+}
+
+// yield suspends the body of the innermost generator running, which is the current frame, and returns the value to whoever resumed it.
+func (vm *VM) yield(v Value) error {
+	r := vm.resumed[len(vm.resumed)-1]
+	g := r.generator
+	if g.closing {
+		return errClosed
+	}
+
+	frame := vm.frames[vm.frameCount-1]
+	base := frame.base
+	g.frame = frame
+	g.frame.base = 0
+	g.stack = make([]Value, vm.sp-base)
+	copy(g.stack, vm.stack[base:vm.sp])
+	first := len(vm.handlers)
+	for first > 0 && vm.handlers[first-1].frameCount > r.frameCount {
+		first--
+	}
+	for _, h := range vm.handlers[first:] {
+		g.handlers = append(g.handlers, handler{sp: h.sp - base, ip: h.ip})
+	}
+	vm.handlers = vm.handlers[:first]
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= base {
+		up := vm.openUpvalues
+		up.slot -= base
+		up.location = &g.stack[up.slot]
+		g.upvalues = append(g.upvalues, up)
+		vm.openUpvalues = up.next
+	}
+
+	g.state = generatorSuspended
+	vm.suspended[g] = struct{}{}
+	vm.resumed = vm.resumed[:len(vm.resumed)-1]
+	vm.frameCount--
+	vm.sp = base
+	vm.push(v)
+	return nil
+}
+
+// returning reports whether the current frame, which is returning, is the body of a generator,
+// which is then done, and returns what whoever resumed it gets in place of the value returned.
+func (vm *VM) returning() (Value, bool) {
+	n := len(vm.resumed)
+	if n == 0 || vm.resumed[n-1].frameCount != vm.frameCount-1 {
+		return nilValue, false
+	}
+	g := vm.resumed[n-1].generator
+	vm.resumed = vm.resumed[:n-1]
+	g.state = generatorDone
+	if g.closing {
+		return nilValue, true
+	}
+	return doneValue, true
+}
+
+// leaveGenerators unwinds the bodies of the generators that the error leaves, as none of their handlers catches it, and marks them done.
+// it reports whether the error was the one closing the innermost of them, which is then left with nil in its place, as close() returns.
+func (vm *VM) leaveGenerators(err error) bool {
+	for len(vm.resumed) > 0 {
+		r := vm.resumed[len(vm.resumed)-1]
+		if len(vm.handlers) > 0 && vm.handlers[len(vm.handlers)-1].frameCount > r.frameCount {
+			// a handler of the body catches it.
+			return false
+		}
+		base := vm.frames[r.frameCount].base
+		vm.closeUpvalues(base)
+		vm.resumed = vm.resumed[:len(vm.resumed)-1]
+		vm.frameCount = r.frameCount
+		vm.sp = base
+		r.generator.state = generatorDone
+		if r.generator.closing && errors.Is(err, errClosed) {
+			vm.push(nilValue)
+			return true
+		}
+	}
+	return false
+}
+
+// Close closes the generators left suspended once the program is done, running their finally blocks.
+func (vm *VM) Close() error {
+	gs := make([]*Generator, 0, len(vm.suspended))
+	for g := range vm.suspended {
+		gs = append(gs, g)
+	}
+	var errs []error
+	for _, g := range gs {
+		if err := vm.Interprete(closer(g)); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// closer returns a script closing the suspended generator, as a for-in loop does when it is left.
+func closer(g *Generator) *Function {
+	fn := &Function{}
+	// the script is reported at the yield the generator is suspended at.
+	tok := g.frame.closure.fn.Chunk.TokenAt(g.frame.ip - 1)
+	idx := fn.Chunk.addConstant(objValue(g))
+	for _, b := range []byte{byte(OpConstant), byte(idx >> 8), byte(idx), byte(OpIterClose), byte(OpReturn)} {
+		fn.Chunk.write(b, tok)
+	}
+	return fn
+}
@@ -0,0 +1,126 @@
+package vm
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+)
+
+// Done is returned by iterators when they have no more values, e.g. by the next() method of an object, or by a closure.
+type Done struct{}
+
+func (d Done) String() string {
+	return "done"
+}
+
+var doneValue = objValue(Done{})
+
+// Range is the integers from start up to stop, exclusive, by step, e.g. range(0, 10, 2).
+// the step may be negative, to count down.
+type Range struct {
+	start, stop, step int64
+}
+
+func (r Range) String() string {
+	return fmt.Sprintf("range(%d, %d, %d)", r.start, r.stop, r.step)
+}
+
+// iterator is what for-in loops run over lists, maps, strings and ranges with. scripts never see it.
+type iterator struct {
+	// next returns the next value, and whether there was one.
+	next func() (Value, bool)
+}
+
+func (it *iterator) String() string {
+	return "<iterator>"
+}
+
+// iterate returns an iterator over the values of lists, the keys of maps, the code points of strings, and ranges.
+// other values are iterated if they are iterators themselves, see asIterator. objects with an iter() method are handled by OpIter.
+func iterate(v Value) (Value, bool) {
+	switch o := v.obj.(type) {
+	case *List:
+		idx := 0
+		return objValue(&iterator{next: func() (Value, bool) {
+			if idx >= len(o.elements) {
+				return nilValue, false
+			}
+			idx++
+			return o.elements[idx-1], true
+		}}), true
+	case *Map:
+		// keys set during the loop aren't visited.
+		keys := make([]Value, len(o.keys))
+		copy(keys, o.keys)
+		return iterate(objValue(&List{elements: keys}))
+	case string:
+		rest := o
+		return objValue(&iterator{next: func() (Value, bool) {
+			if rest == "" {
+				return nilValue, false
+			}
+			_, size := utf8.DecodeRuneInString(rest)
+			c := rest[:size]
+			rest = rest[size:]
+			return objValue(c), true
+		}}), true
+	case Range:
+		n, done := o.start, false
+		return objValue(&iterator{next: func() (Value, bool) {
+			if done || (o.step > 0 && n >= o.stop) || (o.step < 0 && n <= o.stop) {
+				return nilValue, false
+			}
+			curr := n
+			// stop rather than overflow past the end of the int64s.
+			if (o.step > 0 && n > math.MaxInt64-o.step) || (o.step < 0 && n < math.MinInt64-o.step) {
+				done = true
+			} else {
+				n += o.step
+			}
+			return intValue(curr), true
+		}}), true
+	}
+	return asIterator(v)
+}
+
+// asIterator returns the value as an iterator, if it is one: a generator, a function called with no arguments,
+// returning done when it has no more values, or an object with such a next() method.
+func asIterator(v Value) (Value, bool) {
+	switch o := v.obj.(type) {
+	case *iterator, *Generator, *Closure, *BoundMethod:
+		return v, true
+	case *Instance:
+		if next, ok := o.class.methods["next"]; ok {
+			return objValue(&BoundMethod{receiver: v, method: next}), true
+		}
+	}
+	return nilValue, false
+}
+
+// nativeRange returns the range of its arguments: range(stop), range(start, stop) or range(start, stop, step).
+func nativeRange(vm *VM, args []Value) (Value, error) {
+	if len(args) < 1 || len(args) > 3 {
+		return nilValue, fmt.Errorf("range: expected 1 to 3 arguments, got %d", len(args))
+	}
+	bounds := make([]int64, len(args))
+	for idx, arg := range args {
+		n, ok := arg.toInt()
+		if !ok {
+			return nilValue, fmt.Errorf("range: expected integers, got %s", arg)
+		}
+		bounds[idx] = int64(n)
+	}
+	r := Range{step: 1}
+	switch len(bounds) {
+	case 1:
+		r.stop = bounds[0]
+	case 2:
+		r.start, r.stop = bounds[0], bounds[1]
+	case 3:
+		r.start, r.stop, r.step = bounds[0], bounds[1], bounds[2]
+	}
+	if r.step == 0 {
+		return nilValue, fmt.Errorf("range: step must not be zero")
+	}
+	return objValue(r), nil
+}
@@ -0,0 +1,277 @@
+package vm
+
+import (
+	"math"
+
+	"github.com/taehioum/glox/pkg/ast"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+// VisitMatchStmt implements ast.StatementVistior.
+func (c *compiler) VisitMatchStmt(stmt ast.MatchStmt) error {
+	return c.match(stmt.Keyword, stmt.Value, stmt.Arms, false, func(idx int) error {
+		return stmt.Bodies[idx].Accept(c)
+	})
+}
+
+// VisitMatch implements ast.ExpressionVisitor.
+func (c *compiler) VisitMatch(e ast.Match) (any, error) {
+	// the values the enclosing expressions left on the stack are below the locals of the match,
+	// so they are declared as hidden locals first, for the slots of the locals to be right.
+	temps := c.temps
+	c.temps = 0
+	c.beginScope()
+	for i := 0; i < temps; i++ {
+		if _, err := c.addHiddenLocal(e.Keyword); err != nil {
+			return nil, err
+		}
+	}
+
+	err := c.match(e.Keyword, e.Value, e.Arms, true, func(idx int) error {
+		return c.expr(e.Bodies[idx])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.locals = c.locals[:len(c.locals)-temps]
+	c.scopeDepth--
+	c.temps = temps
+	return nil, nil
+}
+
+// match compiles the arms, testing the value against each in turn, kept in a hidden local.
+// the names an arm binds are locals above it, while its guard and its body run.
+// the value of the body of a match expression replaces the value matched, which is left on the stack.
+func (c *compiler) match(keyword token.Token, value ast.Expr, arms []ast.Arm, isExpr bool, body func(idx int) error) error {
+	c.at(keyword)
+	if err := c.expr(value); err != nil {
+		return err
+	}
+	c.beginScope()
+	slot, err := c.addHiddenLocal(keyword)
+	if err != nil {
+		return err
+	}
+	subject := func() error {
+		c.emitOp(OpGetLocal, byte(slot))
+		return nil
+	}
+
+	var ends []int
+	for idx, arm := range arms {
+		c.at(arm.Case)
+		// the jumps taken when none of the patterns match, with the result of the failed test on the stack.
+		var fails []int
+		var matched []int
+		for k, p := range arm.Patterns {
+			fails = nil
+			if err := c.test(p, subject, &fails); err != nil {
+				return err
+			}
+			if k == len(arm.Patterns)-1 {
+				break
+			}
+			// try the next alternative if this one fails.
+			matched = append(matched, c.emitJump(OpJump))
+			if err := c.patchFails(fails); err != nil {
+				return err
+			}
+		}
+		for _, m := range matched {
+			if err := c.patchJump(m); err != nil {
+				return err
+			}
+		}
+
+		// alternatives can't bind names, so only the first pattern of an arm may.
+		c.beginScope()
+		bound := len(c.locals)
+		if err := c.bind(arm.Patterns[0], subject); err != nil {
+			return err
+		}
+		guardFail := -1
+		if arm.Guard != nil {
+			if err := c.expr(arm.Guard); err != nil {
+				return err
+			}
+			guardFail = c.emitJump(OpJumpIfFalse)
+			c.emitOp(OpPop)
+		}
+		if err := body(idx); err != nil {
+			return err
+		}
+		if isExpr {
+			c.emitOp(OpSetLocal, byte(slot))
+			c.emitOp(OpPop)
+		}
+		names := append([]local(nil), c.locals[bound:]...)
+		c.endScope()
+		ends = append(ends, c.emitJump(OpJump))
+
+		if guardFail == -1 {
+			if err := c.patchFails(fails); err != nil {
+				return err
+			}
+			continue
+		}
+		// the guard is falsy: the result of the guard and the names bound are popped.
+		if err := c.patchJump(guardFail); err != nil {
+			return err
+		}
+		c.emitOp(OpPop)
+		for i := len(names) - 1; i >= 0; i-- {
+			c.popLocal(names[i])
+		}
+		if len(fails) > 0 {
+			next := c.emitJump(OpJump)
+			if err := c.patchFails(fails); err != nil {
+				return err
+			}
+			if err := c.patchJump(next); err != nil {
+				return err
+			}
+		}
+	}
+
+	c.at(keyword)
+	c.emitOp(OpGetLocal, byte(slot))
+	c.emitOp(OpNoMatch)
+	for _, end := range ends {
+		if err := c.patchJump(end); err != nil {
+			return err
+		}
+	}
+
+	if isExpr {
+		// the value of the body stays on the stack, in place of the value matched.
+		c.dropHiddenLocal()
+	} else {
+		c.endScope()
+	}
+	return nil
+}
+
+// patchFails patches the jumps of failed tests to here, where the result of the test is popped.
+func (c *compiler) patchFails(fails []int) error {
+	if len(fails) == 0 {
+		return nil
+	}
+	for _, f := range fails {
+		if err := c.patchJump(f); err != nil {
+			return err
+		}
+	}
+	c.emitOp(OpPop)
+	return nil
+}
+
+// test emits the code testing whether the value that get pushes matches the pattern, without binding any names.
+// a test that fails jumps with its result on the stack, and the jump is appended to fails.
+func (c *compiler) test(p ast.Pattern, get func() error, fails *[]int) error {
+	check := func() {
+		*fails = append(*fails, c.emitJump(OpJumpIfFalse))
+		c.emitOp(OpPop)
+	}
+
+	switch p := p.(type) {
+	case ast.LiteralPattern:
+		if err := get(); err != nil {
+			return err
+		}
+		if err := c.expr(p.Value); err != nil {
+			return err
+		}
+		c.emitOp(OpEqual)
+		check()
+	case ast.ListPattern:
+		if len(p.Elements) > math.MaxUint16 {
+			return c.errorAt(p.Bracket, "too many elements in list pattern")
+		}
+		if err := get(); err != nil {
+			return err
+		}
+		c.emitShortOp(OpMatchList, len(p.Elements))
+		check()
+		for idx, el := range p.Elements {
+			if err := c.test(el, c.element(get, idx), fails); err != nil {
+				return err
+			}
+		}
+	case ast.MapPattern:
+		if err := get(); err != nil {
+			return err
+		}
+		c.emitOp(OpMatchMap)
+		check()
+		for idx, key := range p.Keys {
+			if err := get(); err != nil {
+				return err
+			}
+			if err := c.expr(key); err != nil {
+				return err
+			}
+			c.emitOp(OpHasKey)
+			check()
+			if err := c.test(p.Values[idx], c.entry(get, key), fails); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bind declares the names the pattern binds as locals, once the value that get pushes is known to match it.
+func (c *compiler) bind(p ast.Pattern, get func() error) error {
+	switch p := p.(type) {
+	case ast.BindingPattern:
+		if err := get(); err != nil {
+			return err
+		}
+		if err := c.addLocal(p.Name); err != nil {
+			return err
+		}
+		c.markInitialized()
+	case ast.ListPattern:
+		for idx, el := range p.Elements {
+			if err := c.bind(el, c.element(get, idx)); err != nil {
+				return err
+			}
+		}
+	case ast.MapPattern:
+		for idx, key := range p.Keys {
+			if err := c.bind(p.Values[idx], c.entry(get, key)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// element returns a function pushing the element at the index of the list that get pushes.
+func (c *compiler) element(get func() error, idx int) func() error {
+	return func() error {
+		if err := get(); err != nil {
+			return err
+		}
+		if err := c.emitConstant(intValue(int64(idx))); err != nil {
+			return err
+		}
+		c.emitOp(OpGetIndex)
+		return nil
+	}
+}
+
+// entry returns a function pushing the value of the key in the map that get pushes.
+func (c *compiler) entry(get func() error, key ast.Expr) func() error {
+	return func() error {
+		if err := get(); err != nil {
+			return err
+		}
+		if err := c.expr(key); err != nil {
+			return err
+		}
+		c.emitOp(OpGetIndex)
+		return nil
+	}
+}
@@ -0,0 +1,113 @@
+package vm
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+)
+
+// Loader reads the module at the path, and compiles it for the vm to run.
+// its tokens should be scanned with the path as their file, so that its own imports are relative to it.
+type Loader func(path string) (*Function, error)
+
+// Namespace is the module of an imported file, with its own globals.
+// its top-level names are exported, unless they start with '_'.
+//
+// while the module runs, its namespace is in slot zero of its frame, in place of the callee.
+type Namespace struct {
+	name    string
+	path    string
+	globals map[string]Value
+	// the absolute path of the file, which the module is cached by once it has run
+	key string
+}
+
+func (m *Namespace) String() string {
+	return fmt.Sprintf("<module %s>", m.name)
+}
+
+// loadError is the error of a loader. it can't be caught, as it isn't the script's.
+type loadError struct {
+	err error
+}
+
+func (e *loadError) Error() string {
+	return e.err.Error()
+}
+
+func (e *loadError) Unwrap() error {
+	return e.err
+}
+
+// SetLoader sets how the modules that scripts import are loaded. without a loader, imports fail.
+func (vm *VM) SetLoader(loader Loader) {
+	vm.loader = loader
+}
+
+// property returns the global of the module, unless it is private. the natives aren't globals of the module.
+func (m *Namespace) property(vm *VM, name string) (Value, error) {
+	if strings.HasPrefix(name, "_") {
+		return nilValue, vm.runtimeError("'%s' is private to module %s", name, m.name)
+	}
+	v, ok := m.globals[name]
+	if !ok {
+		return nilValue, vm.runtimeError("module %s has no '%s'", m.name, name)
+	}
+	return v, nil
+}
+
+// importModule replaces the name on top of the stack with the module at the path, and runs it on top of it, as if it were called,
+// the first time it is imported. afterwards, nil is pushed in place of the result of running it.
+func (vm *VM) importModule(path string) error {
+	tok := vm.frames[vm.frameCount-1].tokenAt()
+	if !filepath.IsAbs(path) {
+		// relative to the importing file, or to the working directory for sources that are not files.
+		path = filepath.Join(filepath.Dir(tok.File), path)
+	}
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return diagnostic.WrapRuntimeError(tok, err)
+	}
+
+	// the program itself is the root of the chain.
+	var importing []string
+	if root := vm.frames[0].tokenAt().File; root != "" {
+		importing = append(importing, root)
+	}
+	for i := 0; i < vm.frameCount; i++ {
+		if m, ok := vm.stack[vm.frames[i].base].obj.(*Namespace); ok {
+			importing = append(importing, m.path)
+		}
+	}
+	for idx, p := range importing {
+		if abs, _ := filepath.Abs(p); abs == key {
+			chain := append(importing[idx:len(importing):len(importing)], path)
+			return vm.runtimeError("import cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	if m, ok := vm.modules[key]; ok {
+		vm.stack[vm.sp-1] = objValue(m)
+		vm.push(nilValue)
+		return nil
+	}
+
+	if vm.loader == nil {
+		return vm.runtimeError("imports are not available")
+	}
+	fn, err := vm.loader(path)
+	if err != nil {
+		return &loadError{err: err}
+	}
+
+	m := &Namespace{
+		name:    vm.peek(0).obj.(string),
+		path:    path,
+		globals: make(map[string]Value),
+		key:     key,
+	}
+	vm.stack[vm.sp-1] = objValue(m)
+	vm.push(objValue(m))
+	return vm.call(&Closure{fn: fn, globals: m.globals}, 0)
+}
@@ -0,0 +1,122 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"time"
+	"unicode/utf8"
+)
+
+func nativeClock(vm *VM, args []Value) (Value, error) {
+	return floatValue(float64(time.Now().UnixNano()) / 1e9), nil
+}
+
+func nativePrint(vm *VM, args []Value) (Value, error) {
+	vs := make([]any, len(args))
+	for i, arg := range args {
+		vs[i] = arg
+	}
+	_, err := io.WriteString(vm.writer, fmt.Sprintln(vs...))
+	return nilValue, err
+}
+
+func nativeLen(vm *VM, args []Value) (Value, error) {
+	if l, ok := args[0].obj.(*List); ok {
+		return intValue(int64(len(l.elements))), nil
+	}
+	if m, ok := args[0].obj.(*Map); ok {
+		return intValue(int64(len(m.keys))), nil
+	}
+	if s, ok := args[0].asString(); ok {
+		// the number of code points, rather than bytes.
+		return intValue(int64(utf8.RuneCountInString(s))), nil
+	}
+	return nilValue, fmt.Errorf("len: expected a list, a map or a string, got %s", args[0])
+}
+
+// nativeSubstr returns the code points of the string from start, up to but excluding end.
+func nativeSubstr(vm *VM, args []Value) (Value, error) {
+	s, ok := args[0].asString()
+	if !ok {
+		return nilValue, fmt.Errorf("substr: expected a string, got %s", args[0])
+	}
+	start, ok := args[1].toInt()
+	if !ok {
+		return nilValue, fmt.Errorf("substr: start must be an integer, got %s", args[1])
+	}
+	end, ok := args[2].toInt()
+	if !ok {
+		return nilValue, fmt.Errorf("substr: end must be an integer, got %s", args[2])
+	}
+	length := utf8.RuneCountInString(s)
+	if start < 0 || end < start || end > length {
+		return nilValue, fmt.Errorf("substr: range [%v, %v) out of range for length %d", start, end, length)
+	}
+	return objValue(string([]rune(s)[start:end])), nil
+}
+
+func mapArg(name string, v Value) (*Map, error) {
+	m, ok := v.obj.(*Map)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected a map, got %s", name, v)
+	}
+	return m, nil
+}
+
+func nativeKeys(vm *VM, args []Value) (Value, error) {
+	m, err := mapArg("keys", args[0])
+	if err != nil {
+		return nilValue, err
+	}
+	return objValue(&List{elements: append([]Value(nil), m.keys...)}), nil
+}
+
+func nativeValues(vm *VM, args []Value) (Value, error) {
+	m, err := mapArg("values", args[0])
+	if err != nil {
+		return nilValue, err
+	}
+	values := make([]Value, 0, len(m.keys))
+	for _, k := range m.keys {
+		v, _ := m.get(k)
+		values = append(values, v)
+	}
+	return objValue(&List{elements: values}), nil
+}
+
+func nativeHas(vm *VM, args []Value) (Value, error) {
+	m, err := mapArg("has", args[0])
+	if err != nil {
+		return nilValue, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nilValue, fmt.Errorf("has: %w", err)
+	}
+	_, ok := m.get(args[1])
+	return boolValue(ok), nil
+}
+
+func nativeDelete(vm *VM, args []Value) (Value, error) {
+	m, err := mapArg("delete", args[0])
+	if err != nil {
+		return nilValue, err
+	}
+	if err := checkKey(args[1]); err != nil {
+		return nilValue, fmt.Errorf("delete: %w", err)
+	}
+	return boolValue(m.delete(args[1])), nil
+}
+
+func nativePush(vm *VM, args []Value) (Value, error) {
+	l, ok := args[0].obj.(*List)
+	if !ok {
+		return nilValue, fmt.Errorf("push: expected a list, got %s", args[0])
+	}
+	l.elements = append(l.elements, args[1])
+	return nilValue, nil
+}
+
+func nativeInput(vm *VM, args []Value) (Value, error) {
+	s, err := vm.reader.ReadString('\n')
+	return objValue(s), err
+}
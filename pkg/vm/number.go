@@ -0,0 +1,463 @@
+package vm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// numbers are exact integers, exact decimals, or float64s, and follow the interpreter.
+// integers are int64s, and are promoted to *big.Ints when they overflow, so that they never wrap around.
+// an operation on two integers results in an integer, except for "/" which always divides as floats.
+// mixing numbers promotes them to the less exact of the two: integers to decimals, and either of them to floats.
+
+// integerBits is the maximum number of bits of the integers computed by ** and <<,
+// the default limit of the interpreter, so that a single operation can't exhaust memory.
+const integerBits = 1 << 20
+
+func (v Value) isNumber() bool {
+	switch v.typ {
+	case valInt, valFloat:
+		return true
+	case valObj:
+		switch v.obj.(type) {
+		case *big.Int, Decimal:
+			return true
+		}
+	}
+	return false
+}
+
+func (v Value) isInteger() bool {
+	if v.typ == valInt {
+		return true
+	}
+	_, ok := v.obj.(*big.Int)
+	return ok
+}
+
+func (v Value) asFloat() float64 {
+	switch v.typ {
+	case valInt:
+		return float64(v.int)
+	case valFloat:
+		return v.num
+	}
+	switch n := v.obj.(type) {
+	case *big.Int:
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f
+	case Decimal:
+		f, _ := n.r.Float64()
+		return f
+	}
+	return 0
+}
+
+func (v Value) toBig() (*big.Int, bool) {
+	if v.typ == valInt {
+		return big.NewInt(v.int), true
+	}
+	n, ok := v.obj.(*big.Int)
+	return n, ok
+}
+
+// toRat converts an exact number, an integer or a decimal, to a rational.
+func (v Value) toRat() (*big.Rat, bool) {
+	if v.typ == valInt {
+		return new(big.Rat).SetInt64(v.int), true
+	}
+	switch n := v.obj.(type) {
+	case *big.Int:
+		return new(big.Rat).SetInt(n), true
+	case Decimal:
+		return n.r, true
+	}
+	return nil, false
+}
+
+// bigValue returns the integer as an int64 when it fits in one.
+func bigValue(n *big.Int) Value {
+	if n.IsInt64() {
+		return intValue(n.Int64())
+	}
+	return objValue(n)
+}
+
+// toInt converts an integral number to an int, so that 2.0 indexes a list like 2 does.
+func (v Value) toInt() (int, bool) {
+	switch v.typ {
+	case valInt:
+		return int(v.int), true
+	case valFloat:
+		if v.num != math.Trunc(v.num) || v.num >= 0x1p63 || v.num < -0x1p63 {
+			return 0, false
+		}
+		return int(v.num), true
+	}
+	if d, ok := v.obj.(Decimal); ok && d.r.IsInt() && d.r.Num().IsInt64() {
+		return int(d.r.Num().Int64()), true
+	}
+	return 0, false
+}
+
+// arithmetic applies one of the arithmetic or bitwise operators to two numbers.
+func arithmetic(op OpCode, a, b Value) (Value, error) {
+	if a.typ == valInt && b.typ == valInt {
+		return intArithmetic(op, a.int, b.int)
+	}
+
+	switch op {
+	case OpBitAnd, OpBitOr, OpBitXor, OpShiftLeft, OpShiftRight:
+		l, lok := a.toBig()
+		r, rok := b.toBig()
+		if !lok || !rok {
+			return nilValue, fmt.Errorf("operands must be integers, got %s and %s", a, b)
+		}
+		return bigArithmetic(op, l, r)
+	}
+	if !a.isNumber() || !b.isNumber() {
+		return nilValue, fmt.Errorf("operands must be numbers, got %s and %s", a, b)
+	}
+	if a.typ == valFloat || b.typ == valFloat {
+		return floatValue(floatArithmetic(op, a.asFloat(), b.asFloat())), nil
+	}
+	if a.isInteger() && b.isInteger() {
+		l, _ := a.toBig()
+		r, _ := b.toBig()
+		return bigArithmetic(op, l, r)
+	}
+	l, _ := a.toRat()
+	r, _ := b.toRat()
+	return decimalArithmetic(op, l, r)
+}
+
+func floatArithmetic(op OpCode, l, r float64) float64 {
+	switch op {
+	case OpAdd:
+		return l + r
+	case OpSubtract:
+		return l - r
+	case OpMultiply:
+		return l * r
+	case OpDivide:
+		return l / r
+	case OpFloorDivide:
+		return math.Floor(l / r)
+	case OpModulo:
+		// the remainder has the sign of the divisor, so that it agrees with //.
+		m := math.Mod(l, r)
+		if m != 0 && (m < 0) != (r < 0) {
+			m += r
+		}
+		return m
+	default: // OpPower
+		return math.Pow(l, r)
+	}
+}
+
+// intArithmetic computes with int64s, falling back to *big.Ints when the result would overflow.
+func intArithmetic(op OpCode, l, r int64) (Value, error) {
+	switch op {
+	case OpDivide, OpFloorDivide, OpModulo:
+		if r == 0 {
+			return nilValue, errDivisionByZero
+		}
+	}
+
+	switch op {
+	case OpAdd:
+		if s := l + r; (s > l) == (r > 0) {
+			return intValue(s), nil
+		}
+	case OpSubtract:
+		if d := l - r; (d < l) == (r > 0) {
+			return intValue(d), nil
+		}
+	case OpMultiply:
+		if p, ok := mul(l, r); ok {
+			return intValue(p), nil
+		}
+	case OpDivide:
+		return floatValue(float64(l) / float64(r)), nil
+	case OpFloorDivide:
+		if l == math.MinInt64 && r == -1 {
+			break
+		}
+		// rounds towards negative infinity, rather than zero.
+		q := l / r
+		if l%r != 0 && (l < 0) != (r < 0) {
+			q--
+		}
+		return intValue(q), nil
+	case OpModulo:
+		m := l % r
+		if m != 0 && (m < 0) != (r < 0) {
+			m += r
+		}
+		return intValue(m), nil
+	case OpPower:
+		if r < 0 {
+			return floatValue(math.Pow(float64(l), float64(r))), nil
+		}
+		if p, ok := intPow(l, r); ok {
+			return intValue(p), nil
+		}
+	case OpBitAnd:
+		return intValue(l & r), nil
+	case OpBitOr:
+		return intValue(l | r), nil
+	case OpBitXor:
+		return intValue(l ^ r), nil
+	case OpShiftLeft:
+		if r < 0 {
+			return nilValue, fmt.Errorf("negative shift count %d", r)
+		}
+		if l == 0 {
+			return intValue(0), nil
+		}
+		if r < 63 && (l<<r)>>r == l {
+			return intValue(l << r), nil
+		}
+	case OpShiftRight:
+		if r < 0 {
+			return nilValue, fmt.Errorf("negative shift count %d", r)
+		}
+		return intValue(l >> min(r, 63)), nil
+	default:
+		return nilValue, fmt.Errorf("unknown arithmetic operator %s", op)
+	}
+	return bigArithmetic(op, big.NewInt(l), big.NewInt(r))
+}
+
+// mul multiplies two int64s, and reports whether the product didn't overflow.
+func mul(l, r int64) (int64, bool) {
+	p := l * r
+	return p, l == 0 || (p/l == r && !(l == -1 && r == math.MinInt64))
+}
+
+// intPow raises the base to a non-negative exponent by squaring, and reports whether it didn't overflow.
+func intPow(base, exp int64) (int64, bool) {
+	result := int64(1)
+	ok := true
+	for exp > 0 {
+		if exp&1 == 1 {
+			if result, ok = mul(result, base); !ok {
+				return 0, false
+			}
+		}
+		exp >>= 1
+		if exp > 0 {
+			if base, ok = mul(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+var errDivisionByZero = errors.New("division by zero")
+
+// bigArithmetic computes with *big.Ints, failing rather than computing integers of more than integerBits.
+func bigArithmetic(op OpCode, l, r *big.Int) (Value, error) {
+	switch op {
+	case OpDivide, OpFloorDivide, OpModulo:
+		if r.Sign() == 0 {
+			return nilValue, errDivisionByZero
+		}
+	}
+
+	n := new(big.Int)
+	switch op {
+	case OpAdd:
+		n.Add(l, r)
+	case OpSubtract:
+		n.Sub(l, r)
+	case OpMultiply:
+		n.Mul(l, r)
+	case OpDivide:
+		f, _ := new(big.Rat).SetFrac(l, r).Float64()
+		return floatValue(f), nil
+	case OpFloorDivide, OpModulo:
+		q, m := n.QuoRem(l, r, new(big.Int))
+		// rounds towards negative infinity, rather than zero.
+		if m.Sign() != 0 && (m.Sign() < 0) != (r.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+			m.Add(m, r)
+		}
+		if op == OpModulo {
+			return bigValue(m), nil
+		}
+	case OpPower:
+		if r.Sign() < 0 {
+			return floatValue(math.Pow(objValue(l).asFloat(), objValue(r).asFloat())), nil
+		}
+		if powTooLarge(l, r) {
+			return nilValue, fmt.Errorf("exponent %v is too large, the result would take more than %d bits", r, integerBits)
+		}
+		n.Exp(l, r, nil)
+	case OpBitAnd:
+		n.And(l, r)
+	case OpBitOr:
+		n.Or(l, r)
+	case OpBitXor:
+		n.Xor(l, r)
+	case OpShiftLeft, OpShiftRight:
+		if r.Sign() < 0 {
+			return nilValue, fmt.Errorf("negative shift count %v", r)
+		}
+		if op == OpShiftRight {
+			// shifting out all the bits leaves 0, or -1 for negative integers, however large the count.
+			shift := uint64(l.BitLen())
+			if r.IsUint64() {
+				shift = min(shift, r.Uint64())
+			}
+			n.Rsh(l, uint(shift))
+			break
+		}
+		if l.Sign() != 0 && (!r.IsUint64() || r.Uint64() > integerBits || uint64(l.BitLen())+r.Uint64() > integerBits) {
+			return nilValue, fmt.Errorf("shift count %v is too large, the result would take more than %d bits", r, integerBits)
+		}
+		if l.Sign() != 0 {
+			n.Lsh(l, uint(r.Uint64()))
+		}
+	default:
+		return nilValue, fmt.Errorf("unknown arithmetic operator %s", op)
+	}
+	return bigValue(n), nil
+}
+
+// powTooLarge reports whether raising the base to a non-negative exponent could take more than integerBits,
+// so that a power can't exhaust memory.
+func powTooLarge(base, exp *big.Int) bool {
+	if base.CmpAbs(big.NewInt(1)) <= 0 {
+		return false
+	}
+	// the power takes floor(exp * log2(|base|)) + 1 bits.
+	mant := new(big.Float)
+	e := new(big.Float).SetInt(base).MantExp(mant)
+	m, _ := mant.Float64()
+	x, _ := new(big.Float).SetInt(exp).Float64()
+	return x*(float64(e)+math.Log2(math.Abs(m))) >= integerBits
+}
+
+// decimalArithmetic computes with decimals, rounding quotients to decimalPrecision digits.
+func decimalArithmetic(op OpCode, l, r *big.Rat) (Value, error) {
+	switch op {
+	case OpDivide, OpFloorDivide, OpModulo:
+		if r.Sign() == 0 {
+			return nilValue, errDivisionByZero
+		}
+	}
+
+	n := new(big.Rat)
+	switch op {
+	case OpAdd:
+		n.Add(l, r)
+	case OpSubtract:
+		n.Sub(l, r)
+	case OpMultiply:
+		n.Mul(l, r)
+	case OpDivide:
+		n = round(n.Quo(l, r), decimalPrecision)
+	case OpFloorDivide:
+		n.SetInt(floor(n.Quo(l, r)))
+	case OpModulo:
+		q := new(big.Rat).SetInt(floor(n.Quo(l, r)))
+		n.Sub(l, q.Mul(q, r))
+	case OpPower:
+		if !r.IsInt() || !r.Num().IsInt64() {
+			lf, _ := l.Float64()
+			rf, _ := r.Float64()
+			return floatValue(math.Pow(lf, rf)), nil
+		}
+		exp := new(big.Int).Abs(r.Num())
+		if powTooLarge(l.Num(), exp) || powTooLarge(l.Denom(), exp) {
+			return nilValue, fmt.Errorf("exponent %v is too large, the result would take more than %d bits", r.RatString(), integerBits)
+		}
+		n.SetFrac(new(big.Int).Exp(l.Num(), exp, nil), new(big.Int).Exp(l.Denom(), exp, nil))
+		if r.Sign() < 0 {
+			if n.Sign() == 0 {
+				return nilValue, errDivisionByZero
+			}
+			n = round(n.Inv(n), decimalPrecision)
+		}
+	default:
+		return nilValue, fmt.Errorf("unknown arithmetic operator %s", op)
+	}
+	return objValue(Decimal{n}), nil
+}
+
+// comparison applies one of the comparison operators to two numbers.
+func comparison(op OpCode, a, b Value) (Value, error) {
+	if a.typ == valInt && b.typ == valInt {
+		return boolValue(compare(op, a.int, b.int)), nil
+	}
+	if !a.isNumber() || !b.isNumber() {
+		return nilValue, fmt.Errorf("operands must be numbers, got %s and %s", a, b)
+	}
+	l, lok := a.toRat()
+	r, rok := b.toRat()
+	if lok && rok {
+		return boolValue(compare(op, int64(l.Cmp(r)), 0)), nil
+	}
+	return boolValue(compare(op, a.asFloat(), b.asFloat())), nil
+}
+
+func compare[T int64 | float64](op OpCode, l, r T) bool {
+	switch op {
+	case OpGreater:
+		return l > r
+	case OpGreaterEqual:
+		return l >= r
+	case OpLess:
+		return l < r
+	default: // OpLessEqual
+		return l <= r
+	}
+}
+
+// negate negates a number.
+func negate(v Value) (Value, error) {
+	switch v.typ {
+	case valInt:
+		if v.int == math.MinInt64 {
+			return objValue(new(big.Int).Neg(big.NewInt(v.int))), nil
+		}
+		return intValue(-v.int), nil
+	case valFloat:
+		return floatValue(-v.num), nil
+	}
+	switch n := v.obj.(type) {
+	case *big.Int:
+		return bigValue(new(big.Int).Neg(n)), nil
+	case Decimal:
+		return objValue(Decimal{new(big.Rat).Neg(n.r)}), nil
+	}
+	return nilValue, fmt.Errorf("operand must be a number, got %s", v)
+}
+
+// bitNot flips the bits of an integer.
+func bitNot(v Value) (Value, error) {
+	if v.typ == valInt {
+		return intValue(^v.int), nil
+	}
+	if n, ok := v.obj.(*big.Int); ok {
+		return bigValue(new(big.Int).Not(n)), nil
+	}
+	return nilValue, fmt.Errorf("operand must be an integer, got %s", v)
+}
+
+// numbersEqual compares numbers by their value, so that 1 == 1.0 == 1d.
+func numbersEqual(a, b Value) bool {
+	if a.typ == valInt && b.typ == valInt {
+		return a.int == b.int
+	}
+	l, lok := a.toRat()
+	r, rok := b.toRat()
+	if lok && rok {
+		return l.Cmp(r) == 0
+	}
+	return a.asFloat() == b.asFloat()
+}
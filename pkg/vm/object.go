@@ -0,0 +1,251 @@
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Function is a compiled function body.
+type Function struct {
+	Name         string
+	Arity        int
+	UpvalueCount int
+	// Generator is whether the body yields, so that calling the function returns a generator rather than running the body.
+	Generator bool
+	Chunk     Chunk
+}
+
+func (f *Function) String() string {
+	if f.Name == "" {
+		return "<script>"
+	}
+	return fmt.Sprintf("<fn %s>", f.Name)
+}
+
+// Closure is a function, along with the variables it captured.
+type Closure struct {
+	fn       *Function
+	upvalues []*Upvalue
+	// globals of the program or of the module the function is defined in
+	globals map[string]Value
+}
+
+func (c *Closure) String() string {
+	return c.fn.String()
+}
+
+// Upvalue is a variable captured by a closure.
+// while the variable is still on the stack, location points into the stack.
+// once the variable goes out of scope, it is moved into closed.
+type Upvalue struct {
+	location *Value
+	closed   Value
+	// slot is the stack slot of the variable, while the upvalue is open.
+	slot int
+	// next open upvalue, ordered by descending slot.
+	next *Upvalue
+}
+
+type Native struct {
+	Name string
+	// -1 for variadic functions
+	arity int
+	fn    func(vm *VM, args []Value) (Value, error)
+}
+
+func (n *Native) String() string {
+	return fmt.Sprintf("<native fn %s>", n.Name)
+}
+
+type Class struct {
+	Name    string
+	methods map[string]*Closure
+}
+
+func (c *Class) String() string {
+	return c.Name
+}
+
+type Instance struct {
+	class  *Class
+	fields map[string]Value
+}
+
+func (in *Instance) String() string {
+	return fmt.Sprintf("%s instance", in.class.Name)
+}
+
+// List is a growable sequence of values, compared by reference.
+type List struct {
+	elements []Value
+}
+
+func (l *List) String() string {
+	parts := make([]string, len(l.elements))
+	for i, e := range l.elements {
+		parts[i] = e.repr()
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
+
+// index checks that the value is a valid index into the list.
+func (l *List) index(v Value) (int, error) {
+	idx, ok := v.toInt()
+	if !ok {
+		return 0, fmt.Errorf("list index must be an integer, got %s", v)
+	}
+	if idx < 0 {
+		return 0, fmt.Errorf("negative list index %d", idx)
+	}
+	if idx >= len(l.elements) {
+		return 0, fmt.Errorf("list index %d out of range for length %d", idx, len(l.elements))
+	}
+	return idx, nil
+}
+
+// stringIndex returns the code point at the index of the string, as a string of its own.
+// strings are indexed by code points, rather than bytes, so that "é"[0] is "é".
+func stringIndex(s string, v Value) (Value, error) {
+	idx, ok := v.toInt()
+	if !ok {
+		return nilValue, fmt.Errorf("string index must be an integer, got %s", v)
+	}
+	if idx < 0 {
+		return nilValue, fmt.Errorf("negative string index %d", idx)
+	}
+	count := 0
+	for _, c := range s {
+		if count == idx {
+			return objValue(string(c)), nil
+		}
+		count++
+	}
+	return nilValue, fmt.Errorf("string index %d out of range for length %d", idx, count)
+}
+
+// Map is an associative container, iterated in insertion order.
+// keys are limited to strings, numbers, booleans and nil, which are comparable as Values once normalized.
+type Map struct {
+	// keys are kept as they were first set, while entries are keyed by their normalized value.
+	keys    []Value
+	entries map[Value]Value
+}
+
+func newMap() *Map {
+	return &Map{entries: make(map[Value]Value)}
+}
+
+func checkKey(k Value) error {
+	if k.typ == valObj && !k.isNumber() {
+		if _, ok := k.asString(); !ok {
+			return fmt.Errorf("unhashable map key %s: keys must be strings, numbers, booleans or nil", k)
+		}
+	}
+	return nil
+}
+
+// normalize makes numbers that are equal the same key, so that m[1], m[1.0] and m[1d] are one entry.
+// integers too big for an int64, and decimals with a fraction, are keyed by their text.
+func normalize(k Value) Value {
+	if k.typ == valInt || !k.isNumber() {
+		return k
+	}
+	if n, ok := k.toInt(); ok {
+		return intValue(int64(n))
+	}
+	switch n := k.obj.(type) {
+	case *big.Int:
+		return objValue(bigKey(n.String()))
+	case Decimal:
+		if f, _ := n.r.Float64(); n.r.Cmp(new(big.Rat).SetFloat64(f)) == 0 {
+			return floatValue(f)
+		}
+		return objValue(bigKey(n.String()))
+	}
+	return k
+}
+
+// bigKey is the key of a big number, which doesn't compare by value by itself.
+type bigKey string
+
+func (m *Map) get(k Value) (Value, bool) {
+	v, ok := m.entries[normalize(k)]
+	return v, ok
+}
+
+func (m *Map) set(k, v Value) {
+	nk := normalize(k)
+	if _, ok := m.entries[nk]; !ok {
+		m.keys = append(m.keys, k)
+	}
+	m.entries[nk] = v
+}
+
+func (m *Map) delete(k Value) bool {
+	k = normalize(k)
+	if _, ok := m.entries[k]; !ok {
+		return false
+	}
+	delete(m.entries, k)
+	for idx, key := range m.keys {
+		if normalize(key) == k {
+			m.keys = append(m.keys[:idx], m.keys[idx+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+func (m *Map) String() string {
+	parts := make([]string, len(m.keys))
+	for idx, k := range m.keys {
+		v, _ := m.get(k)
+		parts[idx] = fmt.Sprintf("%s: %s", k.repr(), v.repr())
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// BoundMethod is a method whose 'this' is bound to the receiver.
+type BoundMethod struct {
+	receiver Value
+	method   *Closure
+}
+
+func (b *BoundMethod) String() string {
+	return b.method.String()
+}
+
+// Thrown is the error of a throw statement, carrying the thrown value up to the catch block.
+type Thrown struct {
+	Value Value
+}
+
+func (e *Thrown) Error() string {
+	if errValue, ok := e.Value.obj.(*ErrorValue); ok {
+		// rethrown runtime errors read as they did originally.
+		return errValue.Message
+	}
+	return fmt.Sprintf("uncaught exception: %s", e.Value.repr())
+}
+
+// ErrorValue is how runtime errors are seen by catch blocks. its message and line are read as properties.
+type ErrorValue struct {
+	Message string
+	Line    int
+}
+
+func (e *ErrorValue) String() string {
+	return fmt.Sprintf("<error: %s>", e.Message)
+}
+
+func (e *ErrorValue) property(name string) (Value, bool) {
+	switch name {
+	case "message":
+		return objValue(e.Message), true
+	case "line":
+		return intValue(int64(e.Line)), true
+	default:
+		return nilValue, false
+	}
+}
@@ -0,0 +1,112 @@
+package vm
+
+import (
+	"fmt"
+	"strconv"
+)
+
+type valueType uint8
+
+const (
+	valNil valueType = iota
+	valBool
+	valInt
+	valFloat
+	// strings and heap objects (closures, classes, instances...)
+	valObj
+)
+
+// Value is an unboxed script value.
+// numbers and booleans are stored inline, integers in int and the rest in num, so that arithmetic does not allocate.
+type Value struct {
+	typ valueType
+	num float64
+	int int64
+	obj any
+}
+
+var nilValue = Value{typ: valNil}
+
+func boolValue(b bool) Value {
+	if b {
+		return Value{typ: valBool, num: 1}
+	}
+	return Value{typ: valBool}
+}
+
+func intValue(n int64) Value {
+	return Value{typ: valInt, int: n}
+}
+
+func floatValue(n float64) Value {
+	return Value{typ: valFloat, num: n}
+}
+
+func objValue(o any) Value {
+	return Value{typ: valObj, obj: o}
+}
+
+func (v Value) asString() (string, bool) {
+	if v.typ != valObj {
+		return "", false
+	}
+	s, ok := v.obj.(string)
+	return s, ok
+}
+
+// truthy follows the interpreter: nil and false are falsy, everything else is truthy.
+func (v Value) truthy() bool {
+	switch v.typ {
+	case valNil:
+		return false
+	case valBool:
+		return v.num != 0
+	default:
+		return true
+	}
+}
+
+// String formats the value the same way the interpreter prints it.
+func (v Value) String() string {
+	switch v.typ {
+	case valNil:
+		return "<nil>"
+	case valBool:
+		return fmt.Sprint(v.num != 0)
+	case valInt:
+		return strconv.FormatInt(v.int, 10)
+	case valFloat:
+		return fmt.Sprint(v.num)
+	default:
+		return fmt.Sprint(v.obj)
+	}
+}
+
+// repr formats a value nested inside a container, quoting strings.
+func (v Value) repr() string {
+	if v.typ == valNil {
+		return "nil"
+	}
+	if s, ok := v.asString(); ok {
+		return strconv.Quote(s)
+	}
+	return v.String()
+}
+
+// valuesEqual compares numbers, bools and strings by value, so that 1 == 1.0, and objects by identity.
+func valuesEqual(a, b Value) bool {
+	if a.isNumber() && b.isNumber() {
+		return numbersEqual(a, b)
+	}
+	if a.typ != b.typ {
+		return false
+	}
+	switch a.typ {
+	case valNil:
+		return true
+	case valBool:
+		return a.num == b.num
+	default:
+		return a.obj == b.obj
+	}
+}
@@ -0,0 +1,806 @@
+package vm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/token"
+)
+
+const (
+	framesMax = 1024
+	stackMax  = framesMax * 64
+	// maximum number of slots a single frame may use: 256 locals, plus some temporaries.
+	frameSlotsMax = 256 + 64
+)
+
+type callFrame struct {
+	closure *Closure
+	ip      int
+	// index of the frame's slot zero in the stack
+	base int
+}
+
+// tokenAt returns the token of the instruction being run.
+func (f *callFrame) tokenAt() token.Token {
+	return f.closure.fn.Chunk.TokenAt(f.ip - 1)
+}
+
+// handler is where a try statement catches the errors thrown by its body.
+type handler struct {
+	// frameCount and sp are unwound to, before jumping to the ip of the handler in the frame of the try.
+	frameCount int
+	sp         int
+	ip         int
+}
+
+// errStackOverflow can't be caught, so that scripts can't escape the limit on the depth of calls.
+var errStackOverflow = errors.New("stack overflow")
+
+type VM struct {
+	// the stack is allocated once and never grown, as open upvalues point into it.
+	stack []Value
+	sp    int
+
+	frames     []callFrame
+	frameCount int
+
+	// globals of the program, and natives shared by the program and the modules it imports
+	globals      map[string]Value
+	builtins     map[string]Value
+	openUpvalues *Upvalue
+	// handlers of the try statements whose bodies are running, innermost last
+	handlers []handler
+	// generators whose bodies are running, innermost last
+	resumed []resumed
+	// generators whose bodies have started, and are suspended
+	suspended map[*Generator]struct{}
+
+	loader Loader
+	// modules run so far, by the absolute path of their file
+	modules map[string]*Namespace
+
+	writer io.Writer
+	reader *bufio.Reader
+}
+
+func New(writer io.Writer) *VM {
+	vm := &VM{
+		stack:     make([]Value, stackMax),
+		frames:    make([]callFrame, framesMax),
+		globals:   make(map[string]Value),
+		builtins:  make(map[string]Value),
+		modules:   make(map[string]*Namespace),
+		suspended: make(map[*Generator]struct{}),
+		writer:    writer,
+		reader:    bufio.NewReader(os.Stdin),
+	}
+
+	vm.defineNative("clock", 0, nativeClock)
+	vm.defineNative("print", -1, nativePrint)
+	vm.defineNative("input", 0, nativeInput)
+	vm.defineNative("len", 1, nativeLen)
+	vm.defineNative("substr", 3, nativeSubstr)
+	vm.defineNative("push", 2, nativePush)
+	vm.defineNative("keys", 1, nativeKeys)
+	vm.defineNative("values", 1, nativeValues)
+	vm.defineNative("has", 2, nativeHas)
+	vm.defineNative("delete", 2, nativeDelete)
+	vm.defineNative("range", -1, nativeRange)
+	vm.builtins["done"] = doneValue
+
+	return vm
+}
+
+func (vm *VM) defineNative(name string, arity int, fn func(vm *VM, args []Value) (Value, error)) {
+	vm.builtins[name] = objValue(&Native{Name: name, arity: arity, fn: fn})
+}
+
+// Interprete runs the compiled top-level script function.
+func (vm *VM) Interprete(fn *Function) error {
+	closure := &Closure{fn: fn, globals: vm.globals}
+	vm.push(objValue(closure))
+	if err := vm.call(closure, 0); err != nil {
+		return err
+	}
+	err := vm.run()
+	for err != nil && vm.handle(err) {
+		err = vm.run()
+	}
+	if err != nil {
+		vm.resetStack()
+	}
+	return err
+}
+
+func (vm *VM) resetStack() {
+	vm.sp = 0
+	vm.frameCount = 0
+	vm.openUpvalues = nil
+	vm.handlers = vm.handlers[:0]
+	for _, r := range vm.resumed {
+		r.generator.state = generatorDone
+	}
+	vm.resumed = vm.resumed[:0]
+}
+
+// handle unwinds the stack to the innermost handler, and pushes the error for it to catch.
+// it reports whether there was a handler to catch the error, or whether it was done closing a generator.
+func (vm *VM) handle(err error) bool {
+	if errors.Is(err, errStackOverflow) || errors.As(err, new(*loadError)) {
+		return false
+	}
+	if vm.leaveGenerators(err) {
+		return true
+	}
+	if len(vm.handlers) == 0 {
+		return false
+	}
+	h := vm.handlers[len(vm.handlers)-1]
+	vm.handlers = vm.handlers[:len(vm.handlers)-1]
+	vm.closeUpvalues(h.sp)
+	vm.frameCount = h.frameCount
+	vm.sp = h.sp
+	vm.frames[h.frameCount-1].ip = h.ip
+	vm.push(objValue(err))
+	return true
+}
+
+// caught returns the value a catch block sees for the error: the thrown value, or the runtime error as an ErrorValue.
+func caught(err error) Value {
+	var thrown *Thrown
+	if errors.As(err, &thrown) {
+		return thrown.Value
+	}
+	var rerr *diagnostic.RuntimeError
+	if errors.As(err, &rerr) {
+		return objValue(&ErrorValue{Message: rerr.Msg, Line: rerr.Line})
+	}
+	return objValue(&ErrorValue{Message: err.Error()})
+}
+
+func (vm *VM) push(v Value) {
+	vm.stack[vm.sp] = v
+	vm.sp++
+}
+
+func (vm *VM) pop() Value {
+	vm.sp--
+	return vm.stack[vm.sp]
+}
+
+func (vm *VM) peek(distance int) Value {
+	return vm.stack[vm.sp-1-distance]
+}
+
+// runtimeError reports the error at the token of the current instruction, along with the stack of calls.
+func (vm *VM) runtimeError(format string, args ...any) *diagnostic.RuntimeError {
+	frame := &vm.frames[vm.frameCount-1]
+	err := diagnostic.NewRuntimeError(frame.tokenAt(), format, args...)
+	err.Stack = make([]diagnostic.Frame, 0, vm.frameCount)
+	for i := vm.frameCount - 1; i >= 0; i-- {
+		f := &vm.frames[i]
+		// the script's frame is the only one with no caller.
+		var call token.Token
+		if i > 0 {
+			call = vm.frames[i-1].tokenAt()
+		}
+		err.Stack = append(err.Stack, diagnostic.Frame{
+			Function: f.closure.fn.String(),
+			Call:     call,
+			Position: diagnostic.At(f.tokenAt()),
+		})
+	}
+	return err
+}
+
+// undefinedVariable reports the name, along with a hint for a similar global, in case it is a typo.
+// locals are resolved by the compiler, so only globals can be undefined.
+func (vm *VM) undefinedVariable(name string) error {
+	err := vm.runtimeError("undefined variable '%s'", name)
+	globals := vm.frames[vm.frameCount-1].closure.globals
+	names := make([]string, 0, len(globals)+len(vm.builtins))
+	for global := range globals {
+		names = append(names, global)
+	}
+	for builtin := range vm.builtins {
+		names = append(names, builtin)
+	}
+	if similar := diagnostic.Suggest(name, names); similar != "" {
+		err.Hint = fmt.Sprintf("did you mean '%s'?", similar)
+	}
+	return err
+}
+
+func (vm *VM) run() error {
+	frame := &vm.frames[vm.frameCount-1]
+	code := frame.closure.fn.Chunk.Code
+	constants := frame.closure.fn.Chunk.Constants
+
+	readShort := func() int {
+		frame.ip += 2
+		return int(code[frame.ip-2])<<8 | int(code[frame.ip-1])
+	}
+	readName := func() string {
+		return constants[readShort()].obj.(string)
+	}
+	// reload caches the current frame, after a call or a return
+	reload := func() {
+		frame = &vm.frames[vm.frameCount-1]
+		code = frame.closure.fn.Chunk.Code
+		constants = frame.closure.fn.Chunk.Constants
+	}
+
+	for {
+		op := OpCode(code[frame.ip])
+		frame.ip++
+
+		switch op {
+		case OpConstant:
+			vm.push(constants[readShort()])
+		case OpNil:
+			vm.push(nilValue)
+		case OpTrue:
+			vm.push(boolValue(true))
+		case OpFalse:
+			vm.push(boolValue(false))
+		case OpPop:
+			vm.sp--
+		case OpDup:
+			vm.push(vm.peek(0))
+		case OpDup2:
+			vm.push(vm.peek(1))
+			vm.push(vm.peek(1))
+		case OpTuck:
+			depth := int(code[frame.ip])
+			frame.ip++
+			top := vm.peek(0)
+			copy(vm.stack[vm.sp-depth:vm.sp+1], vm.stack[vm.sp-1-depth:vm.sp])
+			vm.stack[vm.sp-1-depth] = top
+			vm.sp++
+		case OpGetLocal:
+			slot := int(code[frame.ip])
+			frame.ip++
+			vm.push(vm.stack[frame.base+slot])
+		case OpSetLocal:
+			slot := int(code[frame.ip])
+			frame.ip++
+			vm.stack[frame.base+slot] = vm.peek(0)
+		case OpGetGlobal:
+			name := readName()
+			v, ok := frame.closure.globals[name]
+			if !ok {
+				v, ok = vm.builtins[name]
+			}
+			if !ok {
+				return vm.undefinedVariable(name)
+			}
+			vm.push(v)
+		case OpDefineGlobal:
+			frame.closure.globals[readName()] = vm.pop()
+		case OpSetGlobal:
+			name := readName()
+			globals := frame.closure.globals
+			if _, ok := globals[name]; !ok {
+				if _, ok := vm.builtins[name]; !ok {
+					return vm.undefinedVariable(name)
+				}
+				// assigning a native changes it for the modules too.
+				globals = vm.builtins
+			}
+			globals[name] = vm.peek(0)
+		case OpGetUpvalue:
+			slot := code[frame.ip]
+			frame.ip++
+			vm.push(*frame.closure.upvalues[slot].location)
+		case OpSetUpvalue:
+			slot := code[frame.ip]
+			frame.ip++
+			*frame.closure.upvalues[slot].location = vm.peek(0)
+		case OpGetProperty:
+			name := readName()
+			if errValue, ok := vm.peek(0).obj.(*ErrorValue); ok {
+				v, ok := errValue.property(name)
+				if !ok {
+					return vm.runtimeError("undefined property '%s'", name)
+				}
+				vm.stack[vm.sp-1] = v
+				break
+			}
+			if g, ok := vm.peek(0).obj.(*Generator); ok {
+				v, ok := g.property(name)
+				if !ok {
+					return vm.runtimeError("undefined property '%s'", name)
+				}
+				vm.stack[vm.sp-1] = v
+				break
+			}
+			if m, ok := vm.peek(0).obj.(*Namespace); ok {
+				v, err := m.property(vm, name)
+				if err != nil {
+					return err
+				}
+				vm.stack[vm.sp-1] = v
+				break
+			}
+			instance, ok := vm.peek(0).obj.(*Instance)
+			if !ok {
+				return vm.runtimeError("only instances have properties")
+			}
+			if v, ok := instance.fields[name]; ok {
+				vm.stack[vm.sp-1] = v
+				break
+			}
+			method, ok := instance.class.methods[name]
+			if !ok {
+				return vm.runtimeError("undefined property '%s'", name)
+			}
+			vm.stack[vm.sp-1] = objValue(&BoundMethod{receiver: vm.peek(0), method: method})
+		case OpSetProperty:
+			name := readName()
+			if _, ok := vm.peek(1).obj.(*ErrorValue); ok {
+				return vm.runtimeError("can't set property '%s' of an error", name)
+			}
+			if _, ok := vm.peek(1).obj.(*Generator); ok {
+				return vm.runtimeError("can't set property '%s' of a generator", name)
+			}
+			if m, ok := vm.peek(1).obj.(*Namespace); ok {
+				return vm.runtimeError("can't assign to '%s' of module %s", name, m.name)
+			}
+			instance, ok := vm.peek(1).obj.(*Instance)
+			if !ok {
+				return vm.runtimeError("only instances have fields")
+			}
+			v := vm.pop()
+			instance.fields[name] = v
+			vm.stack[vm.sp-1] = v
+		case OpGetSuper:
+			name := readName()
+			superclass := vm.pop().obj.(*Class)
+			method, ok := superclass.methods[name]
+			if !ok {
+				return vm.runtimeError("undefined property '%s'", name)
+			}
+			vm.stack[vm.sp-1] = objValue(&BoundMethod{receiver: vm.peek(0), method: method})
+		case OpEqual:
+			b := vm.pop()
+			vm.stack[vm.sp-1] = boolValue(valuesEqual(vm.peek(0), b))
+		case OpNotEqual:
+			b := vm.pop()
+			vm.stack[vm.sp-1] = boolValue(!valuesEqual(vm.peek(0), b))
+		case OpGreater, OpGreaterEqual, OpLess, OpLessEqual:
+			v, err := comparison(op, vm.peek(1), vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = v
+		case OpSubtract, OpMultiply, OpDivide, OpFloorDivide, OpModulo, OpPower,
+			OpBitAnd, OpBitOr, OpBitXor, OpShiftLeft, OpShiftRight:
+			v, err := arithmetic(op, vm.peek(1), vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = v
+		case OpAdd:
+			b, a := vm.peek(0), vm.peek(1)
+			if a.isNumber() && b.isNumber() {
+				v, err := arithmetic(op, a, b)
+				if err != nil {
+					return vm.runtimeError("%s", err)
+				}
+				vm.sp--
+				vm.stack[vm.sp-1] = v
+				break
+			}
+			as, aok := a.asString()
+			bs, bok := b.asString()
+			if !aok || !bok {
+				return vm.runtimeError("operands must be two numbers or two strings, got %s and %s", a, b)
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = objValue(as + bs)
+		case OpNot:
+			vm.stack[vm.sp-1] = boolValue(!vm.peek(0).truthy())
+		case OpNegate:
+			v, err := negate(vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.stack[vm.sp-1] = v
+		case OpIncrement, OpDecrement:
+			v := vm.peek(0)
+			if !v.isNumber() {
+				return vm.runtimeError("operand must be a number, got %s", v)
+			}
+			arith := OpAdd
+			if op == OpDecrement {
+				arith = OpSubtract
+			}
+			v, err := arithmetic(arith, v, intValue(1))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.stack[vm.sp-1] = v
+		case OpBitNot:
+			v, err := bitNot(vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.stack[vm.sp-1] = v
+		case OpJump:
+			offset := readShort()
+			frame.ip += offset
+		case OpJumpIfFalse:
+			offset := readShort()
+			if !vm.peek(0).truthy() {
+				frame.ip += offset
+			}
+		case OpLoop:
+			offset := readShort()
+			frame.ip -= offset
+		case OpCall:
+			argCount := int(code[frame.ip])
+			frame.ip++
+			if err := vm.callValue(vm.peek(argCount), argCount); err != nil {
+				return err
+			}
+			reload()
+		case OpClosure:
+			fn := constants[readShort()].obj.(*Function)
+			closure := &Closure{fn: fn, upvalues: make([]*Upvalue, fn.UpvalueCount), globals: frame.closure.globals}
+			for i := range closure.upvalues {
+				isLocal, index := code[frame.ip], int(code[frame.ip+1])
+				frame.ip += 2
+				if isLocal == 1 {
+					closure.upvalues[i] = vm.captureUpvalue(frame.base + index)
+				} else {
+					closure.upvalues[i] = frame.closure.upvalues[index]
+				}
+			}
+			vm.push(objValue(closure))
+		case OpCloseUpvalue:
+			vm.closeUpvalues(vm.sp - 1)
+			vm.sp--
+		case OpReturn:
+			result := vm.pop()
+			vm.closeUpvalues(frame.base)
+			if done, ok := vm.returning(); ok {
+				result = done
+			}
+			vm.frameCount--
+			if vm.frameCount == 0 {
+				vm.sp = 0
+				return nil
+			}
+			vm.sp = frame.base
+			vm.push(result)
+			reload()
+		case OpClass:
+			vm.push(objValue(&Class{Name: readName(), methods: make(map[string]*Closure)}))
+		case OpInherit:
+			superclass, ok := vm.peek(1).obj.(*Class)
+			if !ok {
+				return vm.runtimeError("superclass must be a class")
+			}
+			subclass := vm.peek(0).obj.(*Class)
+			for name, method := range superclass.methods {
+				subclass.methods[name] = method
+			}
+			vm.sp--
+		case OpMethod:
+			class := vm.peek(1).obj.(*Class)
+			class.methods[readName()] = vm.pop().obj.(*Closure)
+		case OpList:
+			count := readShort()
+			elements := make([]Value, count)
+			copy(elements, vm.stack[vm.sp-count:vm.sp])
+			vm.sp -= count
+			vm.push(objValue(&List{elements: elements}))
+		case OpConcat:
+			count := readShort()
+			var sb strings.Builder
+			for _, v := range vm.stack[vm.sp-count : vm.sp] {
+				// values are formatted as print does.
+				fmt.Fprint(&sb, v)
+			}
+			vm.sp -= count
+			vm.push(objValue(sb.String()))
+		case OpGetIndex:
+			v, err := getIndex(vm.peek(1), vm.peek(0))
+			if err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			vm.sp--
+			vm.stack[vm.sp-1] = v
+		case OpSetIndex:
+			if err := setIndex(vm.peek(2), vm.peek(1), vm.peek(0)); err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			v := vm.pop()
+			vm.sp--
+			vm.stack[vm.sp-1] = v
+		case OpMap:
+			count := readShort()
+			m := newMap()
+			entries := vm.stack[vm.sp-2*count : vm.sp]
+			for idx := 0; idx < len(entries); idx += 2 {
+				if err := checkKey(entries[idx]); err != nil {
+					return vm.runtimeError("%s", err)
+				}
+				m.set(entries[idx], entries[idx+1])
+			}
+			vm.sp -= 2 * count
+			vm.push(objValue(m))
+		case OpThrow:
+			thrown := &Thrown{Value: vm.pop()}
+			err := vm.runtimeError("%s", thrown)
+			err.Err = thrown
+			return err
+		case OpTry:
+			offset := readShort()
+			vm.handlers = append(vm.handlers, handler{frameCount: vm.frameCount, sp: vm.sp, ip: frame.ip + offset})
+		case OpPopHandler:
+			vm.handlers = vm.handlers[:len(vm.handlers)-1]
+		case OpCatch:
+			err := vm.peek(0).obj.(error)
+			if errors.Is(err, errClosed) {
+				// closing a generator runs its finally blocks, but not its catch blocks.
+				return err
+			}
+			vm.stack[vm.sp-1] = caught(err)
+		case OpRethrow:
+			return vm.pop().obj.(error)
+		case OpMatchList:
+			length := readShort()
+			l, ok := vm.peek(0).obj.(*List)
+			vm.stack[vm.sp-1] = boolValue(ok && len(l.elements) == length)
+		case OpMatchMap:
+			_, ok := vm.peek(0).obj.(*Map)
+			vm.stack[vm.sp-1] = boolValue(ok)
+		case OpHasKey:
+			if err := checkKey(vm.peek(0)); err != nil {
+				return vm.runtimeError("%s", err)
+			}
+			_, ok := vm.peek(1).obj.(*Map).get(vm.peek(0))
+			vm.sp--
+			vm.stack[vm.sp-1] = boolValue(ok)
+		case OpNoMatch:
+			return vm.runtimeError("no arm matches %s", vm.pop().repr())
+		case OpIter:
+			v := vm.peek(0)
+			if instance, ok := v.obj.(*Instance); ok {
+				if iter, ok := instance.class.methods["iter"]; ok {
+					// the instance is in the slot of 'this', and what iter() returns is checked by OpIterCheck.
+					if err := vm.call(iter, 0); err != nil {
+						return err
+					}
+					reload()
+					break
+				}
+			}
+			it, ok := iterate(v)
+			if !ok {
+				return vm.runtimeError("can't iterate over %s", v)
+			}
+			vm.stack[vm.sp-1] = it
+		case OpIterCheck:
+			it, ok := asIterator(vm.peek(0))
+			if !ok {
+				return vm.runtimeError("iter() must return an iterator, got %s", vm.peek(0))
+			}
+			vm.stack[vm.sp-1] = it
+		case OpIterNext:
+			switch it := vm.peek(0).obj.(type) {
+			case *iterator:
+				v, ok := it.next()
+				if !ok {
+					v = doneValue
+				}
+				vm.stack[vm.sp-1] = v
+			case *Generator:
+				if err := vm.next(it); err != nil {
+					return err
+				}
+			default:
+				if err := vm.callValue(vm.peek(0), 0); err != nil {
+					return err
+				}
+			}
+			reload()
+		case OpJumpIfDone:
+			offset := readShort()
+			if _, ok := vm.peek(0).obj.(Done); ok {
+				frame.ip += offset
+			}
+		case OpIterClose:
+			if g, ok := vm.peek(0).obj.(*Generator); ok {
+				if err := vm.close(g); err != nil {
+					return err
+				}
+				reload()
+				break
+			}
+			vm.stack[vm.sp-1] = nilValue
+		case OpYield:
+			if err := vm.yield(vm.pop()); err != nil {
+				return err
+			}
+			reload()
+		case OpImport:
+			if err := vm.importModule(readName()); err != nil {
+				return err
+			}
+			reload()
+		case OpImported:
+			vm.sp--
+			m := vm.peek(0).obj.(*Namespace)
+			vm.modules[m.key] = m
+		default:
+			return vm.runtimeError("unknown opcode %d", op)
+		}
+	}
+}
+
+func getIndex(obj, index Value) (Value, error) {
+	switch o := obj.obj.(type) {
+	case *List:
+		idx, err := o.index(index)
+		if err != nil {
+			return nilValue, err
+		}
+		return o.elements[idx], nil
+	case *Map:
+		if err := checkKey(index); err != nil {
+			return nilValue, err
+		}
+		// missing keys evaluate to nil
+		v, _ := o.get(index)
+		return v, nil
+	case string:
+		return stringIndex(o, index)
+	default:
+		return nilValue, fmt.Errorf("can only index lists, maps and strings, got %s", obj)
+	}
+}
+
+func setIndex(obj, index, v Value) error {
+	switch o := obj.obj.(type) {
+	case *List:
+		idx, err := o.index(index)
+		if err != nil {
+			return err
+		}
+		o.elements[idx] = v
+		return nil
+	case *Map:
+		if err := checkKey(index); err != nil {
+			return err
+		}
+		o.set(index, v)
+		return nil
+	default:
+		return fmt.Errorf("can only index lists and maps, got %s", obj)
+	}
+}
+
+func (vm *VM) callValue(callee Value, argCount int) error {
+	switch c := callee.obj.(type) {
+	case *Closure:
+		return vm.call(c, argCount)
+	case *BoundMethod:
+		vm.stack[vm.sp-argCount-1] = c.receiver
+		return vm.call(c.method, argCount)
+	case *Class:
+		vm.stack[vm.sp-argCount-1] = objValue(&Instance{class: c, fields: make(map[string]Value)})
+		if init, ok := c.methods["init"]; ok {
+			return vm.call(init, argCount)
+		}
+		if argCount != 0 {
+			return vm.runtimeError("expected 0 arguments, got %d", argCount)
+		}
+		return nil
+	case *generatorMethod:
+		if argCount != 0 {
+			return vm.runtimeError("expected 0 arguments, got %d", argCount)
+		}
+		if c.name == "close" {
+			return vm.close(c.generator)
+		}
+		return vm.next(c.generator)
+	case *Native:
+		if c.arity != -1 && c.arity != argCount {
+			return vm.runtimeError("expected %d arguments, got %d", c.arity, argCount)
+		}
+		args := make([]Value, argCount)
+		copy(args, vm.stack[vm.sp-argCount:vm.sp])
+		result, err := c.fn(vm, args)
+		if err != nil {
+			rerr := vm.runtimeError("%s", err)
+			rerr.Err = err
+			native := diagnostic.Frame{Function: c.String(), Native: true, Call: rerr.Token}
+			rerr.Stack = append([]diagnostic.Frame{native}, rerr.Stack...)
+			return rerr
+		}
+		vm.sp -= argCount + 1
+		vm.push(result)
+		return nil
+	default:
+		return vm.runtimeError("can only call functions and classes, got %s", callee)
+	}
+}
+
+func (vm *VM) call(closure *Closure, argCount int) error {
+	if argCount != closure.fn.Arity {
+		return vm.runtimeError("expected %d arguments, got %d", closure.fn.Arity, argCount)
+	}
+	if closure.fn.Generator {
+		g := vm.newGenerator(closure, argCount)
+		vm.push(objValue(g))
+		return nil
+	}
+	if err := vm.checkDepth(); err != nil {
+		return err
+	}
+
+	vm.frames[vm.frameCount] = callFrame{
+		closure: closure,
+		ip:      0,
+		base:    vm.sp - argCount - 1,
+	}
+	vm.frameCount++
+	return nil
+}
+
+// checkDepth checks that there is room for one more frame, on the frames and on the stack.
+func (vm *VM) checkDepth() error {
+	if vm.frameCount == framesMax {
+		err := vm.runtimeError("call depth limit of %d exceeded", framesMax)
+		err.Err = errStackOverflow
+		return err
+	}
+	if vm.sp+frameSlotsMax > stackMax {
+		err := vm.runtimeError("stack overflow")
+		err.Err = errStackOverflow
+		return err
+	}
+	return nil
+}
+
+// captureUpvalue returns the open upvalue for the stack slot, creating one if needed,
+// so that closures capturing the same variable share it.
+func (vm *VM) captureUpvalue(slot int) *Upvalue {
+	var prev *Upvalue
+	up := vm.openUpvalues
+	for up != nil && up.slot > slot {
+		prev = up
+		up = up.next
+	}
+	if up != nil && up.slot == slot {
+		return up
+	}
+
+	created := &Upvalue{location: &vm.stack[slot], slot: slot, next: up}
+	if prev == nil {
+		vm.openUpvalues = created
+	} else {
+		prev.next = created
+	}
+	return created
+}
+
+// closeUpvalues moves the variables at or above the stack slot off the stack.
+func (vm *VM) closeUpvalues(last int) {
+	for vm.openUpvalues != nil && vm.openUpvalues.slot >= last {
+		up := vm.openUpvalues
+		up.closed = *up.location
+		up.location = &up.closed
+		vm.openUpvalues = up.next
+	}
+}
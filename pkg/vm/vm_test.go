@@ -0,0 +1,236 @@
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/taehioum/glox/pkg/diagnostic"
+	"github.com/taehioum/glox/pkg/parser"
+	"github.com/taehioum/glox/pkg/scanner"
+)
+
+func run(t *testing.T, source string) (string, error) {
+	t.Helper()
+	fn, err := compile(t, source)
+	if err != nil {
+		t.Fatalf("compiling: %v", err)
+	}
+	var out bytes.Buffer
+	err = New(&out).Interprete(fn)
+	return out.String(), err
+}
+
+func TestRun(t *testing.T) {
+	testcases := []struct {
+		source string
+		out    string
+	}{
+		{"print(1 + 2, 7 / 2, 7 // 2, 2 ** 10);", "3 3.5 3 1024\n"},
+		{"var x = 1; x++; ++x; x--; print(x);", "2\n"},
+		{"var l = [1, 2]; l[0]++; l[1] += 10; print(l);", "[2, 12]\n"},
+		{"fun f(n) { if (n < 2) return n; return f(n - 1) + f(n - 2); } print(f(15));", "610\n"},
+		{"fun counter() { var n = 0; return fun () { n++; return n; }; } var c = counter(); c(); print(c());", "2\n"},
+		{"class A { init(n) { this.n = n; } get() { return this.n; } } class B < A { get() { return super.get() * 2; } } print(B(21).get());", "42\n"},
+		{"var m = {\"a\": 1}; m[\"b\"] = 2; print(m, m[\"c\"]);", "{\"a\": 1, \"b\": 2} <nil>\n"},
+		{"try { throw \"boom\"; } catch (e) { print(e); } finally { print(\"done\"); }", "boom\ndone\n"},
+		{"try { nil(); } catch (e) { print(e.message, e.line); }", "can only call functions and classes, got <nil> 1\n"},
+		{"var s = \"a\"; print(\"${s}${1 + 1}\");", "a2\n"},
+		{"var max = 9223372036854775807; print(max + 1, -(-max - 1), (max + 1) - 1 == max);", "9223372036854775808 9223372036854775808 true\n"},
+		{"print(~(1 << 70), (1 << 70) >> 69, 2 ** 64 // 3);", "-1180591620717411303425 2 6148914691236517205\n"},
+		{"var a = 1; print(a, [a, match ([2, [3]]) { case [x, [y]] if x > y => 0, case [x, [y]] => x * y }]);", "1 [1, 6]\n"},
+		{"print(match ({\"k\": 1}) { case {\"k\": 2}, [] => \"no\", case {\"k\": k} => k });", "1\n"},
+		{"var fs = []; for (var i in range(3)) push(fs, fun () { return i; }); print(fs[0](), fs[2]());", "0 2\n"},
+		{"var s = \"\"; for (var k in {\"a\": 1, \"b\": 2}) s += k; for (var c in \"é!\") s += c; print(s);", "abé!\n"},
+		{"fun g() { var n = 0; yield fun () { return n; }; n = 5; yield nil; } var it = g(); var get = it.next(); it.next(); print(get(), it.next(), it);", "5 done <generator g>\n"},
+		{"fun g() { try { yield 1; yield 2; } finally { print(\"closed\"); } } for (var x in g()) { print(x); break; }", "1\nclosed\n"},
+		{"print(0.1d + 0.2d, 1d / 3, 1.5d * 2 == 3, {2d: \"two\"}[2]);", "0.3 0.3333333333333333333333333333 true two\n"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.source, func(t *testing.T) {
+			out, err := run(t, tc.source)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.out, out)
+			}
+		})
+	}
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	testcases := []struct {
+		source string
+		msg    string
+		line   int
+		col    int
+	}{
+		{"nil();", "can only call functions and classes, got <nil>", 1, 5},
+		{"var x = \"a\";\nx++;", "operand must be a number, got a", 2, 2},
+		{"var x = \"a\";\n--x;", "operand must be a number, got a", 2, 1},
+		{"print(1 + \"a\");", "operands must be two numbers or two strings, got 1 and a", 1, 9},
+		{"print(1 // 0);", "division by zero", 1, 9},
+		{"print([1][5]);", "list index 5 out of range for length 1", 1, 10},
+		{"print(undefined);", "undefined variable 'undefined'", 1, 7},
+		{"fun f(a) {}\nf();", "expected 1 arguments, got 0", 2, 3},
+		{"fun down() { return down(); }\ndown();", "call depth limit of 1024 exceeded", 1, 26},
+		{"match ([1]) {\n  case [] => nil;\n}", "no arm matches [1]", 1, 1},
+		{"for (var x in 42) print(x);", "can't iterate over 42", 1, 12},
+		{"fun g() {\n  yield it.next();\n}\nvar it = g();\nit.next();", "generator g is already running", 2, 17},
+		{"print(2 ** 1048576);", "exponent 1048576 is too large, the result would take more than 1048576 bits", 1, 9},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.source, func(t *testing.T) {
+			_, err := run(t, tc.source)
+			var rerr *diagnostic.RuntimeError
+			if assert.True(t, errors.As(err, &rerr), "expected a runtime error, got %v", err) {
+				assert.Equal(t, tc.msg, rerr.Msg)
+				assert.Equal(t, tc.line, rerr.Line)
+				assert.Equal(t, tc.col, rerr.Column)
+			}
+		})
+	}
+}
+
+func TestClose(t *testing.T) {
+	fn, err := compile(t, `
+fun numbers() {
+  try {
+    var n = 0;
+    while (true) {
+      yield n;
+      n++;
+    }
+  } finally {
+    print("closed");
+  }
+}
+var g = numbers();
+print(g.next());`)
+	if err != nil {
+		t.Fatalf("compiling: %v", err)
+	}
+	var out bytes.Buffer
+	vm := New(&out)
+	if assert.NoError(t, vm.Interprete(fn)) && assert.NoError(t, vm.Close()) {
+		assert.Equal(t, "0\nclosed\n", out.String())
+	}
+}
+
+// loadFiles returns a loader of the files, by path, counting how many times each is loaded.
+func loadFiles(files map[string]string, loads map[string]int) Loader {
+	return func(path string) (*Function, error) {
+		source, ok := files[path]
+		if !ok {
+			return nil, fmt.Errorf("no file %s", path)
+		}
+		loads[path]++
+		tokens, err := scanner.ScanFile(path, source)
+		if err != nil {
+			return nil, err
+		}
+		stmts, err := parser.Parse(tokens)
+		if err != nil {
+			return nil, err
+		}
+		return Compile(stmts)
+	}
+}
+
+func TestImport(t *testing.T) {
+	files := map[string]string{
+		"main.lox": `
+import "lib/math.lox" as m;
+fun helper() { return 100; }
+fun local() {
+  import "lib/util.lox" as u;
+  return u.calls();
+}
+print(m.twice(m.base), local(), helper(), m);`,
+		"lib/math.lox": `
+import "util.lox" as util;
+var base = 2;
+fun twice(x) { return util.double(x); }`,
+		"lib/util.lox": `
+var _calls = 0;
+fun helper() { return 0; }
+fun double(x) {
+  _calls = _calls + 1;
+  return x * 2 + helper();
+}
+fun calls() { return _calls; }`,
+		"errors.lox": `
+import "lib/math.lox" as m;
+try { m._secret; } catch (e) { print(e.message); }
+try { m.helper; } catch (e) { print(e.message); }
+try { m.base = 1; } catch (e) { print(e.message); }`,
+		"a.lox":       `import "b.lox" as b;`,
+		"b.lox":       `import "a.lox" as a;`,
+		"missing.lox": `try { import "nowhere.lox" as n; } catch (e) { print("caught"); }`,
+	}
+
+	run := func(t *testing.T, file string, loader Loader) (string, error) {
+		t.Helper()
+		tokens, err := scanner.ScanFile(file, files[file])
+		if err != nil {
+			t.Fatalf("scanning: %v", err)
+		}
+		stmts, err := parser.Parse(tokens)
+		if err != nil {
+			t.Fatalf("parsing: %v", err)
+		}
+		fn, err := Compile(stmts)
+		if err != nil {
+			t.Fatalf("compiling: %v", err)
+		}
+		var out bytes.Buffer
+		vm := New(&out)
+		if loader != nil {
+			vm.SetLoader(loader)
+		}
+		err = vm.Interprete(fn)
+		return out.String(), err
+	}
+
+	t.Run("modules have their own globals, and run once", func(t *testing.T) {
+		loads := make(map[string]int)
+		out, err := run(t, "main.lox", loadFiles(files, loads))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "4 1 100 <module m>\n", out)
+			assert.Equal(t, map[string]int{"lib/math.lox": 1, "lib/util.lox": 1}, loads)
+		}
+	})
+
+	t.Run("names of modules", func(t *testing.T) {
+		out, err := run(t, "errors.lox", loadFiles(files, make(map[string]int)))
+		if assert.NoError(t, err) {
+			assert.Equal(t, "'_secret' is private to module m\nmodule m has no 'helper'\ncan't assign to 'base' of module m\n", out)
+		}
+	})
+
+	testcases := []struct {
+		file   string
+		loader Loader
+		msg    string
+	}{
+		{"a.lox", loadFiles(files, make(map[string]int)), "import cycle: a.lox -> b.lox -> a.lox"},
+		{"main.lox", nil, "imports are not available"},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.msg, func(t *testing.T) {
+			_, err := run(t, tc.file, tc.loader)
+			var rerr *diagnostic.RuntimeError
+			if assert.True(t, errors.As(err, &rerr), "expected a runtime error, got %v", err) {
+				assert.Equal(t, tc.msg, rerr.Msg)
+			}
+		})
+	}
+
+	t.Run("errors loading modules can't be caught", func(t *testing.T) {
+		out, err := run(t, "missing.lox", loadFiles(files, make(map[string]int)))
+		assert.EqualError(t, err, "no file nowhere.lox")
+		assert.Empty(t, out)
+	})
+}